@@ -3,18 +3,32 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
+	"legally/models"
+	"legally/repositories"
 	"legally/services"
 	"legally/utils"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 func AnalyzeDocument(c *gin.Context) {
-	// Get file from request
-	file, err := c.FormFile("document")
-	if err != nil {
+	// Get file from request (body size is limited by the upload middleware)
+	if _, err := c.FormFile("document"); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "File is too large",
+				"code":  "FILE_TOO_LARGE",
+				"limit": utils.MaxUploadSize(),
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":  "File is required",
 			"code":   "FILE_REQUIRED",
@@ -23,15 +37,6 @@ func AnalyzeDocument(c *gin.Context) {
 		return
 	}
 
-	// Validate file type
-	if !strings.HasSuffix(strings.ToLower(file.Filename), ".pdf") {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "File must be PDF format",
-			"code":  "INVALID_FILE_TYPE",
-		})
-		return
-	}
-
 	// Get user from context
 	userID, exists := c.Get("userId")
 	if !exists {
@@ -42,17 +47,32 @@ func AnalyzeDocument(c *gin.Context) {
 		return
 	}
 
+	// Asynchronous mode: return the job ID right away
+	if isAsyncRequest(c) {
+		job, serviceErr := services.SubmitAnalysisJob(c)
+		if serviceErr != nil {
+			respondServiceError(c, serviceErr)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"success":   true,
+			"jobId":     job.ID.Hex(),
+			"status":    job.Status,
+			"filename":  job.Filename,
+			"duplicate": job.Duplicate,
+		})
+		return
+	}
+
 	// Create a copy of the context with userId
 	newC := c.Copy()
 	newC.Set("userId", userID)
 
-	// Process the file using AnalyzePDFDocument
+	// Process the uploaded document
 	result, serviceErr := services.AnalyzeDocument(newC)
 	if serviceErr != nil {
-		c.JSON(serviceErr.Status, gin.H{
-			"error": serviceErr.Message,
-			"code":  "ANALYSIS_ERROR",
-		})
+		respondServiceError(c, serviceErr)
 		return
 	}
 
@@ -67,12 +87,96 @@ func AnalyzeDocument(c *gin.Context) {
 	}
 
 	// Return successful response with data
-	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"analysis":     analysisResult["analysis"],
-		"documentType": analysisResult["document_type"],
-		"filename":     analysisResult["filename"],
-		"timestamp":    analysisResult["timestamp"],
+	c.JSON(http.StatusOK, analysisPayload(analysisResult))
+}
+
+// analysisPayload переводит ответ сервиса анализа в формат API; он общий
+// для синхронного анализа и результата асинхронной задачи
+func analysisPayload(analysisResult gin.H) gin.H {
+	return gin.H{
+		"success":          true,
+		"analysis":         analysisResult["analysis"],
+		"result":           analysisResult["result"],
+		"parts":            analysisResult["parts"],
+		"partial":          analysisResult["partial"],
+		"usage":            analysisResult["usage"],
+		"prompts":          analysisResult["prompts"],
+		"pages":            analysisResult["pages"],
+		"language":         analysisResult["language"],
+		"responseLanguage": analysisResult["response_language"],
+		"redaction":        analysisResult["redaction"],
+		"metadata":         analysisResult["metadata"],
+		"classification":   analysisResult["classification"],
+		"legalSources":     analysisResult["legal_sources"],
+		"citations":        analysisResult["citations"],
+		"bundle":           analysisResult["bundle"] == true,
+		"files":            analysisResult["files"],
+		"duplicate":        analysisResult["duplicate"] == true,
+		"analysisId":       analysisResult["analysis_id"],
+		"documentType":     analysisResult["document_type"],
+		"filename":         analysisResult["filename"],
+		"timestamp":        analysisResult["timestamp"],
+	}
+}
+
+// AnalyzeDocumentStream — потоковый вариант AnalyzeDocument через Server-Sent Events
+func AnalyzeDocumentStream(c *gin.Context) {
+	if _, exists := c.Get("userId"); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+			"code":  "AUTH_ERROR",
+		})
+		return
+	}
+
+	// Заголовки потока отправляются с первым событием, чтобы ошибки загрузки
+	// по-прежнему возвращались обычным JSON-ответом
+	started := false
+	emit := func(event string, data interface{}) {
+		if !started {
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	if serviceErr := services.StreamAnalyzeDocument(c, emit); serviceErr != nil {
+		if !started {
+			respondServiceError(c, serviceErr)
+			return
+		}
+		emit("error", gin.H{
+			"error": serviceErr.Message,
+			"code":  serviceErr.Code,
+		})
+	}
+}
+
+func isAsyncRequest(c *gin.Context) bool {
+	async := c.Query("async")
+	if async == "" {
+		async = c.PostForm("async")
+	}
+	return async == "true" || async == "1"
+}
+
+// respondServiceError отдаёт ошибку сервиса с её кодом и, если известно, заголовком Retry-After
+func respondServiceError(c *gin.Context, serviceErr *services.HttpError) {
+	code := serviceErr.Code
+	if code == "" {
+		code = "ANALYSIS_ERROR"
+	}
+	if serviceErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(serviceErr.RetryAfter.Seconds()))))
+	}
+	c.JSON(serviceErr.Status, gin.H{
+		"error": serviceErr.Message,
+		"code":  code,
 	})
 }
 
@@ -93,7 +197,13 @@ func GetHistory(c *gin.Context) {
 
 	utils.LogInfo(fmt.Sprintf("Запрос истории для пользователя: %s", userID))
 
-	history, err := services.GetUserHistory(userID.(string))
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_FILTER"})
+		return
+	}
+
+	history, err := services.GetUserHistory(userID.(string), filter)
 	if err != nil {
 		utils.LogError(fmt.Sprintf("Ошибка получения истории: %v", err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения истории"})
@@ -104,6 +214,102 @@ func GetHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, history)
 }
 
+// historyFilter читает параметры counterparty, min_amount, max_amount и currency
+func historyFilter(c *gin.Context) (models.HistoryFilter, error) {
+	filter := models.HistoryFilter{
+		Counterparty: strings.TrimSpace(c.Query("counterparty")),
+		Currency:     strings.ToUpper(strings.TrimSpace(c.Query("currency"))),
+	}
+
+	for param, dst := range map[string]**float64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		raw := strings.TrimSpace(c.Query(param))
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(raw, " ", ""), ",", "."), 64)
+		if err != nil || value < 0 {
+			return filter, fmt.Errorf("invalid %s", param)
+		}
+		*dst = &value
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return filter, fmt.Errorf("min_amount is greater than max_amount")
+	}
+	return filter, nil
+}
+
+// ListDocumentTypes возвращает типы документов, которые можно указать вместо определённого автоматически
+func ListDocumentTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"types": services.DocumentTypes()})
+}
+
+// DeleteAnalysis удаляет анализ из истории пользователя
+func DeleteAnalysis(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := services.DeleteAnalysis(userID.(string), c.Param("id")); err != nil {
+		if errors.Is(err, repositories.ErrAnalysisNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found", "code": "ANALYSIS_NOT_FOUND"})
+			return
+		}
+		utils.LogError(fmt.Sprintf("Ошибка удаления анализа: %v", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete analysis"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+type SetDocumentTypeRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+// SetDocumentType исправляет тип документа сохранённого анализа
+func SetDocumentType(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req SetDocumentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	classification, err := services.SetAnalysisType(userID.(string), c.Param("id"), req.Type)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownDocType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown document type", "code": "INVALID_DOC_TYPE"})
+		case errors.Is(err, services.ErrBundleDocType):
+			c.JSON(http.StatusConflict, gin.H{"error": "Document type of a bundle cannot be changed", "code": "BUNDLE_TYPE_IMMUTABLE"})
+		case errors.Is(err, repositories.ErrAnalysisNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found", "code": "ANALYSIS_NOT_FOUND"})
+		default:
+			utils.LogError(fmt.Sprintf("Ошибка изменения типа документа: %v", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document type"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"documentType":   classification.Label,
+		"classification": classification,
+	})
+}
+
+// CancelRequest — отмена анализа; без jobId отменяются все текущие анализы пользователя
+type CancelRequest struct {
+	JobID string `json:"jobId"`
+}
+
 func CancelAnalysis(c *gin.Context) {
 	userID, exists := c.Get("userId")
 	if !exists {
@@ -111,10 +317,24 @@ func CancelAnalysis(c *gin.Context) {
 		return
 	}
 
-	// Отменяем анализ для данного пользователя
-	err := services.CancelUserAnalysis(userID.(string))
+	// Тело запроса необязательно: прежние клиенты отменяют анализ без него
+	var req CancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Отменяем задачу анализа и её запросы к LLM
+	err := services.CancelUserAnalysis(userID.(string), req.JobID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, repositories.ErrJobNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrAnalysisFinished):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
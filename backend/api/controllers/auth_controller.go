@@ -7,6 +7,7 @@ import (
 	"legally/models"
 	"legally/services"
 	"net/http"
+	"strings"
 )
 
 type AuthRequest struct {
@@ -14,6 +15,14 @@ type AuthRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+type PreferencesRequest struct {
+	Language string `json:"language"`
+}
+
+type OrganizationRequest struct {
+	OrganizationID string `json:"organization_id"`
+}
+
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken" binding:"required"`
 }
@@ -79,11 +88,41 @@ func GetUser(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"email":     user.Email,
-		"role":      user.Role,
-		"createdAt": user.CreatedAt,
+		"email":          user.Email,
+		"role":           user.Role,
+		"language":       user.Language,
+		"organizationId": user.OrganizationID,
+		"createdAt":      user.CreatedAt,
 	})
 }
+
+func UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetUserLanguage(userID.(string), req.Language); err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case services.ErrUnsupportedLang:
+			status = http.StatusBadRequest
+		case services.ErrUserNotFound:
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"language": req.Language})
+}
 func Refresh(c *gin.Context) {
 	var req RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -128,3 +167,25 @@ func Logout(c *gin.Context) {
 		"success": true,
 	})
 }
+
+// SetUserOrganization привязывает пользователя к организации (только для администратора);
+// пустой organization_id отвязывает
+func SetUserOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	organizationID := strings.TrimSpace(req.OrganizationID)
+	if err := services.SetUserOrganization(c.Param("id"), organizationID); err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found", "code": "USER_NOT_FOUND"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "organizationId": organizationID})
+}
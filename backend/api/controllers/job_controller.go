@@ -0,0 +1,62 @@
+// job_controller.go
+
+package controllers
+
+import (
+	"errors"
+	"legally/repositories"
+	"legally/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetJob(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	job, err := services.GetAnalysisJob(userID.(string), c.Param("id"))
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func GetJobResult(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	job, result, err := services.GetAnalysisJobResult(userID.(string), c.Param("id"))
+	if errors.Is(err, services.ErrJobNotReady) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  err.Error(),
+			"code":   "JOB_NOT_READY",
+			"status": job.Status,
+		})
+		return
+	}
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	payload := analysisPayload(result)
+	payload["jobId"] = job.ID.Hex()
+	c.JSON(http.StatusOK, payload)
+}
+
+func respondJobError(c *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrJobNotFound) || errors.Is(err, repositories.ErrAnalysisNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "JOB_NOT_FOUND"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения задачи"})
+}
@@ -0,0 +1,106 @@
+// law_controller.go
+
+package controllers
+
+import (
+	"legally/laws"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	lawSearchDefaultLimit = 10
+	lawSearchMaxLimit     = 50
+)
+
+// SearchLaws ищет фрагменты законов по запросу ?q=; ?source= ограничивает
+// источники (через запятую), ?limit= — число результатов
+func SearchLaws(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is required", "code": "QUERY_REQUIRED"})
+		return
+	}
+
+	limit := lawSearchDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit", "code": "INVALID_LIMIT"})
+			return
+		}
+		limit = min(n, lawSearchMaxLimit)
+	}
+
+	var sources []string
+	for _, s := range strings.Split(c.Query("source"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sources = append(sources, s)
+		}
+	}
+
+	hits := laws.Default().Search(query, laws.SearchOptions{Limit: limit, Sources: sources})
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": hits,
+		"total":   len(hits),
+	})
+}
+
+// GetLawCode возвращает оглавление кодекса: статьи с разделами и главами, без текста
+func GetLawCode(c *gin.Context) {
+	code, ok := laws.DefaultStatutes().Code(c.Param("code"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code not found", "code": "CODE_NOT_FOUND"})
+		return
+	}
+
+	articles := make([]gin.H, 0, len(code.Articles))
+	for _, a := range code.Articles {
+		articles = append(articles, gin.H{
+			"number":    a.Number,
+			"title":     a.Title,
+			"section":   a.Section,
+			"chapter":   a.Chapter,
+			"paragraph": a.Paragraph,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":     code.Source,
+		"articles": articles,
+	})
+}
+
+// GetLawArticle возвращает текст статьи кодекса; ?point= — только указанный пункт
+func GetLawArticle(c *gin.Context) {
+	statutes := laws.DefaultStatutes()
+	if _, ok := statutes.Code(c.Param("code")); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code not found", "code": "CODE_NOT_FOUND"})
+		return
+	}
+	article, ok := statutes.Article(c.Param("code"), c.Param("n"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found", "code": "ARTICLE_NOT_FOUND"})
+		return
+	}
+
+	if n := c.Query("point"); n != "" {
+		point, ok := article.Point(n)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Point not found", "code": "POINT_NOT_FOUND"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    article.Code,
+			"article": article.Number,
+			"title":   article.Title,
+			"point":   point,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
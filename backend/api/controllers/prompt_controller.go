@@ -0,0 +1,28 @@
+// prompt_controller.go
+
+package controllers
+
+import (
+	"legally/prompts"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListPrompts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts.Default().List()})
+}
+
+// ReloadPrompts перечитывает шаблоны из PROMPTS_DIR без перезапуска сервера
+func ReloadPrompts(c *gin.Context) {
+	registry := prompts.Default()
+	if err := registry.Reload(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"prompts": registry.List(),
+	})
+}
@@ -1,28 +1,137 @@
-//similarity_controller
+// similarity_controller
 package controllers
 
 import (
-	"github.com/gin-gonic/gin"
+	"errors"
+	"fmt"
+	"legally/models"
 	"legally/services"
+	"legally/utils"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
-func FindSimilarDocuments(c *gin.Context) {
-	userID, _ := c.Get("userId")
+const (
+	defaultSimilarTopK = 5
+	maxSimilarTopK     = 50
+)
+
+type SimilarRequest struct {
+	Text     string   `json:"text" binding:"required"`
+	Scope    string   `json:"scope"`
+	TopK     *int     `json:"top_k"`
+	MinScore *float64 `json:"min_score"`
+	DocType  string   `json:"doc_type"`
+	DateFrom string   `json:"date_from"`
+	DateTo   string   `json:"date_to"`
+	Filename string   `json:"filename"`
+}
 
-	var req struct {
-		Text string `json:"text"`
+// FindSimilarDocuments ищет похожие документы среди документов пользователя или его организации
+func FindSimilarDocuments(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
 	}
+
+	var req SimilarRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат запроса"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	query, err := similarityQuery(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_FILTER"})
 		return
 	}
 
-	results, err := services.SearchSimilar(userID.(string), req.Text)
+	results, err := services.SearchSimilar(userID.(string), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска: " + err.Error()})
+		switch {
+		case errors.Is(err, services.ErrUnknownDocType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown document type", "code": "INVALID_DOC_TYPE"})
+		case errors.Is(err, services.ErrNoOrganization):
+			c.JSON(http.StatusForbidden, gin.H{"error": "User is not a member of an organization", "code": "NO_ORGANIZATION"})
+		case errors.Is(err, services.ErrVectorStoreDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Similarity search is not configured", "code": "SEARCH_UNAVAILABLE"})
+		default:
+			utils.LogError(fmt.Sprintf("Ошибка поиска похожих документов: %v", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Similarity search failed"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"matches": results})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"matches": results, "scope": query.Scope})
+}
+
+// similarityQuery проверяет параметры поиска: область, top_k, порог и фильтры
+func similarityQuery(req SimilarRequest) (models.SimilarityQuery, error) {
+	query := models.SimilarityQuery{
+		Text:     strings.TrimSpace(req.Text),
+		Scope:    strings.ToLower(strings.TrimSpace(req.Scope)),
+		TopK:     defaultSimilarTopK,
+		DocType:  strings.TrimSpace(req.DocType),
+		Filename: strings.TrimSpace(req.Filename),
+	}
+	if query.Text == "" {
+		return query, fmt.Errorf("text is required")
+	}
+
+	switch query.Scope {
+	case "":
+		query.Scope = models.SimilarityScopeUser
+	case models.SimilarityScopeUser, models.SimilarityScopeOrganization:
+	default:
+		return query, fmt.Errorf("scope must be %q or %q", models.SimilarityScopeUser, models.SimilarityScopeOrganization)
+	}
+
+	if req.TopK != nil {
+		if *req.TopK < 1 || *req.TopK > maxSimilarTopK {
+			return query, fmt.Errorf("top_k must be between 1 and %d", maxSimilarTopK)
+		}
+		query.TopK = *req.TopK
+	}
+	if req.MinScore != nil {
+		if *req.MinScore < 0 || *req.MinScore > 1 {
+			return query, fmt.Errorf("min_score must be between 0 and 1")
+		}
+		query.MinScore = *req.MinScore
+	}
+
+	var err error
+	if query.From, err = similarityDate(req.DateFrom, false); err != nil {
+		return query, fmt.Errorf("invalid date_from")
+	}
+	if query.To, err = similarityDate(req.DateTo, true); err != nil {
+		return query, fmt.Errorf("invalid date_to")
+	}
+	if query.From != nil && query.To != nil && query.From.After(*query.To) {
+		return query, fmt.Errorf("date_from is after date_to")
+	}
+	return query, nil
+}
+
+// similarityDate читает дату в виде 2006-01-02 или RFC 3339; дата без времени
+// в конце периода включает весь день
+func similarityDate(raw string, endOfDay bool) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return &t, nil
+}
@@ -0,0 +1,51 @@
+// usage_controller.go
+
+package controllers
+
+import (
+	"legally/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const usageDefaultPeriod = 30 * 24 * time.Hour
+
+// GetUsage возвращает расход токенов пользователя по дням; период задаётся ?from=&to= (YYYY-MM-DD)
+func GetUsage(c *gin.Context) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-usageDefaultPeriod)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты from, ожидается YYYY-MM-DD"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты to, ожидается YYYY-MM-DD"})
+			return
+		}
+	}
+
+	days, total, err := services.GetUserUsage(userID.(string), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения статистики"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from.Format("2006-01-02"),
+		"to":    to.Format("2006-01-02"),
+		"days":  days,
+		"total": total,
+	})
+}
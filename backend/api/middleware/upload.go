@@ -0,0 +1,29 @@
+// upload.go
+
+package middleware
+
+import (
+	"legally/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadLimit ограничивает размер тела запроса до того, как обработчик
+// начнёт разбирать multipart-форму
+func UploadLimit(maxBytes int64) gin.HandlerFunc {
+	limit := maxBytes + utils.MultipartOverhead
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Файл слишком большой",
+				"code":  "FILE_TOO_LARGE",
+				"limit": maxBytes,
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
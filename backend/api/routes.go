@@ -7,6 +7,7 @@ import (
 	"legally/api/middleware"
 	"legally/db"
 	"legally/models"
+	"legally/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,24 +39,38 @@ func SetupRoutes(router *gin.Engine) {
 		public.POST("/refresh", controllers.Refresh)
 		public.GET("/validate-token", controllers.ValidateToken)
 		public.GET("/laws", controllers.GetRelevantLaws)
+		public.GET("/laws/search", controllers.SearchLaws)
+		public.GET("/laws/:code", controllers.GetLawCode)
+		public.GET("/laws/:code/articles/:n", controllers.GetLawArticle)
 	}
 
 	private := router.Group("/api")
 	private.Use(middleware.AuthRequired(models.RoleUser))
 	{
-		private.POST("/analyze", controllers.AnalyzeDocument)
+		uploadLimit := middleware.UploadLimit(utils.MaxUploadSize())
+		private.POST("/analyze", uploadLimit, controllers.AnalyzeDocument)
+		private.POST("/analyze/stream", uploadLimit, controllers.AnalyzeDocumentStream)
 		private.GET("/history", controllers.GetHistory)
+		private.DELETE("/history/:id", controllers.DeleteAnalysis)
+		private.PUT("/history/:id/type", controllers.SetDocumentType)
+		private.GET("/document-types", controllers.ListDocumentTypes)
 		private.POST("/logout", controllers.Logout)
 		private.GET("/user", controllers.GetUser)
+		private.PUT("/user/preferences", controllers.UpdatePreferences)
+		private.GET("/user/usage", controllers.GetUsage)
 		private.POST("/analysis/cancel", controllers.CancelAnalysis)
+		private.GET("/jobs/:id", controllers.GetJob)
+		private.GET("/jobs/:id/result", controllers.GetJobResult)
 		private.POST("/cache/clear", controllers.ClearFileCache)
-		private.POST("/similar", controllers.FindSimilarDocuments) // 🔍 Новый эндпоинт
+		private.POST("/similar", controllers.FindSimilarDocuments)
 	}
 
 	// Админские маршруты
 	admin := router.Group("/api/admin")
 	admin.Use(middleware.AuthRequired(models.RoleAdmin))
 	{
-		// TODO: admin endpoints
+		admin.GET("/prompts", controllers.ListPrompts)
+		admin.POST("/prompts/reload", controllers.ReloadPrompts)
+		admin.PUT("/users/:id/organization", controllers.SetUserOrganization)
 	}
-}
\ No newline at end of file
+}
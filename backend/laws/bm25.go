@@ -0,0 +1,154 @@
+// bm25.go
+
+package laws
+
+import (
+	"fmt"
+	"legally/utils"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Параметры BM25: насыщение частоты терма и нормализация по длине фрагмента
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type posting struct {
+	doc  int
+	freq int
+}
+
+// Index — BM25-индекс фрагментов корпуса в памяти процесса
+type Index struct {
+	chunks   []Chunk
+	lengths  []int
+	avgLen   float64
+	postings map[string][]posting
+}
+
+// Hit — найденный фрагмент и его оценка BM25
+type Hit struct {
+	Chunk Chunk   `json:"chunk"`
+	Score float64 `json:"score"`
+}
+
+// SearchOptions — ограничения поиска. Sources оставляет только указанные источники,
+// Kind — только источники этого вида (например, законы без типовых договоров).
+type SearchOptions struct {
+	Limit   int
+	Sources []string
+	Kind    string
+}
+
+// NewIndex строит индекс по фрагментам
+func NewIndex(chunks []Chunk) *Index {
+	idx := &Index{
+		chunks:   chunks,
+		lengths:  make([]int, len(chunks)),
+		postings: map[string][]posting{},
+	}
+
+	total := 0
+	for i, c := range chunks {
+		freq := map[string]int{}
+		for _, t := range Tokenize(c.Text) {
+			freq[t]++
+		}
+		for t, f := range freq {
+			idx.postings[t] = append(idx.postings[t], posting{doc: i, freq: f})
+		}
+		for _, f := range freq {
+			idx.lengths[i] += f
+		}
+		total += idx.lengths[i]
+	}
+	if len(chunks) > 0 {
+		idx.avgLen = float64(total) / float64(len(chunks))
+	}
+	return idx
+}
+
+// Len возвращает число фрагментов в индексе
+func (idx *Index) Len() int {
+	return len(idx.chunks)
+}
+
+// Chunks возвращает фрагменты индекса в порядке загрузки
+func (idx *Index) Chunks() []Chunk {
+	return idx.chunks
+}
+
+// Search возвращает фрагменты, лучше всего отвечающие запросу, по убыванию оценки
+func (idx *Index) Search(query string, opts SearchOptions) []Hit {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	allowed := map[string]bool{}
+	for _, s := range opts.Sources {
+		allowed[s] = true
+	}
+
+	terms := map[string]bool{}
+	for _, t := range Tokenize(query) {
+		terms[t] = true
+	}
+
+	n := float64(len(idx.chunks))
+	scores := map[int]float64{}
+	for t := range terms {
+		list := idx.postings[t]
+		if len(list) == 0 {
+			continue
+		}
+		df := float64(len(list))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range list {
+			source := idx.chunks[p.doc].Source
+			if len(allowed) > 0 && !allowed[source.ID] || opts.Kind != "" && source.Kind != opts.Kind {
+				continue
+			}
+			tf := float64(p.freq)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(idx.lengths[p.doc])/idx.avgLen)
+			scores[p.doc] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for doc, score := range scores {
+		hits = append(hits, Hit{Chunk: idx.chunks[doc], Score: math.Round(score*1000) / 1000})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Chunk.ID < hits[j].Chunk.ID
+	})
+	if len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits
+}
+
+var (
+	defaultIndex *Index
+	defaultOnce  sync.Once
+)
+
+// Default возвращает индекс корпуса из каталога LAWS_CHUNKS_DIR
+// (по умолчанию ../rag/data/chunks). Индекс строится при первом обращении;
+// если корпус не найден, индекс пуст.
+func Default() *Index {
+	defaultOnce.Do(func() {
+		dir := utils.GetEnv("LAWS_CHUNKS_DIR", "../rag/data/chunks")
+		chunks, err := LoadChunks(dir)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Ошибка загрузки корпуса законов: %v", err))
+		}
+		defaultIndex = NewIndex(chunks)
+		utils.LogSuccess(fmt.Sprintf("Проиндексировано %d фрагментов корпуса законов (%d термов)", len(chunks), len(defaultIndex.postings)))
+	})
+	return defaultIndex
+}
@@ -0,0 +1,60 @@
+package laws
+
+import "testing"
+
+func TestIndexSearch(t *testing.T) {
+	civil := LookupSource("civil_code_kz")
+	template := LookupSource("rental_contract_template")
+	idx := NewIndex([]Chunk{
+		{ID: "civil_1", Source: civil, Text: "Арендатор обязан своевременно вносить арендную плату за пользование имуществом."},
+		{ID: "civil_2", Source: civil, Text: "Договор купли-продажи заключается в письменной форме."},
+		{ID: "civil_3", Source: civil, Text: "Срок исковой давности составляет три года."},
+		{ID: "template_1", Source: template, Text: "Арендная плата вносится арендатором ежемесячно до пятого числа."},
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+		want  []string
+	}{
+		{"ranks by term overlap", "арендатор пользуется имуществом", SearchOptions{}, []string{"civil_1", "template_1"}},
+		{"limit", "арендная плата", SearchOptions{Limit: 1}, []string{"template_1"}},
+		{"kind", "арендная плата", SearchOptions{Kind: KindLaw}, []string{"civil_1"}},
+		{"sources", "арендная плата", SearchOptions{Sources: []string{template.ID}}, []string{"template_1"}},
+		{"no match", "наследство", SearchOptions{}, nil},
+		{"stop words only", "и в на", SearchOptions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Search(tt.query, tt.opts)
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.Chunk.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+			}
+			for i := 1; i < len(hits); i++ {
+				if hits[i].Score > hits[i-1].Score {
+					t.Errorf("hits are not sorted by score: %v", hits)
+				}
+			}
+		})
+	}
+}
+
+func TestEmptyIndex(t *testing.T) {
+	idx := NewIndex(nil)
+	if idx.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", idx.Len())
+	}
+	if hits := idx.Search("договор", SearchOptions{}); len(hits) != 0 {
+		t.Errorf("Search() on empty index = %v", hits)
+	}
+}
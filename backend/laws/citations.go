@@ -0,0 +1,240 @@
+// citations.go
+
+package laws
+
+import (
+	"fmt"
+	"legally/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reference — ссылка на норму, найденная в тексте. Act — название акта так,
+// как оно написано; Code — ID кодекса корпуса, если акт распознан.
+// Title — название статьи, если оно приведено в ссылке.
+type Reference struct {
+	Text    string
+	Act     string
+	Code    string
+	Article string
+	Point   string
+	Title   string
+}
+
+// Verification — результат проверки ссылки по тексту кодекса
+type Verification struct {
+	Status  string
+	Reason  string
+	Article *Article
+	Point   *Point
+}
+
+const (
+	numberPattern = `\d+(?:-\d+)?`
+	numberList    = numberPattern + `(?:\s*(?:,|и)\s*` + numberPattern + `)*`
+	actRuPattern  = `(?:ГК|ТК|НК|ГПК|УК|КоАП|ПК)(?:\s+РК)?` +
+		`|\p{L}+(?:ого|ой)\s+(?:процессуального\s+)?кодекс\p{L}*(?:\s+(?:Республики\s+Казахстан|РК))?` +
+		`|Кодекс\p{L}*\s+(?:Республики\s+Казахстан|РК)\s+об?\s+[^,.;()«»\n]{3,80}` +
+		`|Конституци\p{L}*(?:\s+(?:Республики\s+Казахстан|РК))?` +
+		`|Закон\p{L}*\s+(?:Республики\s+Казахстан|РК)\s+«[^»]{3,150}»`
+)
+
+var (
+	// «п. 2 ст. 401 ГК РК», «статьи 52 и 53 Трудового кодекса»
+	ruArticleFirstRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}])((?:(?:подпункт\p{L}*|пп\.)\s*\d+\)?\s*)?(?:(?:п\.|пункт\p{L}*|ч\.|част\p{L}*)\s*(` + numberPattern + `)\s*)?` +
+		`(?:ст\.\s*ст\.|стст\.|ст\.|стать\p{L}*)\s*(` + numberList + `)(?:\s*,)?(?:\s*(` + actRuPattern + `))?)`)
+	// «ГК РК, ст. 401»
+	ruCodeFirstRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}])((ГК(?:\s+РК)?|ТК(?:\s+РК)?|НК(?:\s+РК)?|Гражданск\p{L}*\s+кодекс\p{L}*(?:\s+(?:Республики\s+Казахстан|РК))?|Трудов\p{L}*\s+кодекс\p{L}*(?:\s+(?:Республики\s+Казахстан|РК))?|Конституци\p{L}*(?:\s+(?:Республики\s+Казахстан|РК))?|Закон\p{L}*\s+(?:Республики\s+Казахстан|РК)\s+«[^»]{3,150}»)\s*,?\s*(?:ст\.|стать\p{L}*)\s*(` + numberList + `))`)
+	// «Еңбек кодексінің 52-бабы», «АК 401-бабының 2-тармағы»
+	kkArticleRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(((?:ҚР\s+)?(?:Азаматтық\s+кодекс\p{L}*|Еңбек\s+кодекс\p{L}*|Конституция\p{L}*|АК|ЕК))\s+(` + numberPattern + `)-ба[пб]\p{L}*(?:\s+(\d+)-тармағ\p{L}*)?)`)
+	// «paragraph 2 of Article 52 of the Labor Code»
+	enArticleRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}])((?:(?:clause|paragraph|item|point)\s+(\d+)\s+of\s+)?article\s+(` + numberPattern + `)\s+of\s+the\s+((?:civil|labou?r|tax)\s+code|constitution)(?:\s+of\s+the\s+republic\s+of\s+kazakhstan|\s+of\s+the\s+rk)?)`)
+
+	referenceTitleRe = regexp.MustCompile(`^\s*[(«"]\s*«?([^»")]{3,150}?)»?\s*[)»"]`)
+	numberSplitRe    = regexp.MustCompile(numberPattern)
+)
+
+// ExtractReferences находит в тексте ссылки на статьи законов на русском,
+// казахском и английском. Ссылка на несколько статей («ст. 401, 403 ГК РК»)
+// даёт по одной ссылке на статью.
+func ExtractReferences(text string) []Reference {
+	var refs []Reference
+	masked := []byte(text)
+
+	// Порядок важен: совпадения более полного шаблона закрываются пробелами,
+	// чтобы следующий шаблон не нашёл ту же ссылку. Если build вернул nil,
+	// совпадение остаётся следующим шаблонам.
+	take := func(re *regexp.Regexp, build func(m []string, after string) []Reference) {
+		for _, idx := range re.FindAllSubmatchIndex(masked, -1) {
+			m := make([]string, len(idx)/2)
+			for i := range m {
+				if idx[2*i] >= 0 {
+					m[i] = text[idx[2*i]:idx[2*i+1]]
+				}
+			}
+			found := build(m, text[idx[3]:])
+			if found == nil {
+				continue
+			}
+			refs = append(refs, found...)
+			for i := idx[2]; i < idx[3]; i++ {
+				masked[i] = ' '
+			}
+		}
+	}
+
+	// Статья без акта может оказаться окончанием ссылки «ГК РК, ст. 401»,
+	// поэтому такие совпадения разбираются после шаблона «акт, статья»
+	ruArticleFirst := func(withoutAct bool) func(m []string, after string) []Reference {
+		return func(m []string, after string) []Reference {
+			if m[4] == "" && !withoutAct {
+				return nil
+			}
+			return splitReference(m[1], m[4], m[3], m[2], referenceTitle(after))
+		}
+	}
+	take(ruArticleFirstRe, ruArticleFirst(false))
+	take(ruCodeFirstRe, func(m []string, after string) []Reference {
+		return splitReference(m[1], m[2], m[3], "", referenceTitle(after))
+	})
+	take(ruArticleFirstRe, ruArticleFirst(true))
+	take(kkArticleRe, func(m []string, after string) []Reference {
+		return splitReference(m[1], m[2], m[3], m[4], referenceTitle(after))
+	})
+	take(enArticleRe, func(m []string, after string) []Reference {
+		return splitReference(m[1], m[4], m[3], m[2], referenceTitle(after))
+	})
+	return refs
+}
+
+func splitReference(text, act, numbers, point, title string) []Reference {
+	act = strings.Join(strings.Fields(act), " ")
+	code := actCode(act)
+
+	var refs []Reference
+	for _, n := range numberSplitRe.FindAllString(numbers, -1) {
+		refs = append(refs, Reference{
+			Text:    strings.TrimSpace(text),
+			Act:     act,
+			Code:    code,
+			Article: n,
+			Point:   point,
+			Title:   title,
+		})
+	}
+	// Пункт относится к одной статье, а не к перечню
+	if len(refs) > 1 {
+		for i := range refs {
+			refs[i].Point = ""
+		}
+	}
+	return refs
+}
+
+// referenceTitle возвращает название статьи, приведённое сразу после ссылки в скобках или кавычках
+func referenceTitle(after string) string {
+	if m := referenceTitleRe.FindStringSubmatch(after); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+// actCode сопоставляет название акта с кодексом корпуса
+func actCode(act string) string {
+	if act == "" {
+		return ""
+	}
+	if id, ok := ResolveCode(act); ok {
+		return id
+	}
+
+	lower := strings.ToLower(act)
+	switch {
+	case strings.Contains(lower, "процессуальн"):
+		return ""
+	case strings.HasPrefix(lower, "гк"), strings.Contains(lower, "гражданск"),
+		strings.HasPrefix(lower, "ак"), strings.Contains(lower, "азаматтық"), strings.Contains(lower, "civil"):
+		return "civil_code_kz"
+	case strings.HasPrefix(lower, "тк"), strings.Contains(lower, "трудов"),
+		strings.HasPrefix(lower, "ек"), strings.Contains(lower, "еңбек"), strings.Contains(lower, "labo"):
+		return "labor_code_kz"
+	case strings.Contains(lower, "конституц"), strings.Contains(lower, "constitution"):
+		return "constitution_kz"
+	}
+	return ""
+}
+
+// Verify сверяет ссылку с текстом кодекса
+func (s *Statutes) Verify(ref Reference) Verification {
+	if ref.Code == "" {
+		if ref.Act == "" {
+			return Verification{Status: models.CitationUnknown, Reason: "не указан нормативный акт"}
+		}
+		return Verification{Status: models.CitationUnknown, Reason: fmt.Sprintf("акт «%s» отсутствует в корпусе", ref.Act)}
+	}
+	code, ok := s.codes[ref.Code]
+	if !ok {
+		return Verification{Status: models.CitationUnknown, Reason: "кодекс не загружен"}
+	}
+
+	article, ok := code.Article(ref.Article)
+	if !ok {
+		if !code.Covers(ref.Article) {
+			return Verification{Status: models.CitationUnknown, Reason: fmt.Sprintf("статья %s вне загруженной части кодекса", ref.Article)}
+		}
+		return Verification{Status: models.CitationMismatched, Reason: fmt.Sprintf("в %s нет статьи %s", code.Source.Short, ref.Article)}
+	}
+
+	v := Verification{Status: models.CitationVerified, Article: article}
+	if ref.Point != "" {
+		point, ok := article.Point(ref.Point)
+		if !ok {
+			v.Status = models.CitationMismatched
+			v.Reason = fmt.Sprintf("в статье %s нет пункта %s", article.Number, ref.Point)
+			return v
+		}
+		v.Point = &point
+	}
+	if ref.Title != "" && !similarTitle(ref.Title, article.Title) {
+		v.Status = models.CitationMismatched
+		v.Reason = fmt.Sprintf("статья %s называется «%s»", article.Number, article.Title)
+	}
+	return v
+}
+
+// Covers сообщает, что номер статьи попадает в диапазон разобранных статей.
+// Корпус может содержать только часть кодекса, и статья за его пределами
+// не считается выдуманной.
+func (c *Code) Covers(number string) bool {
+	if len(c.Articles) == 0 {
+		return false
+	}
+	n, first, last := articleOrdinal(number), articleOrdinal(c.Articles[0].Number), articleOrdinal(c.Articles[len(c.Articles)-1].Number)
+	return n >= first && n <= last
+}
+
+func articleOrdinal(number string) int {
+	main, _, _ := strings.Cut(number, "-")
+	n, _ := strconv.Atoi(main)
+	return n
+}
+
+// similarTitle сообщает, что хотя бы половина слов названия из ссылки есть в названии статьи
+func similarTitle(stated, actual string) bool {
+	want := Tokenize(stated)
+	if len(want) == 0 {
+		return true
+	}
+	have := map[string]bool{}
+	for _, t := range Tokenize(actual) {
+		have[t] = true
+	}
+	matched := 0
+	for _, t := range want {
+		if have[t] {
+			matched++
+		}
+	}
+	return matched*2 >= len(want)
+}
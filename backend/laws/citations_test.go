@@ -0,0 +1,109 @@
+package laws
+
+import (
+	"legally/models"
+	"testing"
+)
+
+func TestExtractReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Reference
+	}{
+		{
+			name: "point and article before act",
+			text: "согласно п. 2 ст. 401 ГК РК",
+			want: []Reference{{Act: "ГК РК", Code: "civil_code_kz", Article: "401", Point: "2"}},
+		},
+		{
+			name: "act before article",
+			text: "Нормативный акт: ГК РК, ст. 404",
+			want: []Reference{{Act: "ГК РК", Code: "civil_code_kz", Article: "404"}},
+		},
+		{
+			name: "article list drops point",
+			text: "п. 1 статьи 52 и 53 Трудового кодекса",
+			want: []Reference{
+				{Act: "Трудового кодекса", Code: "labor_code_kz", Article: "52"},
+				{Act: "Трудового кодекса", Code: "labor_code_kz", Article: "53"},
+			},
+		},
+		{
+			name: "title in quotes",
+			text: "ст. 272 ГК РК («Недопустимость одностороннего отказа»)",
+			want: []Reference{{Act: "ГК РК", Code: "civil_code_kz", Article: "272", Title: "Недопустимость одностороннего отказа"}},
+		},
+		{
+			name: "article without act",
+			text: "см. статью 5",
+			want: []Reference{{Article: "5"}},
+		},
+		{
+			name: "procedural code is not in corpus",
+			text: "ст. 150 Гражданского процессуального кодекса",
+			want: []Reference{{Act: "Гражданского процессуального кодекса", Article: "150"}},
+		},
+		{
+			name: "kazakh",
+			text: "Еңбек кодексінің 52-бабының 2-тармағы",
+			want: []Reference{{Act: "Еңбек кодексінің", Code: "labor_code_kz", Article: "52", Point: "2"}},
+		},
+		{
+			name: "english",
+			text: "paragraph 2 of Article 52 of the Labor Code",
+			want: []Reference{{Act: "Labor Code", Code: "labor_code_kz", Article: "52", Point: "2"}},
+		},
+		{
+			name: "no reference",
+			text: "Договор вступает в силу с момента подписания.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractReferences(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExtractReferences(%q) = %+v, want %d references", tt.text, got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := got[i]
+				got.Text = ""
+				if got != want {
+					t.Errorf("reference %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	statutes := &Statutes{codes: map[string]*Code{
+		"civil_code_kz": ParseStatute(LookupSource("civil_code_kz"), testStatute),
+	}}
+
+	tests := []struct {
+		name string
+		ref  Reference
+		want string
+	}{
+		{"verified", Reference{Code: "civil_code_kz", Article: "1"}, models.CitationVerified},
+		{"verified point", Reference{Code: "civil_code_kz", Article: "1", Point: "2"}, models.CitationVerified},
+		{"verified title", Reference{Code: "civil_code_kz", Article: "2", Title: "Принципы"}, models.CitationVerified},
+		{"missing point", Reference{Code: "civil_code_kz", Article: "1", Point: "7"}, models.CitationMismatched},
+		{"wrong title", Reference{Code: "civil_code_kz", Article: "2", Title: "Исковая давность"}, models.CitationMismatched},
+		{"missing article inside range", Reference{Code: "civil_code_kz", Article: "2-2"}, models.CitationMismatched},
+		{"article outside loaded range", Reference{Code: "civil_code_kz", Article: "401"}, models.CitationUnknown},
+		{"code not loaded", Reference{Act: "ТК РК", Code: "labor_code_kz", Article: "52"}, models.CitationUnknown},
+		{"act not in corpus", Reference{Act: "НК РК", Article: "1"}, models.CitationUnknown},
+		{"no act", Reference{Article: "1"}, models.CitationUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := statutes.Verify(tt.ref)
+			if v.Status != tt.want {
+				t.Errorf("Verify(%+v) = %s (%s), want %s", tt.ref, v.Status, v.Reason, tt.want)
+			}
+		})
+	}
+}
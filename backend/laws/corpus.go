@@ -0,0 +1,153 @@
+// corpus.go
+
+package laws
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source — документ корпуса, из которого нарезаны фрагменты.
+// Short — сокращённое название для ссылок («ГК РК»).
+type Source struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Short string `json:"short,omitempty"`
+	Kind  string `json:"kind"`
+}
+
+// Виды источников корпуса
+const (
+	KindLaw      = "law"
+	KindTemplate = "template"
+)
+
+// knownSources — названия документов корпуса rag/data; неизвестный файл
+// попадает в корпус под своим именем
+var knownSources = map[string]Source{
+	"civil_code_kz":            {Title: "Гражданский кодекс Республики Казахстан", Short: "ГК РК", Kind: KindLaw},
+	"labor_code_kz":            {Title: "Трудовой кодекс Республики Казахстан", Short: "ТК РК", Kind: KindLaw},
+	"constitution_kz":          {Title: "Конституция Республики Казахстан", Short: "Конституция РК", Kind: KindLaw},
+	"rental_contract_template": {Title: "Типовой договор аренды", Kind: KindTemplate},
+	"sales_contract_template":  {Title: "Типовой договор купли-продажи", Kind: KindTemplate},
+}
+
+// Chunk — фрагмент документа корпуса. Articles — номера статей, текст которых
+// попал во фрагмент (первая — статья, на которой фрагмент начинается).
+type Chunk struct {
+	ID       string   `json:"id"`
+	Source   Source   `json:"source"`
+	Index    int      `json:"index"`
+	Articles []string `json:"articles,omitempty"`
+	Text     string   `json:"text"`
+}
+
+var (
+	chunkFileRe = regexp.MustCompile(`^(.+)_chunk_(\d+)\.txt$`)
+	articleRe   = regexp.MustCompile(`(?m)^[ \t]*Статья[ \t]+(\d+(?:-\d+)?)\.?`)
+)
+
+// LookupSource возвращает описание источника по его ID (имени файла без номера фрагмента)
+func LookupSource(id string) Source {
+	s, ok := knownSources[id]
+	if !ok {
+		return Source{ID: id, Title: id, Short: id, Kind: KindLaw}
+	}
+	s.ID = id
+	return s
+}
+
+// LoadChunks читает фрагменты <источник>_chunk_<N>.txt из каталога dir.
+// Фрагменты нарезаны по числу символов и рвут статьи, поэтому номера статей
+// определяются по склеенному тексту источника.
+func LoadChunks(dir string) ([]Chunk, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_chunk_*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("в каталоге %s нет фрагментов корпуса", dir)
+	}
+
+	bySource := map[string][]Chunk{}
+	for _, path := range paths {
+		m := chunkFileRe.FindStringSubmatch(filepath.Base(path))
+		if m == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(m[2])
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		bySource[m[1]] = append(bySource[m[1]], Chunk{
+			ID:     fmt.Sprintf("%s#%d", m[1], index),
+			Source: LookupSource(m[1]),
+			Index:  index,
+			Text:   string(data),
+		})
+	}
+
+	ids := make([]string, 0, len(bySource))
+	for id := range bySource {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var chunks []Chunk
+	for _, id := range ids {
+		list := bySource[id]
+		sort.Slice(list, func(i, j int) bool { return list[i].Index < list[j].Index })
+		assignArticles(list)
+		chunks = append(chunks, list...)
+	}
+	return chunks, nil
+}
+
+// assignArticles проставляет фрагментам одного источника номера статей
+func assignArticles(chunks []Chunk) {
+	var full strings.Builder
+	starts := make([]int, len(chunks))
+	for i, c := range chunks {
+		starts[i] = full.Len()
+		full.WriteString(c.Text)
+	}
+	text := full.String()
+
+	type heading struct {
+		offset int
+		number string
+	}
+	var headings []heading
+	for _, m := range articleRe.FindAllStringSubmatchIndex(text, -1) {
+		headings = append(headings, heading{m[0], text[m[2]:m[3]]})
+	}
+	if len(headings) == 0 {
+		return
+	}
+
+	h := 0
+	current := ""
+	for i := range chunks {
+		end := starts[i] + len(chunks[i].Text)
+		for h < len(headings) && headings[h].offset <= starts[i] {
+			current = headings[h].number
+			h++
+		}
+		var articles []string
+		if current != "" {
+			articles = append(articles, current)
+		}
+		for h < len(headings) && headings[h].offset < end {
+			current = headings[h].number
+			articles = append(articles, current)
+			h++
+		}
+		chunks[i].Articles = articles
+	}
+}
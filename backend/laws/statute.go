@@ -0,0 +1,279 @@
+// statute.go
+
+package laws
+
+import (
+	"fmt"
+	"legally/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Article — статья кодекса с местом в его структуре. Text — текст статьи
+// без сносок и примечаний; Points — пронумерованные пункты статьи.
+type Article struct {
+	Code      string   `json:"code"`
+	Section   string   `json:"section,omitempty"`
+	Chapter   string   `json:"chapter,omitempty"`
+	Paragraph string   `json:"paragraph,omitempty"`
+	Number    string   `json:"number"`
+	Title     string   `json:"title,omitempty"`
+	Points    []Point  `json:"points,omitempty"`
+	Notes     []string `json:"notes,omitempty"`
+	Text      string   `json:"text"`
+}
+
+// Point — пункт статьи; подпункты «1)» остаются в тексте пункта
+type Point struct {
+	Number string `json:"number"`
+	Text   string `json:"text"`
+}
+
+// Code — кодекс (или Конституция), разобранный на статьи
+type Code struct {
+	Source   Source
+	Articles []*Article
+	byNumber map[string]*Article
+}
+
+// Statutes — разобранные кодексы корпуса
+type Statutes struct {
+	codes map[string]*Code
+}
+
+// codeAliases — другие названия кодексов, по которым их можно найти
+// (в ссылках модели и в адресе запроса)
+var codeAliases = map[string][]string{
+	"civil_code_kz":   {"civil", "gk", "гк", "гк рк", "гражданский кодекс", "гражданский кодекс рк", "гражданский кодекс республики казахстан"},
+	"labor_code_kz":   {"labor", "tk", "тк", "тк рк", "трудовой кодекс", "трудовой кодекс рк", "трудовой кодекс республики казахстан"},
+	"constitution_kz": {"constitution", "конституция", "конституция рк", "конституция республики казахстан"},
+}
+
+var (
+	articleHeadingRe = regexp.MustCompile(`^Статья\s+(\d+(?:-\d+)?)\.?\s*(.*)$`)
+	inlineArticleRe  = regexp.MustCompile(`\s(Статья\s+\d+(?:-\d+)?\..*)$`)
+	structureRe      = regexp.MustCompile(`^(?i:(Раздел|Глава|Параграф))\s+[\dIVXLC]+(?:-\d+)?\.?(?:\s|$)`)
+	partRe           = regexp.MustCompile(`^(?:ОБЩАЯ|ОСОБЕННАЯ)\s+ЧАСТЬ$`)
+	pointRe          = regexp.MustCompile(`^(\d+(?:-\d+)?)\.\s+(.*)$`)
+	noteRe           = regexp.MustCompile(`^(?:Сноска|Примечание)(?:[^\p{L}]|$)`)
+)
+
+// ParseStatute разбирает текст кодекса в формате adilet.zan.kz: заголовки
+// разделов, глав и параграфов пишутся с начала строки и могут переноситься
+// на следующую строку, текст статей — с отступом.
+func ParseStatute(source Source, text string) *Code {
+	code := &Code{Source: source, byNumber: map[string]*Article{}}
+
+	var section, chapter, paragraph string
+	var article *Article
+	var body []string
+	// heading указывает на заголовок, к которому относится перенос строки
+	var heading *string
+
+	finish := func() {
+		if article == nil {
+			return
+		}
+		article.Text = strings.TrimSpace(strings.Join(body, "\n"))
+		if _, dup := code.byNumber[article.Number]; !dup {
+			code.byNumber[article.Number] = article
+			code.Articles = append(code.Articles, article)
+		}
+		article, body = nil, nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(raw)
+		indented := unicode.IsSpace(first)
+
+		if !indented {
+			// Заголовок главы и первая статья иногда склеены в одну строку
+			if m := inlineArticleRe.FindStringSubmatchIndex(line); m != nil && structureRe.MatchString(line) {
+				lines[i] = line[m[2]:]
+				line = strings.TrimSpace(line[:m[0]])
+				i--
+			}
+
+			switch {
+			case articleHeadingRe.MatchString(line):
+				finish()
+				m := articleHeadingRe.FindStringSubmatch(line)
+				article = &Article{
+					Code:      source.ID,
+					Section:   section,
+					Chapter:   chapter,
+					Paragraph: paragraph,
+					Number:    m[1],
+					Title:     strings.TrimSpace(m[2]),
+				}
+				heading = nil
+				continue
+			case structureRe.MatchString(line):
+				finish()
+				switch strings.ToLower(structureRe.FindStringSubmatch(line)[1]) {
+				case "раздел":
+					section, chapter, paragraph = line, "", ""
+					heading = &section
+				case "глава":
+					chapter, paragraph = line, ""
+					heading = &chapter
+				default:
+					paragraph = line
+					heading = &paragraph
+				}
+				continue
+			case partRe.MatchString(line):
+				finish()
+				heading = nil
+				continue
+			case heading != nil:
+				*heading += " " + line
+				continue
+			}
+			// Прочие строки без отступа — подзаголовки внутри параграфа и
+			// служебные пометки; в текст статей они не входят
+			continue
+		}
+
+		heading = nil
+		if article == nil {
+			continue
+		}
+		if noteRe.MatchString(line) {
+			article.Notes = append(article.Notes, line)
+			continue
+		}
+		body = append(body, line)
+		if m := pointRe.FindStringSubmatch(line); m != nil {
+			article.Points = append(article.Points, Point{Number: m[1], Text: m[2]})
+		} else if n := len(article.Points); n > 0 {
+			article.Points[n-1].Text += "\n" + line
+		}
+	}
+	finish()
+	return code
+}
+
+// LoadStatutes разбирает кодексы из каталога с исходными текстами корпуса.
+// Ненайденный кодекс пропускается, ошибка о нём возвращается вместе с остальными.
+func LoadStatutes(dir string) (*Statutes, error) {
+	s := &Statutes{codes: map[string]*Code{}}
+	var firstErr error
+
+	ids := make([]string, 0, len(codeAliases))
+	for id := range codeAliases {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		data, err := os.ReadFile(filepath.Join(dir, id+".txt"))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		s.codes[id] = ParseStatute(LookupSource(id), string(data))
+	}
+	return s, firstErr
+}
+
+// ResolveCode возвращает ID кодекса по ID или одному из его названий
+func ResolveCode(name string) (string, bool) {
+	name = strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(name, ".", " "))), " ")
+	for id, aliases := range codeAliases {
+		if name == id {
+			return id, true
+		}
+		for _, a := range aliases {
+			if name == a {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Codes возвращает разобранные кодексы, упорядоченные по ID
+func (s *Statutes) Codes() []*Code {
+	list := make([]*Code, 0, len(s.codes))
+	for _, c := range s.codes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Source.ID < list[j].Source.ID })
+	return list
+}
+
+// Code возвращает кодекс по ID или названию
+func (s *Statutes) Code(name string) (*Code, bool) {
+	id, ok := ResolveCode(name)
+	if !ok {
+		return nil, false
+	}
+	c, ok := s.codes[id]
+	return c, ok
+}
+
+// Article возвращает статью кодекса по номеру
+func (s *Statutes) Article(code, number string) (*Article, bool) {
+	c, ok := s.Code(code)
+	if !ok {
+		return nil, false
+	}
+	return c.Article(number)
+}
+
+// Article возвращает статью по номеру («188», «193-1»)
+func (c *Code) Article(number string) (*Article, bool) {
+	a, ok := c.byNumber[strings.TrimSpace(number)]
+	return a, ok
+}
+
+// Point возвращает пункт статьи по номеру
+func (a *Article) Point(number string) (Point, bool) {
+	number = strings.TrimSpace(number)
+	for _, p := range a.Points {
+		if p.Number == number {
+			return p, true
+		}
+	}
+	return Point{}, false
+}
+
+var (
+	defaultStatutes     *Statutes
+	defaultStatutesOnce sync.Once
+)
+
+// DefaultStatutes возвращает кодексы из каталога LAWS_RAW_DIR (по умолчанию ../rag/data/raw).
+// Если какой-то кодекс не найден, остальные всё равно доступны.
+func DefaultStatutes() *Statutes {
+	defaultStatutesOnce.Do(func() {
+		dir := utils.GetEnv("LAWS_RAW_DIR", "../rag/data/raw")
+		s, err := LoadStatutes(dir)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Ошибка загрузки кодексов: %v", err))
+		}
+		defaultStatutes = s
+
+		total := 0
+		for _, c := range s.codes {
+			total += len(c.Articles)
+		}
+		utils.LogSuccess(fmt.Sprintf("Разобрано %d кодексов, %d статей", len(s.codes), total))
+	})
+	return defaultStatutes
+}
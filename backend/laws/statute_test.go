@@ -0,0 +1,72 @@
+package laws
+
+import "testing"
+
+const testStatute = `ОБЩАЯ ЧАСТЬ
+Раздел 1. Общие
+положения
+Глава 1. Гражданское законодательство Статья 1. Отношения, регулируемые гражданским законодательством
+  1. Гражданское законодательство регулирует товарно-денежные отношения.
+  2. Семейные отношения регулируются гражданским законодательством,
+  если они не урегулированы семейным законодательством.
+  Сноска. Статья 1 с изменениями.
+Статья 2. Принципы
+  Гражданское законодательство основывается на равенстве участников.
+Параграф 1. Общие условия
+Статья 2-1. Применение
+  1) к отношениям с участием иностранцев;
+  2) к отношениям с участием государства.
+`
+
+func TestParseStatute(t *testing.T) {
+	code := ParseStatute(LookupSource("civil_code_kz"), testStatute)
+
+	if len(code.Articles) != 3 {
+		t.Fatalf("parsed %d articles, want 3", len(code.Articles))
+	}
+
+	a, ok := code.Article("1")
+	if !ok {
+		t.Fatal("article 1 not found")
+	}
+	if a.Title != "Отношения, регулируемые гражданским законодательством" {
+		t.Errorf("title = %q", a.Title)
+	}
+	if a.Section != "Раздел 1. Общие положения" || a.Chapter != "Глава 1. Гражданское законодательство" {
+		t.Errorf("section = %q, chapter = %q", a.Section, a.Chapter)
+	}
+	if len(a.Points) != 2 {
+		t.Fatalf("article 1 has %d points, want 2", len(a.Points))
+	}
+	if p, _ := a.Point("2"); p.Text != "Семейные отношения регулируются гражданским законодательством,\nесли они не урегулированы семейным законодательством." {
+		t.Errorf("point 2 = %q", p.Text)
+	}
+	if len(a.Notes) != 1 {
+		t.Errorf("notes = %q", a.Notes)
+	}
+
+	a, ok = code.Article("2-1")
+	if !ok {
+		t.Fatal("article 2-1 not found")
+	}
+	if a.Paragraph != "Параграф 1. Общие условия" || len(a.Points) != 0 {
+		t.Errorf("article 2-1 = %+v", a)
+	}
+}
+
+func TestResolveCode(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"ГК РК", "civil_code_kz"},
+		{"гк. рк.", "civil_code_kz"},
+		{"Трудовой кодекс Республики Казахстан", "labor_code_kz"},
+		{"constitution_kz", "constitution_kz"},
+		{"Налоговый кодекс", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := ResolveCode(tt.name); got != tt.want {
+			t.Errorf("ResolveCode(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
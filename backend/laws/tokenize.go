@@ -0,0 +1,162 @@
+// tokenize.go
+
+package laws
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords — служебные слова, которые не влияют на поиск
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		а без более бы был была были было быть в вам вас весь во вот все всего всех вы где да даже для до его ее её если есть еще ещё же за здесь
+		и из или им их к как какой когда кто ли либо между меня мне может мы на над нам нас не него нее неё нет ни них но ну о об однако он она они оно
+		от очень по под при с со так также такой там те тем то того тоже той только том ту тут у уже чем через что чтобы чье чья эта эти это этого этой этом я
+		настоящего настоящей настоящим настоящий настоящая настоящее`) {
+		stopWords[w] = true
+	}
+}
+
+// Tokenize разбивает текст на нормализованные термы: нижний регистр, «ё» → «е»,
+// без служебных слов, русские слова приведены к основе. Числа сохраняются,
+// чтобы находились номера статей и сроки.
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		w = strings.ReplaceAll(w, "ё", "е")
+		if stopWords[w] {
+			continue
+		}
+		if len([]rune(w)) < 2 && !unicode.IsDigit([]rune(w)[0]) {
+			continue
+		}
+		tokens = append(tokens, Stem(w))
+	}
+	return tokens
+}
+
+// Окончания русского стеммера Snowball (Портер для русского языка).
+// Окончания группы 1 отсекаются, только если перед ними стоит «а» или «я».
+var (
+	perfectiveGerund1 = []string{"вшись", "вши", "в"}
+	perfectiveGerund2 = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+	reflexive         = []string{"ся", "сь"}
+	adjective         = []string{"ими", "ыми", "его", "ого", "ему", "ому", "ее", "ие", "ые", "ое", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	participle1       = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2       = []string{"ивш", "ывш", "ующ"}
+	verb1             = []string{"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть", "й", "л", "н"}
+	verb2             = []string{"ейте", "уйте", "ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено", "ует", "уют", "ены", "ить", "ыть", "ишь", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ят", "ит", "ыт", "ую", "ю"}
+	noun              = []string{"иями", "ями", "ами", "ией", "иям", "ием", "иях", "ев", "ов", "ие", "ье", "еи", "ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья", "а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я"}
+	superlative       = []string{"ейше", "ейш"}
+	derivational      = []string{"ость", "ост"}
+)
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// Stem возвращает основу русского слова по алгоритму Snowball.
+// Слова не на кириллице возвращаются без изменений.
+func Stem(word string) string {
+	w := []rune(word)
+	rv := len(w)
+	for i, r := range w {
+		if isVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	if rv >= len(w) {
+		return word
+	}
+	r2 := regionAfter(w, regionAfter(w, 0))
+
+	// Шаг 1
+	if n := endingAfterAOrYa(w, rv, perfectiveGerund1); n > 0 {
+		w = w[:len(w)-n]
+	} else if n := ending(w, rv, perfectiveGerund2); n > 0 {
+		w = w[:len(w)-n]
+	} else {
+		if n := ending(w, rv, reflexive); n > 0 {
+			w = w[:len(w)-n]
+		}
+		if n := ending(w, rv, adjective); n > 0 {
+			w = w[:len(w)-n]
+			if n := endingAfterAOrYa(w, rv, participle1); n > 0 {
+				w = w[:len(w)-n]
+			} else if n := ending(w, rv, participle2); n > 0 {
+				w = w[:len(w)-n]
+			}
+		} else if n := endingAfterAOrYa(w, rv, verb1); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := ending(w, rv, verb2); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := ending(w, rv, noun); n > 0 {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// Шаг 2
+	if n := ending(w, rv, []string{"и"}); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// Шаг 3
+	if n := ending(w, r2, derivational); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// Шаг 4
+	if n := ending(w, rv, superlative); n > 0 {
+		w = w[:len(w)-n]
+	}
+	switch {
+	case ending(w, rv, []string{"нн"}) > 0:
+		w = w[:len(w)-1]
+	case ending(w, rv, []string{"ь"}) > 0:
+		w = w[:len(w)-1]
+	}
+	return string(w)
+}
+
+// regionAfter возвращает начало области после первого сочетания «гласная + согласная»,
+// начиная с позиции from (области R1 и R2 стеммера)
+func regionAfter(w []rune, from int) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// ending возвращает длину самого длинного окончания из списка, целиком лежащего
+// в области, которая начинается с позиции region; 0 — окончание не найдено
+func ending(w []rune, region int, endings []string) int {
+	best := 0
+	for _, e := range endings {
+		n := len([]rune(e))
+		if n > best && len(w)-n >= region && string(w[len(w)-n:]) == e {
+			best = n
+		}
+	}
+	return best
+}
+
+// endingAfterAOrYa — как ending, но окончание должно следовать за «а» или «я» внутри области
+func endingAfterAOrYa(w []rune, region int, endings []string) int {
+	best := 0
+	for _, e := range endings {
+		n := len([]rune(e))
+		i := len(w) - n
+		if n > best && i-1 >= region && string(w[i:]) == e && (w[i-1] == 'а' || w[i-1] == 'я') {
+			best = n
+		}
+	}
+	return best
+}
@@ -0,0 +1,45 @@
+package laws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"договорами", "договор"},
+		{"аренды", "аренд"},
+		{"работника", "работник"},
+		{"стороны", "сторон"},
+		{"ответственность", "ответствен"},
+		{"расторгнуть", "расторгнут"},
+		{"обязуется", "обяз"},
+		{"contract", "contract"},
+		{"кв", "кв"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Арендатор обязан вносить арендную плату", []string{"арендатор", "обяза", "внос", "арендн", "плат"}},
+		{"Статья 401. Ответственность за нарушение", []string{"стат", "401", "ответствен", "нарушен"}},
+		{"и в на по — а", nil},
+		{"Её ёмкость", []string{"емкост"}},
+		{"срок 3 дня", []string{"срок", "3", "дня"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// errors.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrRateLimited = errors.New("превышен лимит запросов к LLM")
+	ErrTimeout     = errors.New("таймаут запроса к LLM")
+	ErrUpstream    = errors.New("ошибка LLM-провайдера")
+	ErrEmptyAnswer = errors.New("пустой ответ от LLM")
+	ErrCircuitOpen = errors.New("LLM-провайдер временно недоступен")
+)
+
+// Error — типизированная ошибка обращения к провайдеру.
+// Kind — одна из Err* выше, проверяется через errors.Is.
+type Error struct {
+	Kind       error
+	Provider   string
+	Status     int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Provider != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Provider)
+	}
+	if e.Status != 0 {
+		msg = fmt.Sprintf("%s: статус %d", msg, e.Status)
+	}
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Retryable сообщает, имеет ли смысл повторить запрос
+func (e *Error) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimited, ErrTimeout, ErrEmptyAnswer:
+		return true
+	case ErrUpstream:
+		return e.Status == 0 || e.Status >= 500
+	default:
+		return false
+	}
+}
+
+// classifyTransportError превращает ошибку http.Client в типизированную
+func classifyTransportError(provider string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{Kind: ErrTimeout, Provider: provider, Err: err}
+	}
+	return &Error{Kind: ErrUpstream, Provider: provider, Err: err}
+}
+
+// classifyStatus превращает неуспешный HTTP-статус в типизированную ошибку
+func classifyStatus(provider string, resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &Error{Kind: ErrRateLimited, Provider: provider, Status: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusGatewayTimeout:
+		return &Error{Kind: ErrTimeout, Provider: provider, Status: resp.StatusCode}
+	default:
+		return &Error{Kind: ErrUpstream, Provider: provider, Status: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+}
+
+// parseRetryAfter понимает оба формата заголовка: секунды и HTTP-дату
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
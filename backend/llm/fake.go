@@ -0,0 +1,93 @@
+// fake.go
+
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+)
+
+const fakeModel = "fake/deterministic"
+
+// Fake — провайдер без сети: одинаковый запрос всегда даёт одинаковый ответ.
+// Нужен для разработки и тестов AnalyzeText в офлайне.
+type Fake struct {
+	// Respond, если задан, формирует ответ вместо встроенного шаблона
+	Respond func(req Request) (string, error)
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Name() string {
+	return ProviderFake
+}
+
+func (f *Fake) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content := ""
+	if f.Respond != nil {
+		var err error
+		if content, err = f.Respond(req); err != nil {
+			return nil, err
+		}
+	} else {
+		content = fakeAnalysis(lastUserMessage(req))
+	}
+
+	return &Response{Content: content, Model: fakeModel, Usage: fakeUsage(req, content)}, nil
+}
+
+// fakeUsage оценивает токены по длине текста, как это делал бы токенизатор (~3 символа на токен)
+func fakeUsage(req Request, content string) Usage {
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += utf8.RuneCountInString(m.Content)
+	}
+	u := Usage{
+		PromptTokens:     (prompt + 2) / 3,
+		CompletionTokens: (utf8.RuneCountInString(content) + 2) / 3,
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+func lastUserMessage(req Request) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// fakeAnalysis возвращает JSON по схеме анализа; содержимое зависит только от запроса
+func fakeAnalysis(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	digest := hex.EncodeToString(sum[:4])
+
+	return fmt.Sprintf(`{
+  "risks": [
+    {
+      "title": "Тестовый риск %s",
+      "description": "Детерминированный ответ фейкового провайдера (%d символов запроса)",
+      "level": "low",
+      "legal_reference": "Гражданский кодекс РК, ст. 1",
+      "recommendation": "Подключить реальную модель через LLM_PROVIDER"
+    }
+  ],
+  "ambiguities": [],
+  "violations": [],
+  "recommendations": [
+    {"text": "Проверить документ с реальной моделью", "level": "low", "legal_reference": ""}
+  ],
+  "conclusion": "Анализ выполнен фейковым провайдером, отпечаток запроса %s."
+}`, digest, utf8.RuneCountInString(prompt), digest)
+}
@@ -0,0 +1,132 @@
+// openai.go
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"legally/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	openRouterEndpoint = "https://openrouter.ai/api/v1"
+	requestTimeout     = 120 * time.Second
+)
+
+// OpenAICompatible — клиент любого сервера с API /chat/completions
+// (OpenAI, OpenRouter, локальные llama.cpp / vLLM / Ollama)
+type OpenAICompatible struct {
+	name    string
+	baseURL string
+	apiKey  string
+	model   string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewOpenAICompatible(baseURL, apiKey, model string) *OpenAICompatible {
+	return &OpenAICompatible{
+		name:    ProviderOpenAI,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		headers: map[string]string{},
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func NewOpenRouter(apiKey, model string) *OpenAICompatible {
+	p := NewOpenAICompatible(openRouterEndpoint, apiKey, model)
+	p.name = ProviderOpenRouter
+	p.headers["HTTP-Referer"] = "https://legally.kz"
+	p.headers["X-Title"] = "Legally AI Risk Analyzer"
+	return p
+}
+
+func (p *OpenAICompatible) Name() string {
+	return p.name
+}
+
+func (p *OpenAICompatible) Model() string {
+	return p.model
+}
+
+func (p *OpenAICompatible) Complete(ctx context.Context, req Request) (*Response, error) {
+	payload := map[string]interface{}{
+		"model":       p.model,
+		"messages":    req.Messages,
+		"temperature": req.Temperature,
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга payload: %w", err)
+	}
+
+	endpoint := p.baseURL + "/chat/completions"
+	utils.LogRequest("out", endpoint, len(body))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for k, v := range p.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, classifyTransportError(p.name, err)
+	}
+	defer resp.Body.Close()
+
+	resBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, classifyTransportError(p.name, err)
+	}
+
+	utils.LogRequest("in", fmt.Sprintf("%s (статус: %d)", p.name, resp.StatusCode), len(resBody))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, classifyStatus(p.name, resp)
+	}
+
+	var res struct {
+		Model   string `json:"model"`
+		Usage   Usage  `json:"usage"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+
+	if err := json.Unmarshal(resBody, &res); err != nil {
+		return nil, &Error{Kind: ErrUpstream, Provider: p.name, Status: resp.StatusCode, Err: fmt.Errorf("не удалось распарсить ответ AI: %w", err)}
+	}
+
+	if len(res.Choices) == 0 || res.Choices[0].Message.Content == "" {
+		return nil, &Error{Kind: ErrEmptyAnswer, Provider: p.name}
+	}
+
+	model := res.Model
+	if model == "" {
+		model = p.model
+	}
+
+	return &Response{Content: res.Choices[0].Message.Content, Model: model, Usage: res.Usage}, nil
+}
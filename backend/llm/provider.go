@@ -0,0 +1,145 @@
+// provider.go
+
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderOpenAI     = "openai"
+	ProviderFake       = "fake"
+
+	defaultOpenRouterModel = "deepseek/deepseek-r1-0528:free"
+	defaultOpenAIModel     = "gpt-4o-mini"
+)
+
+// Message — одно сообщение диалога в формате chat completions
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request описывает запрос к модели независимо от провайдера
+type Request struct {
+	Messages    []Message
+	Temperature float64
+	MaxTokens   int
+}
+
+// Usage — расход токенов на один запрос
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Response — ответ модели
+type Response struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// Provider — источник ответов LLM (OpenRouter, OpenAI-совместимый сервер, фейк)
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+var (
+	current   Provider
+	currentMu sync.RWMutex
+)
+
+// Init создаёт провайдера по переменным окружения и делает его текущим
+func Init() error {
+	p, err := NewFromEnv()
+	if err != nil {
+		return err
+	}
+	SetProvider(p)
+	return nil
+}
+
+// SetProvider подменяет текущего провайдера (например, фейком в тестах)
+func SetProvider(p Provider) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = p
+}
+
+// Current возвращает текущего провайдера, при необходимости инициализируя его из окружения
+func Current() (Provider, error) {
+	currentMu.RLock()
+	p := current
+	currentMu.RUnlock()
+	if p != nil {
+		return p, nil
+	}
+
+	p, err := NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	SetProvider(p)
+	return p, nil
+}
+
+// NewFromEnv создаёт провайдера по окружению и оборачивает его повторами и выключателем
+func NewFromEnv() (Provider, error) {
+	p, err := newBaseFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewResilient(p, RetryPolicyFromEnv(), CircuitBreakerFromEnv()), nil
+}
+
+// newBaseFromEnv выбирает провайдера по LLM_PROVIDER:
+//   - openrouter (по умолчанию) — OPENROUTER_API_KEY, LLM_MODEL
+//   - openai — LLM_BASE_URL, LLM_API_KEY (может быть пустым для локального сервера), LLM_MODEL
+//   - fake — детерминированные ответы без сети
+func newBaseFromEnv() (Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+	model := os.Getenv("LLM_MODEL")
+
+	switch name {
+	case "", ProviderOpenRouter:
+		apiKey := os.Getenv("OPENROUTER_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENROUTER_API_KEY не установлен")
+		}
+		if model == "" {
+			model = defaultOpenRouterModel
+		}
+		return NewOpenRouter(apiKey, model), nil
+	case ProviderOpenAI:
+		baseURL := os.Getenv("LLM_BASE_URL")
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		if model == "" {
+			model = defaultOpenAIModel
+		}
+		return NewOpenAICompatible(baseURL, os.Getenv("LLM_API_KEY"), model), nil
+	case ProviderFake:
+		return NewFake(), nil
+	default:
+		return nil, fmt.Errorf("неизвестный LLM_PROVIDER: %s", name)
+	}
+}
+
+// RequiredEnv возвращает переменные окружения, без которых выбранный провайдер не заработает
+func RequiredEnv() []string {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))) {
+	case "", ProviderOpenRouter:
+		return []string{"OPENROUTER_API_KEY"}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,212 @@
+// resilience.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/utils"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy — параметры повторов с экспоненциальной задержкой и джиттером
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// RetryPolicyFromEnv читает LLM_MAX_ATTEMPTS, LLM_RETRY_BASE_MS, LLM_RETRY_MAX_MS
+func RetryPolicyFromEnv() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: utils.GetEnvInt("LLM_MAX_ATTEMPTS", 4),
+		BaseDelay:   time.Duration(utils.GetEnvInt("LLM_RETRY_BASE_MS", 1000)) * time.Millisecond,
+		MaxDelay:    time.Duration(utils.GetEnvInt("LLM_RETRY_MAX_MS", 30000)) * time.Millisecond,
+	}
+}
+
+// backoff возвращает задержку перед попыткой attempt (с нуля): случайную в [0, base*2^attempt]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker размыкается после Threshold подряд идущих сбоев провайдера
+// и в течение Cooldown отклоняет запросы сразу. Затем пропускает один пробный запрос.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreakerFromEnv читает LLM_BREAKER_THRESHOLD и LLM_BREAKER_COOLDOWN_SEC
+func CircuitBreakerFromEnv() *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: utils.GetEnvInt("LLM_BREAKER_THRESHOLD", 5),
+		Cooldown:  time.Duration(utils.GetEnvInt("LLM_BREAKER_COOLDOWN_SEC", 30)) * time.Second,
+	}
+}
+
+// allow возвращает оставшееся время блокировки, если запрос пропускать нельзя
+func (b *CircuitBreaker) allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if remaining := b.Cooldown - time.Since(b.openedAt); remaining > 0 {
+			return remaining, false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return 0, true
+	case breakerHalfOpen:
+		if b.probing {
+			return b.Cooldown, false
+		}
+		b.probing = true
+		return 0, true
+	default:
+		return 0, true
+	}
+}
+
+// release завершает пробный запрос, не меняя состояния
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.Threshold > 0 && b.failures >= b.Threshold) {
+		if b.state != breakerOpen {
+			utils.LogWarning(fmt.Sprintf("LLM-провайдер недоступен, запросы блокируются на %v", b.Cooldown))
+		}
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Resilient оборачивает провайдера повторами и автоматическим выключателем
+type Resilient struct {
+	Provider
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+}
+
+func NewResilient(p Provider, policy RetryPolicy, breaker *CircuitBreaker) *Resilient {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Resilient{Provider: p, policy: policy, breaker: breaker}
+}
+
+func (r *Resilient) Complete(ctx context.Context, req Request) (*Response, error) {
+	return r.retry(ctx, func() (*Response, bool, error) {
+		resp, err := r.Provider.Complete(ctx, req)
+		return resp, true, err
+	})
+}
+
+// Stream повторяет запрос только пока клиенту не отдан ни один фрагмент:
+// после этого повтор привёл бы к дублированию текста
+func (r *Resilient) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	return r.retry(ctx, func() (*Response, bool, error) {
+		emitted := false
+		resp, err := Stream(ctx, r.Provider, req, func(delta string) {
+			emitted = true
+			onDelta(delta)
+		})
+		return resp, !emitted, err
+	})
+}
+
+// retry выполняет call с повторами; call сообщает, допустим ли повтор после неудачи
+func (r *Resilient) retry(ctx context.Context, call func() (*Response, bool, error)) (*Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < r.policy.MaxAttempts; attempt++ {
+		if r.breaker != nil {
+			if wait, ok := r.breaker.allow(); !ok {
+				return nil, &Error{Kind: ErrCircuitOpen, Provider: r.Name(), RetryAfter: wait, Err: lastErr}
+			}
+		}
+
+		resp, repeatable, err := call()
+		if err == nil {
+			if r.breaker != nil {
+				r.breaker.record(true)
+			}
+			return resp, nil
+		}
+		lastErr = err
+
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || !llmErr.Retryable() {
+			// отмена и ошибки запроса (4xx) не говорят о недоступности провайдера
+			if r.breaker != nil {
+				r.breaker.release()
+			}
+			return nil, err
+		}
+		if r.breaker != nil {
+			r.breaker.record(false)
+		}
+
+		if !repeatable || attempt == r.policy.MaxAttempts-1 {
+			break
+		}
+
+		delay := r.policy.backoff(attempt)
+		if llmErr.RetryAfter > 0 {
+			if llmErr.RetryAfter > r.policy.MaxDelay {
+				return nil, err
+			}
+			delay = llmErr.RetryAfter
+		}
+
+		utils.LogWarning(fmt.Sprintf("Попытка %d/%d к LLM не удалась (%v), повтор через %v", attempt+1, r.policy.MaxAttempts, err, delay.Round(time.Millisecond)))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return nil, lastErr
+}
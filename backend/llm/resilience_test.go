@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flaky возвращает ошибки из errs по очереди, затем успешный ответ
+type flaky struct {
+	errs  []error
+	calls int
+}
+
+func (f *flaky) Name() string { return "flaky" }
+
+func (f *flaky) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &Response{Content: "ok"}, nil
+}
+
+var (
+	rateLimited = &Error{Kind: ErrRateLimited}
+	badGateway  = &Error{Kind: ErrUpstream, Status: 502}
+	badRequest  = &Error{Kind: ErrUpstream, Status: 400}
+)
+
+func TestResilientRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error // nil — ожидается успешный ответ
+	}{
+		{"success", nil, 1, nil},
+		{"recovers after retryable errors", []error{rateLimited, badGateway}, 3, nil},
+		{"gives up after max attempts", []error{badGateway, badGateway, badGateway}, 3, ErrUpstream},
+		{"client error is not retried", []error{badRequest}, 1, ErrUpstream},
+		{"retry-after above max delay", []error{&Error{Kind: ErrRateLimited, RetryAfter: time.Minute}}, 1, ErrRateLimited},
+		{"cancellation is not retried", []error{context.Canceled}, 1, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &flaky{errs: tt.errs}
+			resp, err := NewResilient(p, policy, nil).Complete(context.Background(), Request{})
+
+			if p.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", p.calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || resp.Content != "ok" {
+				t.Errorf("Complete() = %v, %v", resp, err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := &CircuitBreaker{Threshold: 2, Cooldown: 20 * time.Millisecond}
+	p := &flaky{errs: []error{badGateway, badGateway, badGateway}}
+	r := NewResilient(p, RetryPolicy{MaxAttempts: 1}, breaker)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Complete(ctx, Request{}); !errors.Is(err, ErrUpstream) {
+			t.Fatalf("call %d: err = %v, want upstream error", i+1, err)
+		}
+	}
+
+	// Выключатель разомкнут: провайдер не вызывается
+	_, err := r.Complete(ctx, Request{})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCircuitOpen || llmErr.RetryAfter <= 0 {
+		t.Fatalf("err = %v, want open circuit", err)
+	}
+	if p.calls != 2 {
+		t.Errorf("calls = %d while open, want 2", p.calls)
+	}
+
+	// Неудачный пробный запрос снова размыкает выключатель
+	time.Sleep(breaker.Cooldown)
+	if _, err := r.Complete(ctx, Request{}); !errors.Is(err, ErrUpstream) {
+		t.Fatalf("probe: err = %v, want upstream error", err)
+	}
+	if _, err := r.Complete(ctx, Request{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after failed probe: err = %v, want open circuit", err)
+	}
+
+	// Удачный пробный запрос замыкает его
+	time.Sleep(breaker.Cooldown)
+	if resp, err := r.Complete(ctx, Request{}); err != nil || resp.Content != "ok" {
+		t.Fatalf("probe: %v, %v", resp, err)
+	}
+	if _, err := r.Complete(ctx, Request{}); err != nil {
+		t.Errorf("after successful probe: err = %v", err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt := 0; attempt < 10; attempt++ {
+		if d := p.backoff(attempt); d < 0 || d > p.MaxDelay {
+			t.Errorf("backoff(%d) = %v, want within [0, %v]", attempt, d, p.MaxDelay)
+		}
+	}
+}
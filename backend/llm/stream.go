@@ -0,0 +1,148 @@
+// stream.go
+
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"legally/utils"
+	"net/http"
+	"strings"
+)
+
+// StreamingProvider — провайдер, умеющий отдавать ответ по мере генерации
+type StreamingProvider interface {
+	Provider
+	Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error)
+}
+
+// Stream вызывает потоковый режим провайдера, а если его нет —
+// обычный запрос, отдавая ответ одним фрагментом
+func Stream(ctx context.Context, p Provider, req Request, onDelta func(string)) (*Response, error) {
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.Stream(ctx, req, onDelta)
+	}
+
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	onDelta(resp.Content)
+	return resp, nil
+}
+
+func (p *OpenAICompatible) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	payload := map[string]interface{}{
+		"model":       p.model,
+		"messages":    req.Messages,
+		"temperature": req.Temperature,
+		"stream":      true,
+		// расход токенов приходит последним фрагментом потока
+		"stream_options": map[string]bool{"include_usage": true},
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга payload: %w", err)
+	}
+
+	endpoint := p.baseURL + "/chat/completions"
+	utils.LogRequest("out", endpoint+" (stream)", len(body))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for k, v := range p.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	// Общий таймаут клиента оборвал бы длинный поток, поэтому здесь ограничиваемся контекстом
+	client := &http.Client{Transport: p.client.Transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, classifyTransportError(p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, classifyStatus(p.name, resp)
+	}
+
+	var content strings.Builder
+	var usage Usage
+	model := p.model
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Model   string `json:"model"`
+			Usage   *Usage `json:"usage"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, classifyTransportError(p.name, err)
+	}
+
+	utils.LogRequest("in", fmt.Sprintf("%s (stream, статус: %d)", p.name, resp.StatusCode), content.Len())
+
+	if content.Len() == 0 {
+		return nil, &Error{Kind: ErrEmptyAnswer, Provider: p.name}
+	}
+	return &Response{Content: content.String(), Model: model, Usage: usage}, nil
+}
+
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	resp, err := f.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		onDelta(word)
+	}
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseServer отдаёт строки lines как поток Server-Sent Events
+func sseServer(t *testing.T, status int, lines ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(status)
+		for _, l := range lines {
+			fmt.Fprintf(w, "%s\n\n", l)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAICompatibleStream(t *testing.T) {
+	server := sseServer(t, http.StatusOK,
+		`: keep-alive`,
+		`data: {"model": "gpt-4o-mini-2024", "choices": [{"delta": {"role": "assistant"}}]}`,
+		`data: {"choices": [{"delta": {"content": "Договор "}}]}`,
+		`data: not json`,
+		`data: {"choices": [{"delta": {"content": "требует доработки"}}]}`,
+		`data: {"choices": [], "usage": {"prompt_tokens": 10, "completion_tokens": 4, "total_tokens": 14}}`,
+		`data: [DONE]`,
+		`data: {"choices": [{"delta": {"content": "после конца"}}]}`,
+	)
+
+	var deltas []string
+	resp, err := NewOpenAICompatible(server.URL, "key", "gpt-4o-mini").Stream(context.Background(), Request{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "Договор требует доработки" || strings.Join(deltas, "|") != "Договор |требует доработки" {
+		t.Errorf("content = %q, deltas = %q", resp.Content, deltas)
+	}
+	if resp.Model != "gpt-4o-mini-2024" || resp.Usage.TotalTokens != 14 {
+		t.Errorf("model = %q, usage = %+v", resp.Model, resp.Usage)
+	}
+}
+
+func TestOpenAICompatibleStreamErrors(t *testing.T) {
+	empty := sseServer(t, http.StatusOK, `data: {"choices": [{"delta": {}}]}`, `data: [DONE]`)
+	_, err := NewOpenAICompatible(empty.URL, "", "m").Stream(context.Background(), Request{}, func(string) {})
+	if !errors.Is(err, ErrEmptyAnswer) {
+		t.Errorf("empty stream error = %v", err)
+	}
+
+	limited := sseServer(t, http.StatusTooManyRequests)
+	_, err = NewOpenAICompatible(limited.URL, "", "m").Stream(context.Background(), Request{}, func(string) {})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("429 error = %v", err)
+	}
+}
+
+func TestStreamFallback(t *testing.T) {
+	// Провайдер без потокового режима отдаёт ответ одним фрагментом
+	var deltas []string
+	resp, err := Stream(context.Background(), &flaky{}, Request{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil || resp.Content != "ok" || len(deltas) != 1 || deltas[0] != "ok" {
+		t.Errorf("Stream() = %+v, %v; deltas %q", resp, err, deltas)
+	}
+
+	// Фейковый провайдер отдаёт ответ по словам
+	deltas = nil
+	fake := &Fake{Respond: func(Request) (string, error) { return "один два три", nil }}
+	if _, err := Stream(context.Background(), fake, Request{}, func(d string) { deltas = append(deltas, d) }); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "") != "один два три" || len(deltas) != 3 {
+		t.Errorf("fake deltas = %q", deltas)
+	}
+}
@@ -8,6 +8,11 @@ import (
 	"github.com/joho/godotenv"
 	"legally/api"
 	"legally/db"
+	"legally/laws"
+	"legally/llm"
+	"legally/services"
+	"legally/utils"
+	"legally/vectors"
 	"log"
 	"net/http"
 	"os"
@@ -20,14 +25,24 @@ func main() {
 	_ = godotenv.Load()
 	checkEnvVars()
 	db.InitMongo()
+	services.InitJobs()
 
-	if err := os.MkdirAll("./temp", os.ModePerm); err != nil {
+	if err := llm.Init(); err != nil {
+		log.Fatal("❌ ERROR: Не удалось инициализировать LLM-провайдера:", err)
+	}
+
+	if err := utils.InitTempFiles(); err != nil {
 		log.Fatal("❌ ERROR: Не удалось создать временную папку:", err)
 	}
 
+	// Индекс корпуса законов, кодексы и векторное хранилище загружаются в фоне, чтобы не задерживать запуск
+	go laws.Default()
+	go laws.DefaultStatutes()
+	go vectors.Default()
+
 	router := gin.Default()
 	api.SetupRoutes(router)
-	
+
 	router.Use(func(c *gin.Context) {
 		log.Printf("Incoming request: %s %s", c.Request.Method, c.Request.URL.Path)
 		c.Next()
@@ -65,7 +80,7 @@ func main() {
 }
 
 func checkEnvVars() {
-	required := []string{"MONGO_URI", "OPENROUTER_API_KEY"}
+	required := append([]string{"MONGO_URI"}, llm.RequiredEnv()...)
 	for _, env := range required {
 		if os.Getenv(env) == "" {
 			log.Fatalf("❌ ERROR: Необходимо установить переменную окружения %s", env)
@@ -2,12 +2,627 @@
 
 package models
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RiskLevel string
+
+const (
+	LevelHigh   RiskLevel = "high"
+	LevelMedium RiskLevel = "medium"
+	LevelLow    RiskLevel = "low"
+)
+
+// ParseRiskLevel понимает как английские значения схемы, так и русские и казахские из markdown-ответов
+func ParseRiskLevel(s string) (RiskLevel, bool) {
+	s = strings.ToLower(strings.Trim(strings.TrimSpace(s), ".*"))
+	switch {
+	case s == string(LevelHigh), strings.HasPrefix(s, "высок"), strings.HasPrefix(s, "критич"), strings.HasPrefix(s, "жоғары"):
+		return LevelHigh, true
+	case s == string(LevelMedium), strings.HasPrefix(s, "средн"), strings.HasPrefix(s, "умерен"), strings.HasPrefix(s, "орташа"):
+		return LevelMedium, true
+	case s == string(LevelLow), strings.HasPrefix(s, "низк"), strings.HasPrefix(s, "незначит"), strings.HasPrefix(s, "төмен"):
+		return LevelLow, true
+	default:
+		return RiskLevel(s), false
+	}
+}
+
+func (l RiskLevel) Valid() bool {
+	return l == LevelHigh || l == LevelMedium || l == LevelLow
+}
+
+// LabelIn возвращает название уровня на языке отчёта (ru, kk, en)
+func (l RiskLevel) LabelIn(lang string) string {
+	labels := markdownLabelsFor(lang)
+	switch l {
+	case LevelHigh:
+		return labels.high
+	case LevelMedium:
+		return labels.medium
+	case LevelLow:
+		return labels.low
+	default:
+		return string(l)
+	}
+}
+
+// Label возвращает русское название уровня для отображения
+func (l RiskLevel) Label() string {
+	return l.LabelIn("ru")
+}
+
+type Risk struct {
+	Title          string    `bson:"title" json:"title"`
+	Description    string    `bson:"description" json:"description"`
+	Level          RiskLevel `bson:"level" json:"level"`
+	LegalReference string    `bson:"legal_reference" json:"legal_reference"`
+	Recommendation string    `bson:"recommendation" json:"recommendation"`
+	Section        string    `bson:"section,omitempty" json:"section,omitempty"`
+	Page           int       `bson:"page,omitempty" json:"page,omitempty"`
+}
+
+type Ambiguity struct {
+	Wording        string    `bson:"wording" json:"wording"`
+	Problem        string    `bson:"problem" json:"problem"`
+	Level          RiskLevel `bson:"level" json:"level"`
+	LegalReference string    `bson:"legal_reference" json:"legal_reference"`
+	Recommendation string    `bson:"recommendation" json:"recommendation"`
+	Section        string    `bson:"section,omitempty" json:"section,omitempty"`
+	Page           int       `bson:"page,omitempty" json:"page,omitempty"`
+}
+
+type Violation struct {
+	Description    string    `bson:"description" json:"description"`
+	Consequences   string    `bson:"consequences" json:"consequences"`
+	Level          RiskLevel `bson:"level" json:"level"`
+	LegalReference string    `bson:"legal_reference" json:"legal_reference"`
+	Recommendation string    `bson:"recommendation" json:"recommendation"`
+	Section        string    `bson:"section,omitempty" json:"section,omitempty"`
+	Page           int       `bson:"page,omitempty" json:"page,omitempty"`
+}
+
+type Recommendation struct {
+	Text           string    `bson:"text" json:"text"`
+	Level          RiskLevel `bson:"level" json:"level"`
+	LegalReference string    `bson:"legal_reference" json:"legal_reference"`
+}
+
+// AnalysisResult — структурированный результат анализа документа
+type AnalysisResult struct {
+	Risks           []Risk           `bson:"risks" json:"risks"`
+	Ambiguities     []Ambiguity      `bson:"ambiguities" json:"ambiguities"`
+	Violations      []Violation      `bson:"violations" json:"violations"`
+	Recommendations []Recommendation `bson:"recommendations" json:"recommendations"`
+	Conclusion      string           `bson:"conclusion" json:"conclusion"`
+}
+
+const (
+	PartStatusDone   = "done"
+	PartStatusFailed = "failed"
+)
+
+// PartStatus — итог анализа одной части документа
+type PartStatus struct {
+	Index  int    `bson:"index" json:"index"`
+	Status string `bson:"status" json:"status"`
+	Error  string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Page — страница исходного документа. Start и End — смещения в символах
+// извлечённого текста, End не включается.
+type Page struct {
+	Number int `bson:"number" json:"number"`
+	Start  int `bson:"start" json:"start"`
+	End    int `bson:"end" json:"end"`
+}
+
+// PageAt возвращает номер страницы, на которую приходится смещение, или 0
+func PageAt(pages []Page, offset int) int {
+	i := sort.Search(len(pages), func(i int) bool { return pages[i].End > offset })
+	if i < len(pages) && pages[i].Start <= offset {
+		return pages[i].Number
+	}
+	return 0
+}
+
+const (
+	BundleFileDone    = "done"
+	BundleFileFailed  = "failed"
+	BundleFileSkipped = "skipped"
+)
+
+// BundleFile — итог обработки одного документа из архива
+type BundleFile struct {
+	Filename   string              `bson:"filename" json:"filename"`
+	Status     string              `bson:"status" json:"status"`
+	Error      string              `bson:"error,omitempty" json:"error,omitempty"`
+	DocType    string              `bson:"doc_type,omitempty" json:"doc_type,omitempty"`
+	AnalysisID *primitive.ObjectID `bson:"analysis_id,omitempty" json:"analysis_id,omitempty"`
+}
+
+// PromptRef — шаблон промпта, которым получен анализ
+type PromptRef struct {
+	ID       string `bson:"id" json:"id"`
+	Version  int    `bson:"version" json:"version"`
+	Language string `bson:"language" json:"language"`
+	DocType  string `bson:"doc_type" json:"doc_type"`
+}
+
+// DocTypeScore — тип документа и уверенность классификатора в нём (0..1)
+type DocTypeScore struct {
+	ID         string  `bson:"id" json:"id"`
+	Label      string  `bson:"label" json:"label"`
+	Confidence float64 `bson:"confidence" json:"confidence"`
+}
+
+// Classification — итог определения типа документа. Если тип указал пользователь,
+// Overridden выставлен, а Detected хранит ответ классификатора.
+type Classification struct {
+	DocTypeScore `bson:",inline"`
+	RunnerUps    []DocTypeScore `bson:"runner_ups,omitempty" json:"runner_ups,omitempty"`
+	Overridden   bool           `bson:"overridden,omitempty" json:"overridden,omitempty"`
+	Detected     *DocTypeScore  `bson:"detected,omitempty" json:"detected,omitempty"`
+}
 
 type Analysis struct {
-	Filename  string    `bson:"filename" json:"filename"`
-	Type      string    `bson:"type" json:"type"`
-	Analysis  string    `bson:"analysis" json:"analysis"`
-	Text      string    `bson:"text" json:"text"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID  `bson:"user_id" json:"-"`
+	Filename         string              `bson:"filename" json:"filename"`
+	Type             string              `bson:"type" json:"type"`
+	Analysis         string              `bson:"analysis" json:"analysis"`
+	Result           *AnalysisResult     `bson:"result,omitempty" json:"result,omitempty"`
+	Parts            []PartStatus        `bson:"parts,omitempty" json:"parts,omitempty"`
+	Usage            *UsageSummary       `bson:"usage,omitempty" json:"usage,omitempty"`
+	Prompts          []PromptRef         `bson:"prompts,omitempty" json:"prompts,omitempty"`
+	Pages            []Page              `bson:"pages,omitempty" json:"pages,omitempty"`
+	Files            []BundleFile        `bson:"files,omitempty" json:"files,omitempty"`
+	ContentHash      string              `bson:"content_hash,omitempty" json:"content_hash,omitempty"`
+	Language         string              `bson:"language,omitempty" json:"language,omitempty"`
+	ResponseLanguage string              `bson:"response_language,omitempty" json:"response_language,omitempty"`
+	Redaction        *RedactionReport    `bson:"redaction,omitempty" json:"redaction,omitempty"`
+	Metadata         *ContractMetadata   `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Classification   *Classification     `bson:"classification,omitempty" json:"classification,omitempty"`
+	LegalSources     []LegalSource       `bson:"legal_sources,omitempty" json:"legal_sources,omitempty"`
+	Citations        []Citation          `bson:"citations,omitempty" json:"citations,omitempty"`
+	BundleID         *primitive.ObjectID `bson:"bundle_id,omitempty" json:"bundle_id,omitempty"`
+	Text             string              `bson:"text" json:"text"`
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// IsBundle сообщает, что запись объединяет документы архива. Тип записи
+// пользователь может изменить, поэтому архив узнаётся по списку файлов.
+func (a *Analysis) IsBundle() bool {
+	return len(a.Files) > 0
+}
+
+// IsEmpty сообщает, что в результате нет ни одной находки и заключения
+func (r *AnalysisResult) IsEmpty() bool {
+	return len(r.Risks) == 0 && len(r.Ambiguities) == 0 && len(r.Violations) == 0 &&
+		len(r.Recommendations) == 0 && strings.TrimSpace(r.Conclusion) == ""
+}
+
+// AttributeSection проставляет раздел находкам, у которых он не указан
+func (r *AnalysisResult) AttributeSection(section string) {
+	for i := range r.Risks {
+		if r.Risks[i].Section == "" {
+			r.Risks[i].Section = section
+		}
+	}
+	for i := range r.Ambiguities {
+		if r.Ambiguities[i].Section == "" {
+			r.Ambiguities[i].Section = section
+		}
+	}
+	for i := range r.Violations {
+		if r.Violations[i].Section == "" {
+			r.Violations[i].Section = section
+		}
+	}
+}
+
+// AttributePage проставляет страницу находкам, у которых она не указана
+func (r *AnalysisResult) AttributePage(page int) {
+	for i := range r.Risks {
+		if r.Risks[i].Page == 0 {
+			r.Risks[i].Page = page
+		}
+	}
+	for i := range r.Ambiguities {
+		if r.Ambiguities[i].Page == 0 {
+			r.Ambiguities[i].Page = page
+		}
+	}
+	for i := range r.Violations {
+		if r.Violations[i].Page == 0 {
+			r.Violations[i].Page = page
+		}
+	}
+}
+
+// Normalize обрезает пробелы и приводит уровни к значениям схемы
+func (r *AnalysisResult) Normalize() {
+	level := func(l RiskLevel) RiskLevel {
+		parsed, _ := ParseRiskLevel(string(l))
+		return parsed
+	}
+
+	for i := range r.Risks {
+		it := &r.Risks[i]
+		it.Title, it.Description = strings.TrimSpace(it.Title), strings.TrimSpace(it.Description)
+		it.Section = strings.TrimSpace(it.Section)
+		it.LegalReference, it.Recommendation = strings.TrimSpace(it.LegalReference), strings.TrimSpace(it.Recommendation)
+		it.Level = level(it.Level)
+	}
+	for i := range r.Ambiguities {
+		it := &r.Ambiguities[i]
+		it.Wording, it.Problem = strings.TrimSpace(it.Wording), strings.TrimSpace(it.Problem)
+		it.Section = strings.TrimSpace(it.Section)
+		it.LegalReference, it.Recommendation = strings.TrimSpace(it.LegalReference), strings.TrimSpace(it.Recommendation)
+		it.Level = level(it.Level)
+	}
+	for i := range r.Violations {
+		it := &r.Violations[i]
+		it.Description, it.Consequences = strings.TrimSpace(it.Description), strings.TrimSpace(it.Consequences)
+		it.Section = strings.TrimSpace(it.Section)
+		it.LegalReference, it.Recommendation = strings.TrimSpace(it.LegalReference), strings.TrimSpace(it.Recommendation)
+		it.Level = level(it.Level)
+	}
+	for i := range r.Recommendations {
+		it := &r.Recommendations[i]
+		it.Text, it.LegalReference = strings.TrimSpace(it.Text), strings.TrimSpace(it.LegalReference)
+		it.Level = level(it.Level)
+	}
+	r.Conclusion = strings.TrimSpace(r.Conclusion)
+}
+
+// MapText применяет fn ко всем текстовым полям находок и заключению
+func (r *AnalysisResult) MapText(fn func(string) string) {
+	for i := range r.Risks {
+		it := &r.Risks[i]
+		it.Title, it.Description = fn(it.Title), fn(it.Description)
+		it.Section = fn(it.Section)
+		it.LegalReference, it.Recommendation = fn(it.LegalReference), fn(it.Recommendation)
+	}
+	for i := range r.Ambiguities {
+		it := &r.Ambiguities[i]
+		it.Wording, it.Problem = fn(it.Wording), fn(it.Problem)
+		it.Section = fn(it.Section)
+		it.LegalReference, it.Recommendation = fn(it.LegalReference), fn(it.Recommendation)
+	}
+	for i := range r.Violations {
+		it := &r.Violations[i]
+		it.Description, it.Consequences = fn(it.Description), fn(it.Consequences)
+		it.Section = fn(it.Section)
+		it.LegalReference, it.Recommendation = fn(it.LegalReference), fn(it.Recommendation)
+	}
+	for i := range r.Recommendations {
+		it := &r.Recommendations[i]
+		it.Text, it.LegalReference = fn(it.Text), fn(it.LegalReference)
+	}
+	r.Conclusion = fn(r.Conclusion)
+}
+
+// WalkText передаёт fn текстовые поля находок и заключение вместе с путём поля
+// («risks[0].legal_reference»); пустые поля пропускаются
+func (r *AnalysisResult) WalkText(fn func(path, text string)) {
+	visit := func(prefix string, i int, fields map[string]string) {
+		for _, name := range []string{"title", "wording", "problem", "description", "consequences", "text", "legal_reference", "recommendation"} {
+			if text, ok := fields[name]; ok && text != "" {
+				fn(fmt.Sprintf("%s[%d].%s", prefix, i, name), text)
+			}
+		}
+	}
+	for i, it := range r.Risks {
+		visit("risks", i, map[string]string{"title": it.Title, "description": it.Description, "legal_reference": it.LegalReference, "recommendation": it.Recommendation})
+	}
+	for i, it := range r.Ambiguities {
+		visit("ambiguities", i, map[string]string{"wording": it.Wording, "problem": it.Problem, "legal_reference": it.LegalReference, "recommendation": it.Recommendation})
+	}
+	for i, it := range r.Violations {
+		visit("violations", i, map[string]string{"description": it.Description, "consequences": it.Consequences, "legal_reference": it.LegalReference, "recommendation": it.Recommendation})
+	}
+	for i, it := range r.Recommendations {
+		visit("recommendations", i, map[string]string{"text": it.Text, "legal_reference": it.LegalReference})
+	}
+	if r.Conclusion != "" {
+		fn("conclusion", r.Conclusion)
+	}
+}
+
+// Validate проверяет обязательные поля и уровни всех находок
+func (r *AnalysisResult) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for i, it := range r.Risks {
+		check(it.Title != "" || it.Description != "", "risks[%d]: пустой риск", i)
+		check(it.Level.Valid(), "risks[%d]: неверный уровень %q", i, it.Level)
+	}
+	for i, it := range r.Ambiguities {
+		check(it.Wording != "", "ambiguities[%d]: пустая формулировка", i)
+		check(it.Level.Valid(), "ambiguities[%d]: неверный уровень %q", i, it.Level)
+	}
+	for i, it := range r.Violations {
+		check(it.Description != "", "violations[%d]: пустое описание", i)
+		check(it.Level.Valid(), "violations[%d]: неверный уровень %q", i, it.Level)
+	}
+	for i, it := range r.Recommendations {
+		check(it.Text != "", "recommendations[%d]: пустой текст", i)
+		check(it.Level.Valid(), "recommendations[%d]: неверный уровень %q", i, it.Level)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("некорректный результат анализа: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// DropInvalid удаляет находки, не прошедшие валидацию, и возвращает их количество
+func (r *AnalysisResult) DropInvalid() int {
+	dropped := 0
+
+	risks := r.Risks[:0]
+	for _, it := range r.Risks {
+		if (it.Title != "" || it.Description != "") && it.Level.Valid() {
+			risks = append(risks, it)
+		} else {
+			dropped++
+		}
+	}
+	r.Risks = risks
+
+	ambiguities := r.Ambiguities[:0]
+	for _, it := range r.Ambiguities {
+		if it.Wording != "" && it.Level.Valid() {
+			ambiguities = append(ambiguities, it)
+		} else {
+			dropped++
+		}
+	}
+	r.Ambiguities = ambiguities
+
+	violations := r.Violations[:0]
+	for _, it := range r.Violations {
+		if it.Description != "" && it.Level.Valid() {
+			violations = append(violations, it)
+		} else {
+			dropped++
+		}
+	}
+	r.Violations = violations
+
+	recommendations := r.Recommendations[:0]
+	for _, it := range r.Recommendations {
+		if it.Text != "" && it.Level.Valid() {
+			recommendations = append(recommendations, it)
+		} else {
+			dropped++
+		}
+	}
+	r.Recommendations = recommendations
+
+	return dropped
+}
+
+// markdownLabels — подписи разделов и полей отчёта на одном языке
+type markdownLabels struct {
+	risks, ambiguities, violations, recommendations, conclusion string
+	none, noRecommendations                                     string
+	section, description, legalReference, riskLevel             string
+	problem, recommendation, importance, consequences, priority string
+	high, medium, low                                           string
+	page                                                        string
+}
+
+var markdownLabelSets = map[string]markdownLabels{
+	"ru": {
+		risks: "Правовые риски", ambiguities: "Неясные формулировки", violations: "Возможные нарушения",
+		recommendations: "Рекомендации", conclusion: "Заключение",
+		none: "Не выявлено.", noRecommendations: "Нет дополнительных рекомендаций.",
+		section: "Раздел", description: "Описание", legalReference: "Нормативный акт", riskLevel: "Уровень риска",
+		problem: "Проблема", recommendation: "Рекомендация", importance: "Уровень важности",
+		consequences: "Последствия", priority: "Приоритет",
+		high: "высокий", medium: "средний", low: "низкий",
+		page: " (стр. %d)",
+	},
+	"kk": {
+		risks: "Құқықтық тәуекелдер", ambiguities: "Түсініксіз тұжырымдар", violations: "Ықтимал бұзушылықтар",
+		recommendations: "Ұсынымдар", conclusion: "Қорытынды",
+		none: "Анықталған жоқ.", noRecommendations: "Қосымша ұсынымдар жоқ.",
+		section: "Бөлім", description: "Сипаттама", legalReference: "Нормативтік акт", riskLevel: "Тәуекел деңгейі",
+		problem: "Мәселе", recommendation: "Ұсыным", importance: "Маңыздылық деңгейі",
+		consequences: "Салдары", priority: "Басымдық",
+		high: "жоғары", medium: "орташа", low: "төмен",
+		page: " (%d-бет)",
+	},
+	"en": {
+		risks: "Legal risks", ambiguities: "Ambiguous wording", violations: "Possible violations",
+		recommendations: "Recommendations", conclusion: "Conclusion",
+		none: "None found.", noRecommendations: "No additional recommendations.",
+		section: "Section", description: "Description", legalReference: "Legal reference", riskLevel: "Risk level",
+		problem: "Problem", recommendation: "Recommendation", importance: "Importance",
+		consequences: "Consequences", priority: "Priority",
+		high: "high", medium: "medium", low: "low",
+		page: " (p. %d)",
+	},
+}
+
+func markdownLabelsFor(lang string) markdownLabels {
+	if labels, ok := markdownLabelSets[lang]; ok {
+		return labels
+	}
+	return markdownLabelSets["ru"]
+}
+
+// Разделы markdown-отчёта и поля его пунктов, общие для всех языков отчёта
+const (
+	MarkdownRisks           = "risks"
+	MarkdownAmbiguities     = "ambiguities"
+	MarkdownViolations      = "violations"
+	MarkdownRecommendations = "recommendations"
+	MarkdownConclusion      = "conclusion"
+
+	MarkdownFieldSection        = "section"
+	MarkdownFieldDescription    = "description"
+	MarkdownFieldLegalReference = "legal_reference"
+	MarkdownFieldLevel          = "level"
+	MarkdownFieldProblem        = "problem"
+	MarkdownFieldRecommendation = "recommendation"
+	MarkdownFieldConsequences   = "consequences"
+)
+
+// markdownLanguages — порядок, в котором сверяются подписи разных языков
+var markdownLanguages = []string{"ru", "kk", "en"}
+
+// MarkdownSection определяет раздел отчёта по заголовку на любом из языков отчёта:
+// заголовок содержит название раздела целиком или его последнее слово
+// («Legal risks» или просто «Risks»)
+func MarkdownSection(heading string) (string, bool) {
+	lower := strings.ToLower(heading)
+	for _, lang := range markdownLanguages {
+		l := markdownLabelSets[lang]
+		for _, s := range []struct{ key, label string }{
+			{MarkdownRisks, l.risks},
+			{MarkdownAmbiguities, l.ambiguities},
+			{MarkdownViolations, l.violations},
+			{MarkdownRecommendations, l.recommendations},
+			{MarkdownConclusion, l.conclusion},
+		} {
+			label := strings.ToLower(s.label)
+			words := strings.Fields(label)
+			if strings.Contains(lower, label) || strings.Contains(lower, words[len(words)-1]) {
+				return s.key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// MarkdownField определяет поле пункта отчёта по его подписи на любом из языков отчёта.
+// Подпись совпадает с названием поля или начинается с него («Risk level», «Risk level (overall)»).
+func MarkdownField(label string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSpace(label))
+	for _, lang := range markdownLanguages {
+		l := markdownLabelSets[lang]
+		for _, f := range []struct{ key, label string }{
+			{MarkdownFieldSection, l.section},
+			{MarkdownFieldDescription, l.description},
+			{MarkdownFieldLegalReference, l.legalReference},
+			{MarkdownFieldLevel, l.riskLevel},
+			{MarkdownFieldLevel, l.importance},
+			{MarkdownFieldLevel, l.priority},
+			{MarkdownFieldProblem, l.problem},
+			{MarkdownFieldRecommendation, l.recommendation},
+			{MarkdownFieldConsequences, l.consequences},
+		} {
+			if strings.HasPrefix(lower, strings.ToLower(f.label)) {
+				return f.key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Markdown рендерит результат в прежнем формате разделов, который показывает фронтенд
+func (r *AnalysisResult) Markdown() string {
+	return r.MarkdownIn("ru")
+}
+
+// MarkdownIn рендерит результат с подписями на языке отчёта (ru, kk, en)
+func (r *AnalysisResult) MarkdownIn(lang string) string {
+	l := markdownLabelsFor(lang)
+	page := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return fmt.Sprintf(l.page, n)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "### %s\n\n", l.risks)
+	if len(r.Risks) == 0 {
+		b.WriteString(l.none + "\n\n")
+	}
+	for i, it := range r.Risks {
+		fmt.Fprintf(&b, "%d. %s%s\n", i+1, orDash(it.Title), page(it.Page))
+		writeField(&b, l.section, it.Section)
+		writeField(&b, l.description, it.Description)
+		writeField(&b, l.legalReference, it.LegalReference)
+		writeField(&b, l.riskLevel, it.Level.LabelIn(lang))
+		writeField(&b, l.recommendation, it.Recommendation)
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", l.ambiguities)
+	if len(r.Ambiguities) == 0 {
+		b.WriteString(l.none + "\n\n")
+	}
+	for i, it := range r.Ambiguities {
+		fmt.Fprintf(&b, "%d. %s%s\n", i+1, orDash(it.Wording), page(it.Page))
+		writeField(&b, l.section, it.Section)
+		writeField(&b, l.problem, it.Problem)
+		writeField(&b, l.legalReference, it.LegalReference)
+		writeField(&b, l.recommendation, it.Recommendation)
+		writeField(&b, l.importance, it.Level.LabelIn(lang))
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", l.violations)
+	if len(r.Violations) == 0 {
+		b.WriteString(l.none + "\n\n")
+	}
+	for i, it := range r.Violations {
+		fmt.Fprintf(&b, "%d. %s%s\n", i+1, orDash(it.Description), page(it.Page))
+		writeField(&b, l.section, it.Section)
+		writeField(&b, l.legalReference, it.LegalReference)
+		writeField(&b, l.consequences, it.Consequences)
+		writeField(&b, l.riskLevel, it.Level.LabelIn(lang))
+		writeField(&b, l.recommendation, it.Recommendation)
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", l.recommendations)
+	if len(r.Recommendations) == 0 {
+		b.WriteString(l.noRecommendations + "\n\n")
+	}
+	for i, it := range r.Recommendations {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, it.Text)
+		writeField(&b, l.legalReference, it.LegalReference)
+		writeField(&b, l.priority, it.Level.LabelIn(lang))
+	}
+	if len(r.Recommendations) > 0 {
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "### %s\n\n", l.conclusion)
+	b.WriteString(orDash(r.Conclusion))
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func writeField(b *strings.Builder, label, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "   - %s: %s\n", label, value)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
 }
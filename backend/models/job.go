@@ -0,0 +1,41 @@
+// job.go
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Finished сообщает, что задача больше не изменится
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// Job — асинхронная задача анализа документа
+type Job struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	Filename   string             `bson:"filename" json:"filename"`
+	Status     JobStatus          `bson:"status" json:"status"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorCode  string             `bson:"error_code,omitempty" json:"error_code,omitempty"`
+	AnalysisID primitive.ObjectID `bson:"analysis_id,omitempty" json:"analysis_id,omitempty"`
+	Parts      []PartStatus       `bson:"parts,omitempty" json:"parts,omitempty"`
+	Duplicate  bool               `bson:"duplicate,omitempty" json:"duplicate,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+	StartedAt  *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
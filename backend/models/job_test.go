@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestJobStatusFinished(t *testing.T) {
+	tests := []struct {
+		status JobStatus
+		want   bool
+	}{
+		{JobQueued, false},
+		{JobRunning, false},
+		{JobDone, true},
+		{JobFailed, true},
+		{JobCancelled, true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Finished(); got != tt.want {
+			t.Errorf("%s.Finished() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
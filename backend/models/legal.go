@@ -0,0 +1,41 @@
+// legal.go
+
+package models
+
+// LegalSource — статья закона из локального корпуса, переданная модели вместе
+// с частями документа. Citation — ссылка в том виде, в каком модель должна её
+// указывать («ст. 401 ГК РК»); Parts — номера частей, к которым статья подобрана.
+type LegalSource struct {
+	ID       string  `bson:"id" json:"id"`
+	Code     string  `bson:"code" json:"code"`
+	Article  string  `bson:"article" json:"article"`
+	Title    string  `bson:"title,omitempty" json:"title,omitempty"`
+	Citation string  `bson:"citation" json:"citation"`
+	Score    float64 `bson:"score" json:"score"`
+	Parts    []int   `bson:"parts,omitempty" json:"parts,omitempty"`
+}
+
+// Статусы проверки ссылки на норму
+const (
+	CitationVerified   = "verified"
+	CitationUnknown    = "unknown"
+	CitationMismatched = "mismatched"
+)
+
+// Citation — ссылка на норму из текста анализа и результат её проверки по
+// локальному корпусу. Unknown — акт или статья вне корпуса, проверить нельзя;
+// mismatched — в корпусе такой статьи или пункта нет либо название не совпадает.
+// Text — текст найденной статьи или пункта, Fields — поля анализа со ссылкой.
+type Citation struct {
+	Reference    string   `bson:"reference" json:"reference"`
+	Act          string   `bson:"act,omitempty" json:"act,omitempty"`
+	Code         string   `bson:"code,omitempty" json:"code,omitempty"`
+	Article      string   `bson:"article" json:"article"`
+	Point        string   `bson:"point,omitempty" json:"point,omitempty"`
+	Status       string   `bson:"status" json:"status"`
+	Reason       string   `bson:"reason,omitempty" json:"reason,omitempty"`
+	ArticleTitle string   `bson:"article_title,omitempty" json:"article_title,omitempty"`
+	Text         string   `bson:"text,omitempty" json:"text,omitempty"`
+	Supplied     bool     `bson:"supplied" json:"supplied"`
+	Fields       []string `bson:"fields" json:"fields"`
+}
@@ -0,0 +1,59 @@
+// metadata.go
+
+package models
+
+import "time"
+
+// ContractMetadata — реквизиты договора, извлечённые из текста правилами, без модели
+type ContractMetadata struct {
+	Number      string        `bson:"number,omitempty" json:"number,omitempty"`
+	City        string        `bson:"city,omitempty" json:"city,omitempty"`
+	SignedAt    *time.Time    `bson:"signed_at,omitempty" json:"signed_at,omitempty"`
+	Term        *ContractTerm `bson:"term,omitempty" json:"term,omitempty"`
+	Parties     []Party       `bson:"parties,omitempty" json:"parties,omitempty"`
+	Amounts     []Amount      `bson:"amounts,omitempty" json:"amounts,omitempty"`
+	Identifiers []Identifier  `bson:"identifiers,omitempty" json:"identifiers,omitempty"`
+}
+
+// ContractTerm — срок действия договора. Text — фраза договора, из которой он взят.
+type ContractTerm struct {
+	From     *time.Time `bson:"from,omitempty" json:"from,omitempty"`
+	Until    *time.Time `bson:"until,omitempty" json:"until,omitempty"`
+	Duration string     `bson:"duration,omitempty" json:"duration,omitempty"`
+	Text     string     `bson:"text" json:"text"`
+}
+
+// Party — сторона договора и её роль («Поставщик», «Арендатор»)
+type Party struct {
+	Name   string `bson:"name" json:"name"`
+	Role   string `bson:"role,omitempty" json:"role,omitempty"`
+	ID     string `bson:"id,omitempty" json:"id,omitempty"`
+	IDKind string `bson:"id_kind,omitempty" json:"id_kind,omitempty"`
+}
+
+// Amount — денежная сумма; Currency — код ISO 4217
+type Amount struct {
+	Value    float64 `bson:"value" json:"value"`
+	Currency string  `bson:"currency" json:"currency"`
+	Text     string  `bson:"text" json:"text"`
+}
+
+// Identifier — ИИН или БИН, встреченный в тексте
+type Identifier struct {
+	Kind  string `bson:"kind" json:"kind"`
+	Value string `bson:"value" json:"value"`
+}
+
+// IsEmpty сообщает, что из текста не удалось извлечь ни одного реквизита
+func (m *ContractMetadata) IsEmpty() bool {
+	return m.Number == "" && m.City == "" && m.SignedAt == nil && m.Term == nil &&
+		len(m.Parties) == 0 && len(m.Amounts) == 0 && len(m.Identifiers) == 0
+}
+
+// HistoryFilter — условия отбора записей истории по реквизитам договора
+type HistoryFilter struct {
+	Counterparty string
+	MinAmount    *float64
+	MaxAmount    *float64
+	Currency     string
+}
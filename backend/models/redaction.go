@@ -0,0 +1,19 @@
+// redaction.go
+
+package models
+
+// RedactedItem — одно замаскированное значение: тип данных, его заглушка
+// и сколько раз оно встретилось. Само значение в отчёт не попадает.
+type RedactedItem struct {
+	Kind        string `bson:"kind" json:"kind"`
+	Placeholder string `bson:"placeholder" json:"placeholder"`
+	Count       int    `bson:"count" json:"count"`
+}
+
+// RedactionReport — что было замаскировано перед отправкой текста внешним сервисам
+type RedactionReport struct {
+	Detectors []string       `bson:"detectors" json:"detectors"`
+	Total     int            `bson:"total" json:"total"`
+	ByKind    map[string]int `bson:"by_kind" json:"by_kind"`
+	Items     []RedactedItem `bson:"items" json:"items"`
+}
@@ -0,0 +1,38 @@
+// similarity.go
+
+package models
+
+import "time"
+
+// Области поиска похожих документов
+const (
+	SimilarityScopeUser         = "user"
+	SimilarityScopeOrganization = "organization"
+)
+
+// SimilarityQuery — поиск похожих документов. Scope — чьи документы искать:
+// только свои или всей организации пользователя. From и To ограничивают дату
+// анализа, Filename — часть имени файла, MinScore — порог близости.
+type SimilarityQuery struct {
+	Text     string
+	Scope    string
+	TopK     int
+	MinScore float64
+	DocType  string
+	From     *time.Time
+	To       *time.Time
+	Filename string
+}
+
+// SimilarDocument — найденный документ: сохранённый анализ и фрагмент текста,
+// ближе всего к запросу. Own — документ загружен самим пользователем.
+type SimilarDocument struct {
+	AnalysisID string     `json:"analysis_id"`
+	Filename   string     `json:"filename,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	Language   string     `json:"language,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	Score      float64    `json:"score"`
+	Snippet    string     `json:"snippet,omitempty"`
+	Own        bool       `json:"own"`
+}
@@ -0,0 +1,42 @@
+// usage.go
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenUsage — расход токенов и стоимость по одной модели
+type TokenUsage struct {
+	Model            string  `bson:"model" json:"model"`
+	Requests         int     `bson:"requests" json:"requests"`
+	PromptTokens     int     `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int     `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int     `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64 `bson:"cost" json:"cost"`
+}
+
+// UsageSummary — расход одного анализа по всем моделям
+type UsageSummary struct {
+	PromptTokens     int          `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int          `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int          `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64      `bson:"cost" json:"cost"`
+	Models           []TokenUsage `bson:"models" json:"models"`
+}
+
+// DailyUsage — агрегат расхода пользователя за день по модели (коллекция usage)
+type DailyUsage struct {
+	UserID           primitive.ObjectID `bson:"user_id" json:"-"`
+	Date             string             `bson:"date" json:"date"`
+	Model            string             `bson:"model" json:"model"`
+	Analyses         int                `bson:"analyses" json:"analyses"`
+	Requests         int                `bson:"requests" json:"requests"`
+	PromptTokens     int                `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int                `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int                `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64            `bson:"cost" json:"cost"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
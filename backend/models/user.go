@@ -22,10 +22,14 @@ const (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Email     string             `bson:"email"`
-	Password  string             `bson:"password"`
-	Role      UserRole           `bson:"role"`
-	CreatedAt time.Time          `bson:"createdAt"`
-	UpdatedAt time.Time          `bson:"updatedAt"`
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Email    string             `bson:"email"`
+	Password string             `bson:"password"`
+	Role     UserRole           `bson:"role"`
+	// Язык отчётов по умолчанию; пусто — язык документа
+	Language string `bson:"language,omitempty"`
+	// Организация пользователя; её участникам доступен поиск по документам друг друга
+	OrganizationID string    `bson:"organization_id,omitempty"`
+	CreatedAt      time.Time `bson:"createdAt"`
+	UpdatedAt      time.Time `bson:"updatedAt"`
 }
@@ -0,0 +1,257 @@
+// registry.go
+
+package prompts
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"legally/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// AnyDocType — шаблон подходит для документа любого типа
+const AnyDocType = "*"
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Template — версия шаблона промпта.
+// Файл шаблона состоит из заголовка "ключ: значение" и блоков "--- system" и "--- user".
+type Template struct {
+	ID          string `json:"id"`
+	Version     int    `json:"version"`
+	Language    string `json:"language"`
+	DocType     string `json:"doc_type"`
+	Active      bool   `json:"active"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+
+	system *template.Template
+	user   *template.Template
+}
+
+// Vars — переменные, доступные в шаблонах
+type Vars map[string]interface{}
+
+// Rendered — готовые сообщения для модели
+type Rendered struct {
+	System string
+	User   string
+}
+
+func (t *Template) Render(vars Vars) (Rendered, error) {
+	var system, user bytes.Buffer
+	if err := t.system.Execute(&system, vars); err != nil {
+		return Rendered{}, fmt.Errorf("шаблон %s v%d (system): %w", t.ID, t.Version, err)
+	}
+	if err := t.user.Execute(&user, vars); err != nil {
+		return Rendered{}, fmt.Errorf("шаблон %s v%d (user): %w", t.ID, t.Version, err)
+	}
+	return Rendered{System: strings.TrimSpace(system.String()), User: strings.TrimSpace(user.String())}, nil
+}
+
+// Registry хранит шаблоны: встроенные в бинарник и загруженные из каталога PROMPTS_DIR.
+// Шаблоны из каталога можно менять на лету и перечитывать через Reload.
+type Registry struct {
+	dir string
+
+	mu        sync.RWMutex
+	templates []*Template
+}
+
+var (
+	defaultRegistry *Registry
+	defaultOnce     sync.Once
+)
+
+// Default возвращает реестр, загруженный из встроенных шаблонов и PROMPTS_DIR
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry = &Registry{dir: os.Getenv("PROMPTS_DIR")}
+		if err := defaultRegistry.Reload(); err != nil {
+			utils.LogError(fmt.Sprintf("Ошибка загрузки шаблонов промптов: %v", err))
+		}
+	})
+	return defaultRegistry
+}
+
+// Reload перечитывает шаблоны; при ошибке остаются прежние
+func (r *Registry) Reload() error {
+	var loaded []*Template
+
+	err := fs.WalkDir(builtin, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := builtin.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		t, err := parseTemplate(data, "builtin:"+path)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.dir != "" {
+		paths, err := filepath.Glob(filepath.Join(r.dir, "*.tmpl"))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			t, err := parseTemplate(data, path)
+			if err != nil {
+				return err
+			}
+			loaded = append(loaded, t)
+		}
+	}
+
+	// Файл из каталога с тем же ID, версией, языком и типом заменяет встроенный
+	byKey := map[string]*Template{}
+	for _, t := range loaded {
+		byKey[fmt.Sprintf("%s|%d|%s|%s", t.ID, t.Version, t.Language, t.DocType)] = t
+	}
+	loaded = loaded[:0]
+	for _, t := range byKey {
+		loaded = append(loaded, t)
+	}
+	sort.Slice(loaded, func(i, j int) bool {
+		if loaded[i].ID != loaded[j].ID {
+			return loaded[i].ID < loaded[j].ID
+		}
+		return loaded[i].Version > loaded[j].Version
+	})
+
+	r.mu.Lock()
+	r.templates = loaded
+	r.mu.Unlock()
+
+	utils.LogSuccess(fmt.Sprintf("Загружено %d шаблонов промптов", len(loaded)))
+	return nil
+}
+
+// List возвращает все загруженные шаблоны
+func (r *Registry) List() []Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, *t)
+	}
+	return list
+}
+
+// Resolve выбирает активный шаблон с наибольшей версией. Точное совпадение
+// типа документа важнее общего шаблона, язык при отсутствии шаблона падает на русский.
+func (r *Registry) Resolve(id, docType, language string) (*Template, error) {
+	return r.ResolveAny(id, []string{docType}, language)
+}
+
+// ResolveAny работает как Resolve, но перебирает типы документа по порядку —
+// от частного к общему (например, «Договор аренды», затем «Договор»)
+func (r *Registry) ResolveAny(id string, docTypes []string, language string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	languages := []string{language}
+	if language != "ru" {
+		languages = append(languages, "ru")
+	}
+
+	for _, lang := range languages {
+		for _, dt := range append(append([]string{}, docTypes...), AnyDocType) {
+			for _, t := range r.templates {
+				if t.Active && t.ID == id && t.Language == lang && t.DocType == dt {
+					return t, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("шаблон промпта %q (%s, %s) не найден", id, strings.Join(docTypes, ", "), language)
+}
+
+func parseTemplate(data []byte, source string) (*Template, error) {
+	t := &Template{Language: "ru", DocType: AnyDocType, Active: true, Source: source}
+	blocks := map[string]*strings.Builder{}
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "--- ") {
+			name := strings.TrimSpace(strings.TrimPrefix(line, "--- "))
+			current = &strings.Builder{}
+			blocks[name] = current
+			continue
+		}
+		if current != nil {
+			current.WriteString(line)
+			current.WriteString("\n")
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "id":
+			t.ID = value
+		case "version":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: неверная версия %q", source, value)
+			}
+			t.Version = v
+		case "language":
+			t.Language = value
+		case "doc_type":
+			t.DocType = value
+		case "active":
+			t.Active = value == "true"
+		case "description":
+			t.Description = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if t.ID == "" || t.Version == 0 {
+		return nil, fmt.Errorf("%s: не указаны id или version", source)
+	}
+	if blocks["system"] == nil || blocks["user"] == nil {
+		return nil, fmt.Errorf("%s: нужны блоки --- system и --- user", source)
+	}
+
+	var err error
+	name := fmt.Sprintf("%s.v%d", t.ID, t.Version)
+	if t.system, err = template.New(name + ".system").Option("missingkey=zero").Parse(blocks["system"].String()); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if t.user, err = template.New(name + ".user").Option("missingkey=zero").Parse(blocks["user"].String()); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return t, nil
+}
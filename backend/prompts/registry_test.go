@@ -0,0 +1,118 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplate кладёт шаблон в каталог реестра
+func writeTemplate(t *testing.T, dir, name, header, user string) {
+	t.Helper()
+	body := header + "\n--- system\nСистема\n--- user\n" + user + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveFallback(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "analysis.lease.tmpl", "id: analysis\nversion: 1\nlanguage: ru\ndoc_type: Договор аренды", "аренда")
+	writeTemplate(t, dir, "analysis.contract.tmpl", "id: analysis\nversion: 1\nlanguage: ru\ndoc_type: Договор", "договор")
+	writeTemplate(t, dir, "analysis.contract.v2.tmpl", "id: analysis\nversion: 2\nlanguage: ru\ndoc_type: Договор\nactive: false", "черновик")
+	writeTemplate(t, dir, "analysis.kk.lease.tmpl", "id: analysis\nversion: 1\nlanguage: kk\ndoc_type: Договор аренды", "жалдау")
+
+	r := &Registry{dir: dir}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		docTypes []string
+		language string
+		want     string
+	}{
+		{"exact doc type", []string{"Договор аренды", "Договор"}, "ru", "аренда"},
+		{"parent doc type, inactive version skipped", []string{"Договор подряда", "Договор"}, "ru", "договор"},
+		{"any doc type is the builtin template", []string{"Закон"}, "ru", "{{.Schema}}"},
+		{"language specific template", []string{"Договор аренды"}, "kk", "жалдау"},
+		{"builtin language beats russian doc type", []string{"Договор"}, "kk", "{{.Schema}}"},
+		{"unknown language falls back to russian", []string{"Договор"}, "de", "договор"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := r.ResolveAny("analysis", tt.docTypes, tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "{{.Schema}}" {
+				if !strings.HasPrefix(tpl.Source, "builtin:") {
+					t.Errorf("resolved %s, want a builtin template", tpl.Source)
+				}
+				return
+			}
+			out, err := tpl.Render(Vars{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.User != tt.want {
+				t.Errorf("resolved %s with %q, want %q", tpl.Source, out.User, tt.want)
+			}
+		})
+	}
+
+	if _, err := r.Resolve("missing", AnyDocType, "ru"); err == nil {
+		t.Error("Resolve() of an unknown template should fail")
+	}
+}
+
+func TestResolveLatestVersion(t *testing.T) {
+	dir := t.TempDir()
+	// Файл из каталога с той же версией заменяет встроенный шаблон
+	writeTemplate(t, dir, "reduce.ru.v2.tmpl", "id: reduce\nversion: 2\nlanguage: ru", "из каталога")
+
+	r := &Registry{dir: dir}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := r.Resolve("reduce", AnyDocType, "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Version != 2 || tpl.Source != filepath.Join(dir, "reduce.ru.v2.tmpl") {
+		t.Errorf("resolved %s v%d", tpl.Source, tpl.Version)
+	}
+
+	// Ошибка в шаблоне не затирает загруженные ранее
+	writeTemplate(t, dir, "broken.tmpl", "id: broken", "")
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload() of a template without version should fail")
+	}
+	if again, _ := r.Resolve("reduce", AnyDocType, "ru"); again != tpl {
+		t.Error("failed Reload() replaced the loaded templates")
+	}
+}
+
+func TestBuiltinTemplatesRender(t *testing.T) {
+	r := &Registry{}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"analysis", "reduce"} {
+		for _, lang := range []string{"ru", "kk", "en"} {
+			tpl, err := r.Resolve(id, AnyDocType, lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tpl.Language != lang {
+				t.Errorf("%s (%s) resolved to language %s", id, lang, tpl.Language)
+			}
+			out, err := tpl.Render(Vars{"Schema": "{}", "Text": "текст", "Results": "[]", "Sections": "—", "ResponseLanguage": "русском"})
+			if err != nil || out.System == "" || out.User == "" {
+				t.Errorf("%s (%s) v%d rendered %+v, %v", id, lang, tpl.Version, out, err)
+			}
+		}
+	}
+}
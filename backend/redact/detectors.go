@@ -0,0 +1,169 @@
+// detectors.go
+
+package redact
+
+import (
+	"legally/utils"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Типы персональных данных, которые маскируются по умолчанию
+const (
+	KindIIN      = utils.IdentifierIIN
+	KindBIN      = utils.IdentifierBIN
+	KindPassport = "passport"
+	KindIBAN     = "iban"
+	KindPhone    = "phone"
+	KindEmail    = "email"
+	KindAddress  = "address"
+)
+
+// Detector находит в тексте значения одного типа. Если задан Group,
+// маскируется только эта подгруппа совпадения (например, номер после слова «паспорт»).
+// Classify может уточнить тип по значению или отбросить совпадение, вернув пустую строку.
+type Detector struct {
+	Name     string
+	Kind     string
+	Pattern  *regexp.Regexp
+	Group    int
+	Classify func(value string) string
+}
+
+var (
+	detectorsMu sync.RWMutex
+	detectors   []Detector
+)
+
+func init() {
+	// Порядок важен: при пересечении совпадений побеждает более длинное,
+	// а при равной длине — детектор, зарегистрированный раньше
+	RegisterDetector(Detector{
+		Name:     KindIIN,
+		Kind:     KindIIN,
+		Pattern:  regexp.MustCompile(`\b\d{12}\b`),
+		Classify: classifyIIN,
+	})
+	// После подписи «ИИН/БИН» номер маскируется и с неверным контрольным разрядом
+	RegisterDetector(Detector{
+		Name:     KindIIN,
+		Kind:     KindIIN,
+		Pattern:  regexp.MustCompile(`(?:ИИН|БИН|ЖСН|БСН|IIN|BIN)[\s:№]*(\d{12})\b`),
+		Group:    1,
+		Classify: utils.IdentifierKind,
+	})
+	RegisterDetector(Detector{
+		Name:    KindIBAN,
+		Kind:    KindIBAN,
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[0-9A-Z]{4}){2,7}(?: ?[0-9A-Z]{1,3})?\b`),
+		Classify: func(value string) string {
+			if validIBAN(value) {
+				return KindIBAN
+			}
+			return ""
+		},
+	})
+	RegisterDetector(Detector{
+		Name:    KindPassport,
+		Kind:    KindPassport,
+		Pattern: regexp.MustCompile(`(?i)(?:паспорт\p{L}*|удостоверени\p{L}*\s+личности|жеке\s+куәлі\p{L}*|passport)[^\d\n]{0,30}?(N?\d{8,9}|\d{2} ?\d{2} ?\d{6})\b`),
+		Group:   1,
+	})
+	RegisterDetector(Detector{
+		Name:    KindPassport,
+		Kind:    KindPassport,
+		Pattern: regexp.MustCompile(`\bN\d{8}\b`),
+	})
+	RegisterDetector(Detector{
+		Name:    KindPhone,
+		Kind:    KindPhone,
+		Pattern: regexp.MustCompile(`(?:\+7|\b8)[ \-]*\(?\d{3}\)?[ \-]*\d{3}[ \-]*\d{2}[ \-]*\d{2}\b`),
+	})
+	RegisterDetector(Detector{
+		Name:    KindEmail,
+		Kind:    KindEmail,
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	})
+	// Адрес — улица с номером дома, квартиры или офиса; город без улицы не маскируется
+	RegisterDetector(Detector{
+		Name: KindAddress,
+		Kind: KindAddress,
+		Pattern: regexp.MustCompile(`(?i)(?:^|[^\p{L}])((?:ул\.|улица|пр\.|пр-т|проспект|мкр\.?|микрорайон|бульвар|б-р|пер\.|переулок|шоссе|көшесі|даңғылы)` +
+			`\s*[^\s,;][^,;\n]{0,50}?` +
+			`(?:,?\s*(?:д\.|дом|зд\.|здание|корп\.|корпус|кв\.|квартира|оф\.|офис|үй|пәтер)\s*№?\s*\d+\p{L}?(?:/\d+)?)+)`),
+		Group: 1,
+	})
+}
+
+// RegisterDetector добавляет детектор. Детекторы с одинаковым Name
+// включаются и выключаются вместе.
+func RegisterDetector(d Detector) {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	detectors = append(detectors, d)
+}
+
+// DetectorNames возвращает имена зарегистрированных детекторов
+func DetectorNames() []string {
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+
+	var names []string
+	seen := map[string]bool{}
+	for _, d := range detectors {
+		if !seen[d.Name] {
+			seen[d.Name] = true
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// detectorsByName возвращает детекторы с указанными именами в порядке регистрации
+func detectorsByName(names []string) []Detector {
+	enabled := map[string]bool{}
+	for _, n := range names {
+		enabled[n] = true
+	}
+
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+
+	var list []Detector
+	for _, d := range detectors {
+		if enabled[d.Name] {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+// classifyIIN пропускает только номера с верным контрольным разрядом
+func classifyIIN(value string) string {
+	if !utils.ValidIIN(value) {
+		return ""
+	}
+	return utils.IdentifierKind(value)
+}
+
+// validIBAN проверяет контрольную сумму IBAN (ISO 13616, mod 97)
+func validIBAN(value string) bool {
+	iban := strings.ReplaceAll(value, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rem := 0
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			rem = (rem*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			rem = (rem*100 + int(r-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
@@ -0,0 +1,281 @@
+// redactor.go
+
+package redact
+
+import (
+	"fmt"
+	"legally/models"
+	"legally/utils"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var placeholderRe = regexp.MustCompile(`\[?\b([A-Z]+_\d+)\b\]?`)
+
+// EnabledDetectors возвращает детекторы, включённые настройками:
+// PII_REDACTION=off отключает маскирование, PII_DETECTORS — список имён через запятую
+// (по умолчанию включены все зарегистрированные детекторы)
+func EnabledDetectors() []string {
+	if mode := strings.ToLower(utils.GetEnv("PII_REDACTION", "on")); mode == "off" || mode == "false" || mode == "0" {
+		return nil
+	}
+
+	all := DetectorNames()
+	raw := strings.TrimSpace(utils.GetEnv("PII_DETECTORS", ""))
+	if raw == "" {
+		return all
+	}
+
+	known := map[string]bool{}
+	for _, n := range all {
+		known[n] = true
+	}
+	var names []string
+	for _, n := range strings.Split(raw, ",") {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" {
+			continue
+		}
+		if !known[n] {
+			utils.LogWarning(fmt.Sprintf("Неизвестный детектор персональных данных в PII_DETECTORS: %s", n))
+			continue
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+// Redactor заменяет персональные данные заглушками вида [IIN_1] и возвращает
+// их обратно. Одно и то же значение во всём документе получает одну и ту же
+// заглушку, поэтому части документа, отправленные модели отдельно, согласованы.
+// Безопасен для использования из нескольких горутин.
+type Redactor struct {
+	names     []string
+	detectors []Detector
+
+	mu           sync.Mutex
+	placeholders map[string]string // тип и нормализованное значение → заглушка
+	values       map[string]string // заглушка → исходное значение
+	perKind      map[string]int
+	items        []models.RedactedItem
+	itemIndex    map[string]int
+}
+
+// New создаёт маскировщик с детекторами из настроек
+func New() *Redactor {
+	return NewWith(EnabledDetectors())
+}
+
+// NewWith создаёт маскировщик с указанными детекторами
+func NewWith(names []string) *Redactor {
+	return &Redactor{
+		names:        names,
+		detectors:    detectorsByName(names),
+		placeholders: map[string]string{},
+		values:       map[string]string{},
+		perKind:      map[string]int{},
+		itemIndex:    map[string]int{},
+	}
+}
+
+// Text маскирует текст отдельным маскировщиком, когда восстанавливать его не нужно
+func Text(text string) string {
+	return New().Redact(text)
+}
+
+// Redact маскирует текст и учитывает найденные значения в отчёте
+func (r *Redactor) Redact(text string) string {
+	return r.redact(text, true)
+}
+
+// Mask маскирует фрагмент уже замаскированного через Redact текста,
+// не меняя счётчиков отчёта (например, перекрывающиеся части документа)
+func (r *Redactor) Mask(text string) string {
+	return r.redact(text, false)
+}
+
+type span struct {
+	start, end int
+	kind       string
+	order      int
+}
+
+func (r *Redactor) redact(text string, count bool) string {
+	if len(r.detectors) == 0 || text == "" {
+		return text
+	}
+
+	var found []span
+	for order, d := range r.detectors {
+		for _, m := range d.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if d.Group > 0 && len(m) > 2*d.Group+1 {
+				start, end = m[2*d.Group], m[2*d.Group+1]
+			}
+			if start < 0 || end <= start {
+				continue
+			}
+			kind := d.Kind
+			if d.Classify != nil {
+				kind = d.Classify(text[start:end])
+			}
+			if kind != "" {
+				found = append(found, span{start, end, kind, order})
+			}
+		}
+	}
+
+	// Найденное или уже замаскированное значение маскируется везде, даже там,
+	// где детектор его не узнал бы (например, ИИН без подписи)
+	known := map[string]string{}
+	for _, f := range found {
+		known[text[f.start:f.end]] = f.kind
+	}
+	r.mu.Lock()
+	for p, value := range r.values {
+		known[value] = r.items[r.itemIndex[p]].Kind
+	}
+	r.mu.Unlock()
+	for value, kind := range known {
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], value)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(value)
+			if standalone(text, start, end) {
+				found = append(found, span{start, end, kind, len(r.detectors)})
+			}
+			offset = end
+		}
+	}
+
+	if len(found) == 0 {
+		return text
+	}
+
+	// Из пересекающихся совпадений остаётся самое длинное
+	sort.SliceStable(found, func(i, j int) bool {
+		li, lj := found[i].end-found[i].start, found[j].end-found[j].start
+		if li != lj {
+			return li > lj
+		}
+		return found[i].order < found[j].order
+	})
+	var chosen []span
+	for _, s := range found {
+		overlaps := false
+		for _, c := range chosen {
+			if s.start < c.end && c.start < s.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			chosen = append(chosen, s)
+		}
+	}
+	sort.Slice(chosen, func(i, j int) bool { return chosen[i].start < chosen[j].start })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	last := 0
+	for _, s := range chosen {
+		b.WriteString(text[last:s.start])
+		b.WriteString(r.placeholder(s.kind, text[s.start:s.end], count))
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// placeholder возвращает заглушку значения, заводя новую при первой встрече.
+// Вызывается под r.mu.
+func (r *Redactor) placeholder(kind, value string, count bool) string {
+	key := kind + "|" + normalizeValue(value)
+	p, ok := r.placeholders[key]
+	if !ok {
+		r.perKind[kind]++
+		p = fmt.Sprintf("[%s_%d]", strings.ToUpper(kind), r.perKind[kind])
+		r.placeholders[key] = p
+		r.values[p] = value
+		r.itemIndex[p] = len(r.items)
+		r.items = append(r.items, models.RedactedItem{Kind: kind, Placeholder: p})
+	}
+	// Значение, впервые встреченное во фрагменте, тоже попадает в отчёт
+	if count || !ok {
+		r.items[r.itemIndex[p]].Count++
+	}
+	return p
+}
+
+// Restore подставляет исходные значения вместо заглушек. Модель иногда теряет
+// квадратные скобки, поэтому заглушка узнаётся и без них.
+func (r *Redactor) Restore(text string) string {
+	if !strings.Contains(text, "_") {
+		return text
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.values) == 0 {
+		return text
+	}
+
+	return placeholderRe.ReplaceAllStringFunc(text, func(m string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(m, "["), "]")
+		if value, ok := r.values["["+name+"]"]; ok {
+			return value
+		}
+		return m
+	})
+}
+
+// RestoreResult возвращает исходные значения во все текстовые поля результата
+func (r *Redactor) RestoreResult(result *models.AnalysisResult) {
+	result.MapText(r.Restore)
+}
+
+// Report возвращает отчёт о замаскированных значениях без самих значений
+func (r *Redactor) Report() *models.RedactionReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &models.RedactionReport{
+		Detectors: append([]string{}, r.names...),
+		ByKind:    map[string]int{},
+		Items:     append([]models.RedactedItem{}, r.items...),
+	}
+	for _, it := range r.items {
+		report.Total += it.Count
+		report.ByKind[it.Kind] += it.Count
+	}
+	return report
+}
+
+// standalone сообщает, что text[start:end] не продолжает соседнее слово или число
+func standalone(text string, start, end int) bool {
+	word := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	first, _ := utf8.DecodeRuneInString(text[start:])
+	last, _ := utf8.DecodeLastRuneInString(text[:end])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return !(word(before) && word(first)) && !(word(last) && word(after))
+}
+
+// normalizeValue приводит значение к виду, в котором одинаковые данные,
+// записанные по-разному (пробелы, скобки, регистр), совпадают
+func normalizeValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || strings.ContainsRune("-()", r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, value)
+}
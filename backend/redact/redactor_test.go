@@ -0,0 +1,114 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"valid iin", "ИИН 900101300017 выдан", "ИИН [IIN_1] выдан"},
+		{"bin", "Компания 050140000011", "Компания [BIN_1]"},
+		{"bad checksum without label", "номер 900101300018", "номер 900101300018"},
+		{"bad checksum with label", "ИИН: 900101300018", "ИИН: [IIN_1]"},
+		{"iban", "счёт KZ86125KZT5004100100 в банке", "счёт [IBAN_1] в банке"},
+		{"iban with bad checksum", "счёт KZ87125KZT5004100100", "счёт KZ87125KZT5004100100"},
+		{"passport after label", "паспорт серии N12345678", "паспорт серии [PASSPORT_1]"},
+		{"phone", "тел. +7 (701) 123-45-67", "тел. [PHONE_1]"},
+		{"email", "почта ivanov@mail.kz.", "почта [EMAIL_1]."},
+		{"address", "г. Алматы, ул. Абая, д. 10, кв. 5", "г. Алматы, [ADDRESS_1]"},
+		{"city without street", "г. Алматы", "г. Алматы"},
+		{"same value twice", "900101300017 и 900101300017", "[IIN_1] и [IIN_1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewWith(DetectorNames())
+			if got := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorConsistentAcrossParts(t *testing.T) {
+	r := NewWith(DetectorNames())
+	first := r.Redact("Арендатор ИИН 900101300017, телефон +7 (701) 123-45-67")
+	// Во второй части ИИН без подписи, телефон записан иначе
+	second := r.Redact("Подпись 900101300017, тел. +7 701 123 45 67")
+
+	if !strings.Contains(first, "[IIN_1]") || !strings.Contains(second, "[IIN_1]") {
+		t.Errorf("iin placeholders differ: %q / %q", first, second)
+	}
+	if !strings.Contains(second, "[PHONE_1]") {
+		t.Errorf("phone placeholder differs: %q", second)
+	}
+
+	report := r.Report()
+	if report.Total != 4 || report.ByKind[KindIIN] != 2 || report.ByKind[KindPhone] != 2 || len(report.Items) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestMaskDoesNotCount(t *testing.T) {
+	r := NewWith(DetectorNames())
+	r.Redact("ИИН 900101300017")
+	if got := r.Mask("повтор 900101300017"); got != "повтор [IIN_1]" {
+		t.Errorf("Mask() = %q", got)
+	}
+	if report := r.Report(); report.Total != 1 {
+		t.Errorf("Mask() changed the report: %+v", report)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	r := NewWith(DetectorNames())
+	masked := r.Redact("Связаться: ivanov@mail.kz, ИИН 900101300017")
+
+	tests := []struct {
+		text, want string
+	}{
+		{masked, "Связаться: ivanov@mail.kz, ИИН 900101300017"},
+		{"Уведомить EMAIL_1 письменно", "Уведомить ivanov@mail.kz письменно"},
+		{"[PHONE_1] неизвестна", "[PHONE_1] неизвестна"},
+		{"без заглушек", "без заглушек"},
+	}
+	for _, tt := range tests {
+		if got := r.Restore(tt.text); got != tt.want {
+			t.Errorf("Restore(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNewWithSelectedDetectors(t *testing.T) {
+	r := NewWith([]string{KindEmail})
+	text := "ivanov@mail.kz, ИИН 900101300017"
+	if got := r.Redact(text); got != "[EMAIL_1], ИИН 900101300017" {
+		t.Errorf("Redact() = %q", got)
+	}
+	if got := NewWith(nil).Redact(text); got != text {
+		t.Errorf("Redact() without detectors = %q", got)
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"KZ86125KZT5004100100", true},
+		{"KZ86 125K ZT50 0410 0100", true},
+		{"GB82WEST12345698765432", true},
+		{"KZ87125KZT5004100100", false},
+		{"KZ86125", false},
+		{"kz86125kzt5004100100", false},
+	}
+	for _, tt := range tests {
+		if got := validIBAN(tt.value); got != tt.want {
+			t.Errorf("validIBAN(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -4,17 +4,21 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"legally/db"
+	"legally/models"
 	"legally/utils"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func SaveAnalysis(userID, filename, docType, analysis, text string) error {
+func SaveAnalysis(userID string, analysis *models.Analysis) error {
 	utils.LogAction("Сохранение анализа в БД")
 
 	objID, err := primitive.ObjectIDFromHex(userID)
@@ -22,25 +26,25 @@ func SaveAnalysis(userID, filename, docType, analysis, text string) error {
 		return fmt.Errorf("неверный ID пользователя")
 	}
 
-	_, err = db.GetCollection("analyses").InsertOne(context.TODO(), bson.M{
-		"user_id":    objID,
-		"filename":   filename,
-		"type":       docType,
-		"analysis":   analysis,
-		"text":       text,
-		"created_at": time.Now(),
-	})
+	analysis.UserID = objID
+	if analysis.CreatedAt.IsZero() {
+		analysis.CreatedAt = time.Now()
+	}
+
+	res, err := db.GetCollection("analyses").InsertOne(context.TODO(), analysis)
 
 	if err != nil {
 		utils.LogError(fmt.Sprintf("Ошибка сохранения анализа: %v", err))
 	} else {
+		analysis.ID = res.InsertedID.(primitive.ObjectID)
 		utils.LogSuccess("Анализ успешно сохранён в БД")
 	}
 
 	return err
 }
 
-func GetUserHistory(userID string) ([]map[string]interface{}, error) {
+// GetUserHistory возвращает последние анализы пользователя, отобранные по реквизитам договора
+func GetUserHistory(userID string, historyFilter models.HistoryFilter) ([]map[string]interface{}, error) {
 	utils.LogAction(fmt.Sprintf("Получение истории анализов для пользователя %s", userID))
 
 	objID, err := primitive.ObjectIDFromHex(userID)
@@ -56,7 +60,10 @@ func GetUserHistory(userID string) ([]map[string]interface{}, error) {
 		SetSort(bson.D{{Key: "created_at", Value: -1}}).
 		SetLimit(50)
 
-	cursor, err := coll.Find(ctx, bson.M{"user_id": objID}, opts)
+	// Документы из архива показываются в составе записи архива
+	filter := bson.M{"user_id": objID, "bundle_id": bson.M{"$exists": false}}
+	applyHistoryFilter(filter, historyFilter)
+	cursor, err := coll.Find(ctx, filter, opts)
 	if err != nil {
 		utils.LogError(fmt.Sprintf("Ошибка получения истории: %v", err))
 		return nil, err
@@ -69,10 +76,152 @@ func GetUserHistory(userID string) ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
+	// ID нужен клиенту, чтобы изменить или удалить запись
 	for _, result := range results {
+		if id, ok := result["_id"].(primitive.ObjectID); ok {
+			result["id"] = id.Hex()
+		}
 		delete(result, "_id")
 	}
 
 	utils.LogSuccess(fmt.Sprintf("Получено %d записей истории", len(results)))
 	return results, nil
 }
+
+// applyHistoryFilter добавляет условия по контрагенту (название или ИИН/БИН стороны)
+// и по сумме договора
+func applyHistoryFilter(filter bson.M, f models.HistoryFilter) {
+	if f.Counterparty != "" {
+		filter["$or"] = bson.A{
+			bson.M{"metadata.parties.name": primitive.Regex{Pattern: regexp.QuoteMeta(f.Counterparty), Options: "i"}},
+			bson.M{"metadata.parties.id": f.Counterparty},
+		}
+	}
+
+	amount := bson.M{}
+	if f.MinAmount != nil {
+		amount["$gte"] = *f.MinAmount
+	}
+	if f.MaxAmount != nil {
+		amount["$lte"] = *f.MaxAmount
+	}
+	if len(amount) > 0 || f.Currency != "" {
+		match := bson.M{}
+		if len(amount) > 0 {
+			match["value"] = amount
+		}
+		if f.Currency != "" {
+			match["currency"] = f.Currency
+		}
+		filter["metadata.amounts"] = bson.M{"$elemMatch": match}
+	}
+}
+
+var ErrAnalysisNotFound = errors.New("анализ не найден")
+
+// GetAnalysis возвращает анализ пользователя по ID
+func GetAnalysis(userID, analysisID string) (*models.Analysis, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+	objID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, ErrAnalysisNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var analysis models.Analysis
+	err = db.GetCollection("analyses").FindOne(ctx, bson.M{"_id": objID, "user_id": userObjID}).Decode(&analysis)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// FindAnalysisByHash возвращает последний анализ пользователя с тем же содержимым документа
+func FindAnalysisByHash(userID, contentHash string) (*models.Analysis, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	var analysis models.Analysis
+	err = db.GetCollection("analyses").FindOne(ctx, bson.M{"user_id": userObjID, "content_hash": contentHash}, opts).Decode(&analysis)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// UpdateAnalysisType меняет тип документа анализа и итог классификации
+func UpdateAnalysisType(analysisID primitive.ObjectID, docType string, classification *models.Classification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := db.GetCollection("analyses").UpdateOne(ctx,
+		bson.M{"_id": analysisID},
+		bson.M{"$set": bson.M{"type": docType, "classification": classification}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrAnalysisNotFound
+	}
+	return nil
+}
+
+// DeleteAnalysis удаляет анализ пользователя, а для архива — и анализы входящих в него
+// документов. Возвращает ID удалённых записей.
+func DeleteAnalysis(userID, analysisID string) ([]primitive.ObjectID, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+	objID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, ErrAnalysisNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	coll := db.GetCollection("analyses")
+	filter := bson.M{"user_id": userObjID, "$or": bson.A{bson.M{"_id": objID}, bson.M{"bundle_id": objID}}}
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrAnalysisNotFound
+	}
+
+	ids := make([]primitive.ObjectID, len(found))
+	for i, f := range found {
+		ids[i] = f.ID
+	}
+	if _, err := coll.DeleteMany(ctx, bson.M{"user_id": userObjID, "_id": bson.M{"$in": ids}}); err != nil {
+		return nil, err
+	}
+	utils.LogSuccess(fmt.Sprintf("Удалено %d записей анализа", len(ids)))
+	return ids, nil
+}
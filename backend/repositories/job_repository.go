@@ -0,0 +1,122 @@
+// job_repository.go
+
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/db"
+	"legally/models"
+	"legally/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrJobNotFound = errors.New("задача не найдена")
+
+func CreateJob(userID, filename string) (*models.Job, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+
+	now := time.Now()
+	job := &models.Job{
+		UserID:    objID,
+		Filename:  filename,
+		Status:    models.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := db.GetCollection("jobs").InsertOne(ctx, job)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Ошибка создания задачи: %v", err))
+		return nil, err
+	}
+
+	job.ID = res.InsertedID.(primitive.ObjectID)
+	utils.LogSuccess(fmt.Sprintf("Создана задача анализа %s", job.ID.Hex()))
+	return job, nil
+}
+
+// GetJob возвращает задачу пользователя; чужие задачи не находятся
+func GetJob(userID, jobID string) (*models.Job, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+	jobObjID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.Job
+	err = db.GetCollection("jobs").FindOne(ctx, bson.M{"_id": jobObjID, "user_id": userObjID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateJob меняет статус задачи и дополнительные поля.
+// Завершённые задачи не переводятся в другой статус.
+func UpdateJob(jobID primitive.ObjectID, status models.JobStatus, fields bson.M) error {
+	now := time.Now()
+	set := bson.M{"status": status, "updated_at": now}
+	for k, v := range fields {
+		set[k] = v
+	}
+	switch {
+	case status == models.JobRunning:
+		set["started_at"] = now
+	case status.Finished():
+		set["finished_at"] = now
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.GetCollection("jobs").UpdateOne(ctx,
+		bson.M{"_id": jobID, "status": bson.M{"$nin": []models.JobStatus{models.JobDone, models.JobFailed, models.JobCancelled}}},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Ошибка обновления задачи %s: %v", jobID.Hex(), err))
+	}
+	return err
+}
+
+// FailInterruptedJobs помечает неудачными задачи, оборванные остановкой сервера
+func FailInterruptedJobs() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	res, err := db.GetCollection("jobs").UpdateMany(ctx,
+		bson.M{"status": bson.M{"$in": []models.JobStatus{models.JobQueued, models.JobRunning}}},
+		bson.M{"$set": bson.M{
+			"status":      models.JobFailed,
+			"error":       "анализ прерван перезапуском сервера",
+			"updated_at":  now,
+			"finished_at": now,
+		}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
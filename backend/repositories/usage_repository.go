@@ -0,0 +1,86 @@
+// usage_repository.go
+
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"legally/db"
+	"legally/models"
+	"legally/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const usageDateLayout = "2006-01-02"
+
+// RecordUsage добавляет расход анализа к дневным агрегатам пользователя
+func RecordUsage(userID string, usage *models.UsageSummary, at time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("неверный ID пользователя")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	date := at.UTC().Format(usageDateLayout)
+	coll := db.GetCollection("usage")
+	for _, m := range usage.Models {
+		_, err := coll.UpdateOne(ctx,
+			bson.M{"user_id": objID, "date": date, "model": m.Model},
+			bson.M{
+				"$inc": bson.M{
+					"analyses":          1,
+					"requests":          m.Requests,
+					"prompt_tokens":     m.PromptTokens,
+					"completion_tokens": m.CompletionTokens,
+					"total_tokens":      m.TotalTokens,
+					"cost":              m.Cost,
+				},
+				"$set": bson.M{"updated_at": time.Now()},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Ошибка учёта расхода токенов: %v", err))
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserUsage возвращает дневные агрегаты пользователя за период [from, to] (даты в UTC)
+func GetUserUsage(userID string, from, to time.Time) ([]models.DailyUsage, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID пользователя")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id": objID,
+		"date": bson.M{
+			"$gte": from.UTC().Format(usageDateLayout),
+			"$lte": to.UTC().Format(usageDateLayout),
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "model", Value: 1}})
+
+	cursor, err := db.GetCollection("usage").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	usage := []models.DailyUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,286 @@
+// analysis_parser.go
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"legally/models"
+	"legally/utils"
+	"regexp"
+	"strings"
+)
+
+const (
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+)
+
+// analysisSchema — JSON-схема ответа, которую получает модель
+const analysisSchema = `{
+  "risks": [
+    {"title": "название риска", "description": "подробное описание", "level": "high|medium|low", "legal_reference": "закон/статья", "recommendation": "предложение по исправлению", "section": "раздел документа"}
+  ],
+  "ambiguities": [
+    {"wording": "формулировка из документа", "problem": "в чем неясность", "level": "high|medium|low", "legal_reference": "закон/статья или пустая строка", "recommendation": "как переформулировать", "section": "раздел документа"}
+  ],
+  "violations": [
+    {"description": "описание нарушения", "consequences": "возможные санкции", "level": "high|medium|low", "legal_reference": "закон/статья", "recommendation": "как избежать", "section": "раздел документа"}
+  ],
+  "recommendations": [
+    {"text": "конкретная рекомендация", "level": "high|medium|low", "legal_reference": "закон/статья или пустая строка"}
+  ],
+  "conclusion": "общая сводка по документу с выводами"
+}`
+
+var (
+	thinkBlockRe  = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	numberedRe    = regexp.MustCompile(`^\s*(?:\*\*)?\d+[.)]\s+(.+)$`)
+	bulletRe      = regexp.MustCompile(`^\s*[-*•]\s+(.+)$`)
+	fieldRe       = regexp.MustCompile(`^\s*[-*•]?\s*\**([^:*]{2,40})\**:\s*(.*)$`)
+	hashHeadingRe = regexp.MustCompile(`^\s*#{1,6}\s+\S`)
+	boldHeadingRe = regexp.MustCompile(`^\s*\*\*([^*:]+)\*\*\s*$`)
+)
+
+// parseAnalysis разбирает ответ модели: сначала как JSON по схеме,
+// при неудаче — как markdown с прежними разделами
+func parseAnalysis(raw string) (*models.AnalysisResult, string, error) {
+	raw = thinkBlockRe.ReplaceAllString(raw, "")
+
+	jsonResult, jsonErr := parseAnalysisJSON(raw)
+	if jsonErr == nil {
+		jsonResult.Normalize()
+		if jsonErr = jsonResult.Validate(); jsonErr == nil {
+			return jsonResult, formatJSON, nil
+		}
+	}
+	utils.LogWarning(fmt.Sprintf("Ответ AI не соответствует JSON-схеме, разбираем как markdown: %v", jsonErr))
+
+	mdResult := parseAnalysisMarkdown(raw)
+	mdResult.Normalize()
+	if mdResult.IsEmpty() {
+		if jsonResult == nil {
+			return nil, "", fmt.Errorf("не удалось разобрать ответ AI")
+		}
+		mdResult = jsonResult
+	}
+
+	if dropped := mdResult.DropInvalid(); dropped > 0 {
+		utils.LogWarning(fmt.Sprintf("Отброшено %d некорректных пунктов анализа", dropped))
+	}
+	if mdResult == jsonResult {
+		return mdResult, formatJSON, nil
+	}
+	return mdResult, formatMarkdown, nil
+}
+
+func parseAnalysisJSON(raw string) (*models.AnalysisResult, error) {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("JSON не найден в ответе")
+	}
+
+	var result models.AnalysisResult
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("некорректный JSON: %w", err)
+	}
+	return &result, nil
+}
+
+type markdownSection int
+
+const (
+	sectionNone markdownSection = iota
+	sectionRisks
+	sectionAmbiguities
+	sectionViolations
+	sectionRecommendations
+	sectionConclusion
+)
+
+// markdownSections — разделы отчёта по ключам models.MarkdownSection
+var markdownSections = map[string]markdownSection{
+	models.MarkdownRisks:           sectionRisks,
+	models.MarkdownAmbiguities:     sectionAmbiguities,
+	models.MarkdownViolations:      sectionViolations,
+	models.MarkdownRecommendations: sectionRecommendations,
+	models.MarkdownConclusion:      sectionConclusion,
+}
+
+// Основы русских названий разделов и полей: модель часто пишет их не дословно
+// («Риски», «Нормативная база»), а не так, как в подписях отчёта
+var (
+	sectionStems = []struct {
+		stem    string
+		section markdownSection
+	}{
+		{"риск", sectionRisks},
+		{"неясн", sectionAmbiguities},
+		{"нарушен", sectionViolations},
+		{"рекомендац", sectionRecommendations},
+		{"заключен", sectionConclusion},
+	}
+	fieldStems = []struct{ stem, key string }{
+		{"описание", models.MarkdownFieldDescription},
+		{"уровень", models.MarkdownFieldLevel},
+		{"приоритет", models.MarkdownFieldLevel},
+		{"нормативн", models.MarkdownFieldLegalReference},
+		{"закон", models.MarkdownFieldLegalReference},
+		{"рекомендац", models.MarkdownFieldRecommendation},
+		{"раздел", models.MarkdownFieldSection},
+		{"проблема", models.MarkdownFieldProblem},
+		{"последств", models.MarkdownFieldConsequences},
+	}
+)
+
+// isHeading сообщает, что строка — заголовок: с разметкой # или целиком
+// выделенная жирным без двоеточия и номера, чтобы не путать её с полем
+// («**Уровень риска:** высокий») или пунктом («**1. Риск неустойки**»)
+func isHeading(line string) bool {
+	if hashHeadingRe.MatchString(line) {
+		return true
+	}
+	m := boldHeadingRe.FindStringSubmatch(line)
+	return m != nil && !numberedRe.MatchString(m[1])
+}
+
+// detectSection распознаёт заголовок раздела на русском, казахском или английском
+func detectSection(line string) (markdownSection, bool) {
+	if !isHeading(line) {
+		return sectionNone, false
+	}
+	if key, ok := models.MarkdownSection(line); ok {
+		return markdownSections[key], true
+	}
+
+	lower := strings.ToLower(line)
+	for _, s := range sectionStems {
+		if strings.Contains(lower, s.stem) {
+			return s.section, true
+		}
+	}
+	return sectionNone, false
+}
+
+// fieldKey приводит подпись поля пункта к ключу models.MarkdownField*;
+// нераспознанная подпись остаётся как есть
+func fieldKey(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if key, ok := models.MarkdownField(label); ok {
+		return key
+	}
+	for _, f := range fieldStems {
+		if strings.HasPrefix(label, f.stem) {
+			return f.key
+		}
+	}
+	return label
+}
+
+// markdownItem — пункт раздела: заголовок и поля вида "- Метка: значение"
+type markdownItem struct {
+	title  string
+	fields map[string]string
+}
+
+func (it *markdownItem) field(key string) string {
+	return it.fields[key]
+}
+
+func parseAnalysisMarkdown(raw string) *models.AnalysisResult {
+	result := &models.AnalysisResult{}
+	items := map[markdownSection][]*markdownItem{}
+	var conclusion []string
+
+	section := sectionNone
+	var current *markdownItem
+
+	for _, line := range strings.Split(raw, "\n") {
+		if s, ok := detectSection(line); ok {
+			section, current = s, nil
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || section == sectionNone {
+			continue
+		}
+
+		if section == sectionConclusion {
+			conclusion = append(conclusion, trimmed)
+			continue
+		}
+
+		if m := fieldRe.FindStringSubmatch(line); m != nil && current != nil && !numberedRe.MatchString(line) {
+			current.fields[fieldKey(m[1])] = cleanMarkdown(m[2])
+			continue
+		}
+
+		if m := numberedRe.FindStringSubmatch(line); m != nil {
+			current = &markdownItem{title: cleanMarkdown(m[1]), fields: map[string]string{}}
+			items[section] = append(items[section], current)
+			continue
+		}
+
+		if m := bulletRe.FindStringSubmatch(line); m != nil && section == sectionRecommendations {
+			current = &markdownItem{title: cleanMarkdown(m[1]), fields: map[string]string{}}
+			items[section] = append(items[section], current)
+		}
+	}
+
+	for _, it := range items[sectionRisks] {
+		result.Risks = append(result.Risks, models.Risk{
+			Title:          it.title,
+			Description:    it.field(models.MarkdownFieldDescription),
+			Level:          markdownLevel(it.field(models.MarkdownFieldLevel)),
+			LegalReference: it.field(models.MarkdownFieldLegalReference),
+			Recommendation: it.field(models.MarkdownFieldRecommendation),
+			Section:        it.field(models.MarkdownFieldSection),
+		})
+	}
+	for _, it := range items[sectionAmbiguities] {
+		result.Ambiguities = append(result.Ambiguities, models.Ambiguity{
+			Wording:        it.title,
+			Problem:        it.field(models.MarkdownFieldProblem),
+			Level:          markdownLevel(it.field(models.MarkdownFieldLevel)),
+			LegalReference: it.field(models.MarkdownFieldLegalReference),
+			Recommendation: it.field(models.MarkdownFieldRecommendation),
+			Section:        it.field(models.MarkdownFieldSection),
+		})
+	}
+	for _, it := range items[sectionViolations] {
+		result.Violations = append(result.Violations, models.Violation{
+			Description:    it.title,
+			Consequences:   it.field(models.MarkdownFieldConsequences),
+			Level:          markdownLevel(it.field(models.MarkdownFieldLevel)),
+			LegalReference: it.field(models.MarkdownFieldLegalReference),
+			Recommendation: it.field(models.MarkdownFieldRecommendation),
+			Section:        it.field(models.MarkdownFieldSection),
+		})
+	}
+	for _, it := range items[sectionRecommendations] {
+		result.Recommendations = append(result.Recommendations, models.Recommendation{
+			Text:           it.title,
+			Level:          markdownLevel(it.field(models.MarkdownFieldLevel)),
+			LegalReference: it.field(models.MarkdownFieldLegalReference),
+		})
+	}
+
+	result.Conclusion = cleanMarkdown(strings.Join(conclusion, "\n"))
+	return result
+}
+
+// markdownLevel — в markdown-ответах уровень часто не указан, такие пункты считаем средними
+func markdownLevel(s string) models.RiskLevel {
+	if level, ok := models.ParseRiskLevel(s); ok {
+		return level
+	}
+	return models.LevelMedium
+}
+
+func cleanMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "**", "")
+	s = strings.ReplaceAll(s, "__", "")
+	return strings.TrimSpace(s)
+}
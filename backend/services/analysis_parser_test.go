@@ -0,0 +1,252 @@
+package services
+
+import (
+	"legally/models"
+	"testing"
+)
+
+func TestParseAnalysisMarkdownLanguages(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"ru", `### Правовые риски
+
+1. Одностороннее расторжение
+   - Описание: Арендодатель вправе расторгнуть договор без уведомления
+   - Нормативный акт: ГК РК, ст. 404
+   - Уровень риска: высокий
+
+### Неясные формулировки
+
+1. «в разумный срок»
+   - Проблема: Срок не определён
+   - Уровень важности: низкий
+
+### Возможные нарушения
+
+1. Неустойка выше допустимой
+   - Последствия: Условие может быть признано недействительным
+   - Уровень риска: средний
+
+### Рекомендации
+
+1. Добавить срок уведомления
+   - Приоритет: высокий
+
+### Заключение
+
+Договор требует доработки.
+`},
+		{"kk", `### Құқықтық тәуекелдер
+
+1. Біржақты бұзу
+   - Сипаттама: Жалға беруші шартты ескертусіз бұза алады
+   - Нормативтік акт: ҚР АК, 404-бап
+   - Тәуекел деңгейі: жоғары
+
+### Түсініксіз тұжырымдар
+
+1. «ақылға қонымды мерзімде»
+   - Мәселе: Мерзім анықталмаған
+   - Маңыздылық деңгейі: төмен
+
+### Ықтимал бұзушылықтар
+
+1. Тұрақсыздық айыбы шектен асады
+   - Салдары: Талап жарамсыз деп танылуы мүмкін
+   - Тәуекел деңгейі: орташа
+
+### Ұсынымдар
+
+1. Ескерту мерзімін қосу
+   - Басымдық: жоғары
+
+### Қорытынды
+
+Шартты пысықтау қажет.
+`},
+		{"en", `### Legal risks
+
+1. Unilateral termination
+   - Description: The landlord may terminate without notice
+   - Legal reference: Civil Code, art. 404
+   - Risk level: high
+
+### Ambiguous wording
+
+1. "within a reasonable time"
+   - Problem: The term is not defined
+   - Importance: low
+
+### Possible violations
+
+1. Penalty above the statutory cap
+   - Consequences: The clause may be held void
+   - Risk level: medium
+
+### Recommendations
+
+1. Add a notice period
+   - Priority: high
+
+### Conclusion
+
+The contract needs revision.
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseAnalysisMarkdown(tt.raw)
+
+			if len(r.Risks) != 1 || len(r.Ambiguities) != 1 || len(r.Violations) != 1 || len(r.Recommendations) != 1 {
+				t.Fatalf("parsed %d risks, %d ambiguities, %d violations, %d recommendations; want 1 each",
+					len(r.Risks), len(r.Ambiguities), len(r.Violations), len(r.Recommendations))
+			}
+			if r.Risks[0].Level != models.LevelHigh || r.Risks[0].Description == "" || r.Risks[0].LegalReference == "" {
+				t.Errorf("risk = %+v", r.Risks[0])
+			}
+			if r.Ambiguities[0].Level != models.LevelLow || r.Ambiguities[0].Problem == "" {
+				t.Errorf("ambiguity = %+v", r.Ambiguities[0])
+			}
+			if r.Violations[0].Level != models.LevelMedium || r.Violations[0].Consequences == "" {
+				t.Errorf("violation = %+v", r.Violations[0])
+			}
+			if r.Recommendations[0].Level != models.LevelHigh {
+				t.Errorf("recommendation = %+v", r.Recommendations[0])
+			}
+			if r.Conclusion == "" {
+				t.Error("conclusion is empty")
+			}
+		})
+	}
+}
+
+func TestParseAnalysisMarkdownRoundTrip(t *testing.T) {
+	want := &models.AnalysisResult{
+		Risks: []models.Risk{{
+			Title: "Risk", Description: "Description", LegalReference: "Art. 1",
+			Level: models.LevelHigh, Recommendation: "Fix it", Section: "2.1",
+		}},
+		Ambiguities:     []models.Ambiguity{{Wording: "Wording", Problem: "Problem", Level: models.LevelLow}},
+		Violations:      []models.Violation{{Description: "Violation", Consequences: "Fine", Level: models.LevelMedium}},
+		Recommendations: []models.Recommendation{{Text: "Recommendation", Level: models.LevelHigh}},
+		Conclusion:      "Conclusion",
+	}
+
+	for _, lang := range []string{"ru", "kk", "en"} {
+		t.Run(lang, func(t *testing.T) {
+			got := parseAnalysisMarkdown(want.MarkdownIn(lang))
+
+			if len(got.Risks) != 1 || got.Risks[0] != want.Risks[0] {
+				t.Errorf("risks = %+v, want %+v", got.Risks, want.Risks)
+			}
+			if len(got.Ambiguities) != 1 || got.Ambiguities[0] != want.Ambiguities[0] {
+				t.Errorf("ambiguities = %+v, want %+v", got.Ambiguities, want.Ambiguities)
+			}
+			if len(got.Violations) != 1 || got.Violations[0] != want.Violations[0] {
+				t.Errorf("violations = %+v, want %+v", got.Violations, want.Violations)
+			}
+			if len(got.Recommendations) != 1 || got.Recommendations[0] != want.Recommendations[0] {
+				t.Errorf("recommendations = %+v, want %+v", got.Recommendations, want.Recommendations)
+			}
+			if got.Conclusion != want.Conclusion {
+				t.Errorf("conclusion = %q, want %q", got.Conclusion, want.Conclusion)
+			}
+		})
+	}
+}
+
+func TestParseAnalysisMarkdownBoldLines(t *testing.T) {
+	// Жирные поля и пункты не считаются заголовками разделов,
+	// хотя содержат основы «риск» и «рекомендац»
+	raw := `**Правовые риски**
+
+**1. Риск неустойки**
+   - **Уровень риска:** высокий
+   - **Рекомендация:** Снизить неустойку
+
+2. Отсутствие срока
+**Уровень риска:** низкий
+
+**Рекомендации**
+
+1. Указать срок оплаты
+
+**Заключение**
+
+Договор требует доработки.
+`
+	r := parseAnalysisMarkdown(raw)
+
+	if len(r.Risks) != 2 {
+		t.Fatalf("parsed %d risks, want 2: %+v", len(r.Risks), r.Risks)
+	}
+	if r.Risks[0].Title != "Риск неустойки" || r.Risks[0].Level != models.LevelHigh || r.Risks[0].Recommendation != "Снизить неустойку" {
+		t.Errorf("risk = %+v", r.Risks[0])
+	}
+	if r.Risks[1].Level != models.LevelLow {
+		t.Errorf("risk = %+v", r.Risks[1])
+	}
+	if len(r.Recommendations) != 1 || r.Recommendations[0].Text != "Указать срок оплаты" {
+		t.Errorf("recommendations = %+v", r.Recommendations)
+	}
+	if r.Conclusion != "Договор требует доработки." {
+		t.Errorf("conclusion = %q", r.Conclusion)
+	}
+}
+
+func TestDetectSection(t *testing.T) {
+	tests := []struct {
+		line string
+		want markdownSection
+		ok   bool
+	}{
+		{"### Правовые риски", sectionRisks, true},
+		{"## Recommendations", sectionRecommendations, true},
+		{"**Заключение**", sectionConclusion, true},
+		{"**Уровень риска:** высокий", sectionNone, false},
+		{"**1. Риск неустойки**", sectionNone, false},
+		{"**Риски:**", sectionNone, false},
+		{"Риски договора", sectionNone, false},
+		{"#риск", sectionNone, false},
+	}
+	for _, tt := range tests {
+		got, ok := detectSection(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("detectSection(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseAnalysisFallback(t *testing.T) {
+	valid := `{"risks": [{"title": "Неустойка", "description": "Завышена", "level": "high", "legal_reference": "ГК РК, ст. 293", "recommendation": "Снизить"}], "conclusion": "Итог"}`
+
+	tests := []struct {
+		name       string
+		raw        string
+		wantFormat string
+		wantRisks  int
+		wantErr    bool
+	}{
+		{"json", valid, formatJSON, 1, false},
+		{"json with think block", "<think>{\"x\": 1}</think>\n" + valid, formatJSON, 1, false},
+		{"markdown", "### Риски\n\n1. Неустойка\n   - Уровень риска: высокий\n\n### Заключение\n\nИтог", formatMarkdown, 1, false},
+		{"garbage", "нет ответа", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, format, err := parseAnalysis(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAnalysis() error = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if format != tt.wantFormat || len(r.Risks) != tt.wantRisks {
+				t.Errorf("parseAnalysis() = %d risks as %s, want %d as %s", len(r.Risks), format, tt.wantRisks, tt.wantFormat)
+			}
+		})
+	}
+}
@@ -2,224 +2,512 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"legally/llm"
+	"legally/models"
+	"legally/prompts"
+	"legally/redact"
 	"legally/repositories"
 	"legally/utils"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/net/context"
 )
 
-const (
-	model       = "deepseek/deepseek-r1-0528:free"
-	apiEndpoint = "https://openrouter.ai/api/v1/chat/completions"
-)
-
 type HttpError struct {
-	Status  int
-	Message string
+	Status     int
+	Message    string
+	Code       string
+	RetryAfter time.Duration
 }
 
-func AnalyzeDocument(c *gin.Context) (interface{}, *HttpError) {
-	utils.LogAction("Получен запрос на анализ документа")
+// uploadHttpError переводит ошибку обработки загруженного файла в HTTP-ответ
+func uploadHttpError(err error) *HttpError {
+	switch {
+	case errors.Is(err, utils.ErrFileTooLarge):
+		return &HttpError{Status: http.StatusRequestEntityTooLarge, Message: err.Error(), Code: "FILE_TOO_LARGE"}
+	case errors.Is(err, utils.ErrBundleLimit):
+		return &HttpError{Status: http.StatusRequestEntityTooLarge, Message: err.Error(), Code: "BUNDLE_LIMIT"}
+	case errors.Is(err, utils.ErrUnsupportedFile):
+		return &HttpError{Status: http.StatusUnsupportedMediaType, Message: err.Error(), Code: "INVALID_FILE_TYPE"}
+	default:
+		return &HttpError{Status: http.StatusBadRequest, Message: err.Error(), Code: "FILE_ERROR"}
+	}
+}
+
+// analysisHttpError переводит ошибку анализа в HTTP-ответ с учётом типа ошибки LLM
+func analysisHttpError(err error) *HttpError {
+	httpErr := &HttpError{Status: http.StatusInternalServerError, Message: err.Error(), Code: "ANALYSIS_ERROR"}
+
+	var llmErr *llm.Error
+	if errors.As(err, &llmErr) {
+		httpErr.RetryAfter = llmErr.RetryAfter
+	}
+
+	switch {
+	case errors.Is(err, llm.ErrCircuitOpen):
+		httpErr.Status, httpErr.Code = http.StatusServiceUnavailable, "LLM_UNAVAILABLE"
+	case errors.Is(err, llm.ErrRateLimited):
+		httpErr.Status, httpErr.Code = http.StatusTooManyRequests, "LLM_RATE_LIMITED"
+	case errors.Is(err, llm.ErrTimeout):
+		httpErr.Status, httpErr.Code = http.StatusGatewayTimeout, "LLM_TIMEOUT"
+	case errors.Is(err, llm.ErrUpstream):
+		httpErr.Status, httpErr.Code = http.StatusBadGateway, "LLM_UPSTREAM_ERROR"
+	case errors.Is(err, llm.ErrEmptyAnswer):
+		httpErr.Status, httpErr.Code = http.StatusBadGateway, "LLM_EMPTY_ANSWER"
+	}
+
+	return httpErr
+}
+
+const defaultAnalysisWorkers = 4
+
+// TextAnalysis — итог анализа текста документа
+type TextAnalysis struct {
+	Markdown string
+	Result   *models.AnalysisResult
+	DocType  string
+	Parts    []models.PartStatus
+	Partial  bool
+	Usage    *models.UsageSummary
+	Prompts  []models.PromptRef
+	Pages    []models.Page
+	// Язык документа и язык, на котором написан отчёт
+	Language         string
+	ResponseLanguage string
+	Redaction        *models.RedactionReport
+	Metadata         *models.ContractMetadata
+	Classification   *models.Classification
+	// Статьи законов, переданные модели вместе с частями документа,
+	// и проверка ссылок на нормы из ответа модели
+	LegalSources []models.LegalSource
+	Citations    []models.Citation
+}
+
+// analysisPrompts — шаблоны промптов, выбранные для одного анализа,
+// и язык, на котором модель должна ответить
+type analysisPrompts struct {
+	analysis *prompts.Template
+	reduce   *prompts.Template
+	response string
+}
 
-	text, filename, err := utils.ProcessUploadedFile(c)
+// responseLanguage — название языка ответа для подстановки в шаблон t
+func (p *analysisPrompts) responseLanguage(t *prompts.Template) string {
+	return languageName(p.response, t.Language)
+}
+
+func resolvePrompts(docType, language, response string) (*analysisPrompts, error) {
+	registry := prompts.Default()
+	docTypes := docTypeCandidates(docType)
+
+	analysis, err := registry.ResolveAny("analysis", docTypes, language)
 	if err != nil {
-		utils.LogError(err.Error())
-		return nil, &HttpError{Status: http.StatusBadRequest, Message: err.Error()}
+		return nil, err
+	}
+	reduce, err := registry.ResolveAny("reduce", docTypes, language)
+	if err != nil {
+		return nil, err
 	}
+	return &analysisPrompts{analysis: analysis, reduce: reduce, response: response}, nil
+}
 
-	utils.LogInfo(fmt.Sprintf("Извлечено %d символов из документа", len(text)))
+func (p *analysisPrompts) refs() []models.PromptRef {
+	var refs []models.PromptRef
+	for _, t := range []*prompts.Template{p.analysis, p.reduce} {
+		refs = append(refs, models.PromptRef{ID: t.ID, Version: t.Version, Language: t.Language, DocType: t.DocType})
+	}
+	return refs
+}
 
-	analysis, docType, err := AnalyzeText(text)
+func AnalyzeDocument(c *gin.Context) (interface{}, *HttpError) {
+	utils.LogAction("Получен запрос на анализ документа")
+
+	doc, err := utils.ProcessUploadedFile(c)
 	if err != nil {
 		utils.LogError(err.Error())
-		return nil, &HttpError{Status: http.StatusInternalServerError, Message: err.Error()}
+		return nil, uploadHttpError(err)
 	}
 
+	utils.LogInfo(fmt.Sprintf("Извлечено %d символов из документа", len(doc.Text)))
+
 	userID, _ := c.Get("userId")
-	err = repositories.SaveAnalysis(userID.(string), filename, docType, analysis, text)
-	if err != nil {
-		utils.LogWarning(fmt.Sprintf("Ошибка сохранения в MongoDB: %v", err))
+	force := forceReanalysis(c)
+	ctx := withResponseLanguage(c.Request.Context(), requestedLanguage(c, userID.(string)))
+	ctx = withDocumentType(ctx, requestedDocumentType(c))
+	if !doc.IsBundle() && !force {
+		if existing := findDuplicate(ctx, userID.(string), doc); existing != nil {
+			return duplicateResponse(existing), nil
+		}
 	}
 
-	// 🔵 Отправка эмбеддинга в Pinecone
-	err = SendToPinecone(
-		userID.(string),
-		text,
-		map[string]string{
-			"filename": filename,
-			"type":     docType,
-		},
-	)
+	ctx, meter := withUsageMeter(ctx)
+	defer recordUserUsage(userID.(string), meter)
+
+	if doc.IsBundle() {
+		bundle, err := analyzeBundle(ctx, userID.(string), doc, force)
+		if err != nil {
+			utils.LogError(err.Error())
+			return nil, analysisHttpError(err)
+		}
+		_, _ = storeBundle(userID.(string), doc, bundle)
+
+		utils.LogSuccess(fmt.Sprintf("Анализ архива %s готов, отправляем ответ клиенту", doc.Filename))
+		return bundleResponse(doc.Filename, bundle), nil
+	}
+
+	analysis, err := AnalyzeText(ctx, doc)
 	if err != nil {
-		utils.LogWarning(fmt.Sprintf("Ошибка отправки в Pinecone: %v", err))
+		utils.LogError(err.Error())
+		return nil, analysisHttpError(err)
 	}
 
+	_, _ = storeAnalysis(userID.(string), doc, analysis)
+
 	utils.LogSuccess("Полный анализ готов, отправляем ответ клиенту")
-	utils.LogInfo(fmt.Sprintf("Тип документа: %s, длина анализа: %d символов", docType, len(analysis)))
+	utils.LogInfo(fmt.Sprintf("Тип документа: %s, длина анализа: %d символов", analysis.DocType, len(analysis.Markdown)))
 
-	return gin.H{
-		"analysis":      analysis,
-		"timestamp":     time.Now().Format(time.RFC3339),
-		"document_type": docType,
-		"filename":      filename,
-	}, nil
+	return analysisResponse(doc.Filename, analysis), nil
 }
 
-func AnalyzeText(text string) (string, string, error) {
-	parts := utils.SplitText(text, 12000)
-	utils.LogInfo(fmt.Sprintf("Документ разбит на %d частей для анализа", len(parts)))
+// storeAnalysis сохраняет анализ в MongoDB и отправляет текст на индексацию.
+// Ошибка индексации только логируется, ошибка сохранения возвращается.
+func storeAnalysis(userID string, doc *utils.UploadedDocument, analysis *TextAnalysis) (*models.Analysis, error) {
+	return saveAnalysisRecord(userID, newAnalysisRecord(doc, analysis))
+}
 
-	var analysisResults []string
-	for i, part := range parts {
-		partNum := i + 1
-		utils.LogAction(fmt.Sprintf("Анализ части %d/%d...", partNum, len(parts)))
+func newAnalysisRecord(doc *utils.UploadedDocument, analysis *TextAnalysis) *models.Analysis {
+	return &models.Analysis{
+		Filename:         doc.Filename,
+		Type:             analysis.DocType,
+		Classification:   analysis.Classification,
+		Analysis:         analysis.Markdown,
+		Result:           analysis.Result,
+		Parts:            analysis.Parts,
+		Usage:            analysis.Usage,
+		Prompts:          analysis.Prompts,
+		Pages:            analysis.Pages,
+		ContentHash:      doc.ContentHash,
+		Language:         analysis.Language,
+		Text:             doc.Text,
+		ResponseLanguage: analysis.ResponseLanguage,
+		Redaction:        analysis.Redaction,
+		Metadata:         analysis.Metadata,
+		LegalSources:     analysis.LegalSources,
+		Citations:        analysis.Citations,
+	}
+}
 
-		result, err := analyzeDocumentPart(part)
-		if err != nil {
-			utils.LogError(fmt.Sprintf("При анализе части %d: %v", partNum, err))
-			return "", "", err
-		}
+func saveAnalysisRecord(userID string, record *models.Analysis) (*models.Analysis, error) {
+	saveErr := repositories.SaveAnalysis(userID, record)
+	if saveErr != nil {
+		utils.LogWarning(fmt.Sprintf("Ошибка сохранения в MongoDB: %v", saveErr))
+	}
 
-		utils.LogSuccess(fmt.Sprintf("Анализ части %d завершён, результат длиной %d символов", partNum, len(result)))
-		analysisResults = append(analysisResults, result)
+	if err := indexAnalysis(userID, record); err != nil {
+		utils.LogWarning(fmt.Sprintf("Ошибка индексации документа в векторном хранилище: %v", err))
 	}
 
-	fullAnalysis := strings.Join(analysisResults, "\n\n---\n\n")
-	docType := detectDocumentType(text)
+	return record, saveErr
+}
 
-	return fullAnalysis, docType, nil
+func analysisResponse(filename string, analysis *TextAnalysis) gin.H {
+	return gin.H{
+		"analysis":          analysis.Markdown,
+		"result":            analysis.Result,
+		"parts":             analysis.Parts,
+		"partial":           analysis.Partial,
+		"usage":             analysis.Usage,
+		"prompts":           analysis.Prompts,
+		"pages":             analysis.Pages,
+		"language":          analysis.Language,
+		"response_language": analysis.ResponseLanguage,
+		"redaction":         analysis.Redaction,
+		"metadata":          analysis.Metadata,
+		"timestamp":         time.Now().Format(time.RFC3339),
+		"document_type":     analysis.DocType,
+		"classification":    analysis.Classification,
+		"legal_sources":     analysis.LegalSources,
+		"citations":         analysis.Citations,
+		"filename":          filename,
+	}
 }
 
-func analyzeDocumentPart(text string) (string, error) {
-	prompt := fmt.Sprintf(`Проанализируй следующий юридический документ на соответствие законодательству Казахстана. 
+// AnalysisObserver получает события анализа по мере выполнения.
+// Любой обработчик может быть nil; вызовы приходят из разных горутин.
+type AnalysisObserver struct {
+	OnStart       func(parts []utils.DocumentPart)
+	OnPartStart   func(part utils.DocumentPart)
+	OnToken       func(part int, delta string)
+	OnPartEnd     func(status models.PartStatus)
+	OnConsolidate func()
+}
 
-В ответе придерживайся следующей структуры:
+// AnalyzeText анализирует текст документа. Если у документа есть страницы,
+// находкам проставляются номера страниц.
+func AnalyzeText(ctx context.Context, doc *utils.UploadedDocument) (*TextAnalysis, error) {
+	return analyzeText(ctx, doc, &AnalysisObserver{})
+}
 
-### Правовые риски
+// AnalyzeTextStream анализирует текст в потоковом режиме провайдера, сообщая о ходе работы observer
+func AnalyzeTextStream(ctx context.Context, doc *utils.UploadedDocument, observer *AnalysisObserver) (*TextAnalysis, error) {
+	return analyzeText(ctx, doc, observer)
+}
 
-1. [Название риска]
-   - Описание: [подробное описание]
-   - Нормативный акт: [закон/статья]
-   - Уровень риска: [высокий/средний/низкий]
-   - Рекомендация: [предложение по исправлению]
+func analyzeText(ctx context.Context, doc *utils.UploadedDocument, observer *AnalysisObserver) (*TextAnalysis, error) {
+	ctx, meter := withUsageMeter(ctx)
+	text := doc.Text
 
-2. [Название риска]
-   ...
+	lang := doc.Language
+	if lang == "" {
+		lang = utils.DetectLanguage(text).Code
+	}
+	respLang := responseLanguage(ctx, lang)
 
-### Неясные формулировки
+	classification := documentClassification(ctx, text, lang)
+	docType := classification.Label
+	utils.LogInfo(fmt.Sprintf("Тип документа: %s (уверенность %.2f)", docType, classification.Confidence))
+	tpl, err := resolvePrompts(docType, lang, respLang)
+	if err != nil {
+		return nil, err
+	}
+	utils.LogInfo(fmt.Sprintf("Язык документа: %s, язык отчёта: %s", lang, respLang))
 
-1. [Формулировка]
-   - Проблема: [в чем неясность]
-   - Рекомендация: [как переформулировать]
-   - Уровень важности: [высокий/средний/низкий]
+	parts := utils.SplitDocument(text, utils.DefaultSplitOptions())
+	utils.LogInfo(fmt.Sprintf("Документ разбит на %d частей для анализа", len(parts)))
 
-### Возможные нарушения
+	// Персональные данные не покидают сервер: модель видит заглушки,
+	// а смещения частей остаются смещениями в исходном тексте
+	redactor := redact.New()
+	redactor.Redact(text)
+	masked := redactParts(parts, redactor)
+	if report := redactor.Report(); report.Total > 0 {
+		utils.LogInfo(fmt.Sprintf("Замаскировано персональных данных: %d %v", report.Total, report.ByKind))
+	}
 
-1. [Описание нарушения]
-   - Нормативный акт: [закон/статья]
-   - Последствия: [возможные санкции]
-   - Рекомендация: [как избежать]
+	if observer.OnStart != nil {
+		observer.OnStart(masked)
+	}
 
-### Рекомендации
+	// Статьи подбираются по замаскированному тексту — тому же, что увидит модель
+	legal := retrieveLegalContext(masked)
+	results, statuses, errs := analyzeParts(ctx, masked, legal, tpl, observer)
+
+	var succeeded []*models.AnalysisResult
+	var firstErr error
+	for i, result := range results {
+		if result != nil {
+			attributePartPage(result, parts[i], doc.Pages)
+			succeeded = append(succeeded, result)
+		} else if firstErr == nil {
+			firstErr = fmt.Errorf("при анализе части %d: %w", i+1, errs[i])
+		}
+	}
 
-[Список конкретных рекомендаций по исправлению документа]
+	if len(succeeded) == 0 {
+		return nil, firstErr
+	}
+	if len(succeeded) < len(parts) {
+		utils.LogWarning(fmt.Sprintf("Проанализировано %d из %d частей", len(succeeded), len(parts)))
+	}
 
-### Заключение
+	if len(succeeded) > 1 && observer.OnConsolidate != nil {
+		observer.OnConsolidate()
+	}
+	result := consolidateResults(ctx, succeeded, tpl)
+	redactor.RestoreResult(result)
+	locatePages(result, text, doc.Pages)
+	legalSources := mergeLegalSources(legal)
+
+	return &TextAnalysis{
+		Markdown:         result.MarkdownIn(respLang),
+		Result:           result,
+		DocType:          docType,
+		Classification:   classification,
+		LegalSources:     legalSources,
+		Citations:        verifyCitations(result, legalSources),
+		Parts:            statuses,
+		Partial:          len(succeeded) < len(parts),
+		Usage:            meter.Summary(),
+		Prompts:          tpl.refs(),
+		Pages:            doc.Pages,
+		Language:         lang,
+		ResponseLanguage: respLang,
+		Redaction:        redactor.Report(),
+		Metadata:         utils.ExtractContractMetadata(text),
+	}, nil
+}
 
-[Общая сводка по документу с выводами]
+// redactParts возвращает копии частей, в которых текст и заголовки разделов замаскированы
+func redactParts(parts []utils.DocumentPart, redactor *redact.Redactor) []utils.DocumentPart {
+	masked := make([]utils.DocumentPart, len(parts))
+	for i, p := range parts {
+		p.Text = redactor.Mask(p.Text)
+		sections := make([]string, len(p.Sections))
+		for j, s := range p.Sections {
+			sections[j] = redactor.Mask(s)
+		}
+		p.Sections = sections
+		masked[i] = p
+	}
+	return masked
+}
 
-Документ:
-%s`, text)
+// analyzeParts анализирует части пулом из ANALYSIS_WORKERS воркеров.
+// Результаты возвращаются в исходном порядке; у неудачных частей результат nil, а ошибка — в errs.
+func analyzeParts(ctx context.Context, parts []utils.DocumentPart, legal [][]models.LegalSource, tpl *analysisPrompts, observer *AnalysisObserver) ([]*models.AnalysisResult, []models.PartStatus, []error) {
+	results := make([]*models.AnalysisResult, len(parts))
+	statuses := make([]models.PartStatus, len(parts))
+	errs := make([]error, len(parts))
 
-	utils.LogInfo(fmt.Sprintf("Отправка запроса к AI с текстом длиной %d символов", len(text)))
+	runPool(len(parts), func(i int) {
+		partNum := i + 1
+		statuses[i] = models.PartStatus{Index: partNum}
+		utils.LogAction(fmt.Sprintf("Анализ части %d/%d...", partNum, len(parts)))
 
-	result, err := queryOpenRouter(prompt)
-	if err != nil {
-		return "", err
-	}
+		if observer.OnPartStart != nil {
+			observer.OnPartStart(parts[i])
+		}
+		if observer.OnPartEnd != nil {
+			defer func() { observer.OnPartEnd(statuses[i]) }()
+		}
 
-	utils.LogSuccess(fmt.Sprintf("Успешно получен ответ от AI длиной %d символов", len(result)))
-	return result, nil
+		var onDelta func(string)
+		if observer.OnToken != nil {
+			onDelta = func(delta string) { observer.OnToken(partNum, delta) }
+		}
+
+		result, err := analyzeDocumentPart(ctx, parts[i], legal[i], tpl, onDelta)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("При анализе части %d: %v", partNum, err))
+			statuses[i].Status = models.PartStatusFailed
+			statuses[i].Error = err.Error()
+			errs[i] = err
+			return
+		}
+
+		utils.LogSuccess(fmt.Sprintf("Анализ части %d завершён: рисков %d, нарушений %d", partNum, len(result.Risks), len(result.Violations)))
+		statuses[i].Status = models.PartStatusDone
+		results[i] = result
+	})
+
+	return results, statuses, errs
 }
 
-func queryOpenRouter(prompt string) (string, error) {
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENROUTER_API_KEY не установлен")
-	}
+// analyzeDocumentPart анализирует одну часть вместе с подобранными к ней статьями законов;
+// если onDelta задан, ответ модели читается потоком
+func analyzeDocumentPart(ctx context.Context, part utils.DocumentPart, legal []models.LegalSource, tpl *analysisPrompts, onDelta func(string)) (*models.AnalysisResult, error) {
+	text := part.Text
 
-	payload := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "system", "content": "Ты — юридический эксперт по законодательству Казахстана. Анализируй документы и давай развернутые ответы с конкретными ссылками на законы."},
-			{"role": "user", "content": prompt},
-		},
-		"temperature": 0.3,
-		"max_tokens":  4000,
+	sections := "не определены"
+	if len(part.Sections) > 0 {
+		sections = strings.Join(part.Sections, "; ")
 	}
 
-	body, err := json.Marshal(payload)
+	prompt, err := tpl.analysis.Render(prompts.Vars{
+		"Schema":           analysisSchema,
+		"Sections":         sections,
+		"Document":         text,
+		"ResponseLanguage": tpl.responseLanguage(tpl.analysis),
+		"Laws":             legalContextPrompt(legal),
+	})
 	if err != nil {
-		return "", fmt.Errorf("ошибка маршалинга payload: %w", err)
+		return nil, err
 	}
 
-	utils.LogRequest("out", apiEndpoint, len(body))
+	utils.LogInfo(fmt.Sprintf("Отправка запроса к AI с текстом длиной %d символов", len(text)))
 
-	req, err := http.NewRequest("POST", apiEndpoint, bytes.NewBuffer(body))
+	raw, err := queryLLMStream(ctx, prompt, onDelta)
 	if err != nil {
-		return "", fmt.Errorf("ошибка создания запроса: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("HTTP-Referer", "https://legally.kz")
-	req.Header.Set("X-Title", "Legally AI Risk Analyzer")
+	utils.LogSuccess(fmt.Sprintf("Успешно получен ответ от AI длиной %d символов", len(raw)))
 
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
+	result, format, err := parseAnalysis(raw)
 	if err != nil {
-		return "", fmt.Errorf("ошибка запроса к OpenRouter: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	resBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения ответа OpenRouter: %w", err)
+	if len(part.Sections) == 1 {
+		result.AttributeSection(part.Sections[0])
 	}
 
-	utils.LogRequest("in", fmt.Sprintf("OpenRouter (статус: %d)", resp.StatusCode), len(resBody))
+	utils.LogInfo(fmt.Sprintf("Ответ AI разобран (формат: %s)", format))
+	return result, nil
+}
+
+// runPool выполняет fn для индексов 0..n-1 не более чем в ANALYSIS_WORKERS горутинах
+func runPool(n int, fn func(i int)) {
+	workers := utils.GetEnvInt("ANALYSIS_WORKERS", defaultAnalysisWorkers)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка от OpenRouter: статус %d", resp.StatusCode)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
 	}
 
-	var res struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	for i := 0; i < n; i++ {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
+}
+
+func queryLLM(ctx context.Context, prompt prompts.Rendered) (string, error) {
+	return queryLLMStream(ctx, prompt, nil)
+}
 
-	if err := json.Unmarshal(resBody, &res); err != nil {
-		return "", fmt.Errorf("не удалось распарсить ответ AI: %w", err)
+// queryLLMStream отправляет запрос модели; при заданном onDelta использует потоковый режим
+func queryLLMStream(ctx context.Context, prompt prompts.Rendered, onDelta func(string)) (string, error) {
+	provider, err := llm.Current()
+	if err != nil {
+		return "", err
+	}
+
+	req := llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: prompt.System},
+			{Role: "user", Content: prompt.User},
+		},
+		Temperature: 0.3,
+		MaxTokens:   4000,
+	}
+
+	var resp *llm.Response
+	if onDelta != nil {
+		resp, err = llm.Stream(ctx, provider, req, onDelta)
+	} else {
+		resp, err = provider.Complete(ctx, req)
+	}
+	if err != nil {
+		return "", err
 	}
 
-	if len(res.Choices) == 0 || res.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("пустой ответ от OpenRouter")
+	if meter := usageMeterFrom(ctx); meter != nil {
+		meter.add(resp.Model, resp.Usage)
 	}
 
-	return res.Choices[0].Message.Content, nil
+	return resp.Content, nil
 }
 
 func GetRelevantLaws() []map[string]string {
@@ -231,45 +519,74 @@ func GetRelevantLaws() []map[string]string {
 	}
 }
 
-func GetUserHistory(userID string) ([]map[string]interface{}, error) {
-	return repositories.GetUserHistory(userID)
+func GetUserHistory(userID string, filter models.HistoryFilter) ([]map[string]interface{}, error) {
+	return repositories.GetUserHistory(userID, filter)
 }
 
-func detectDocumentType(text string) string {
-	text = strings.ToLower(text)
-	switch {
-	case strings.Contains(text, "договор"):
-		return "Договор"
-	case strings.Contains(text, "приказ"):
-		return "Приказ"
-	case strings.Contains(text, "постановление"):
-		return "Постановление"
-	case strings.Contains(text, "закон"):
-		return "Закон"
-	case strings.Contains(text, "решение"):
-		return "Решение"
-	default:
-		return "Неизвестно"
+// DeleteAnalysis удаляет анализ пользователя из истории и его документы из векторного хранилища
+func DeleteAnalysis(userID, analysisID string) error {
+	ids, err := repositories.DeleteAnalysis(userID, analysisID)
+	if err != nil {
+		return err
+	}
+
+	hexIDs := make([]string, len(ids))
+	for i, id := range ids {
+		hexIDs[i] = id.Hex()
+	}
+	if err := forgetAnalyses(userID, hexIDs); err != nil {
+		utils.LogWarning(fmt.Sprintf("Ошибка удаления документов из векторного хранилища: %v", err))
 	}
+	return nil
+}
+
+// activeJob — выполняемая задача анализа и отмена её контекста
+type activeJob struct {
+	userID string
+	cancel context.CancelFunc
 }
 
 var (
 	userCache      = make(map[string]string)
-	activeAnalysis = make(map[string]context.CancelFunc)
+	activeAnalysis = make(map[string]activeJob) // по ID задачи
 	cacheMutex     sync.Mutex
 )
 
-func CancelUserAnalysis(userID string) error {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
+// CancelUserAnalysis останавливает задачу анализа пользователя вместе с её запросами к LLM.
+// Без ID задачи останавливаются все текущие анализы пользователя.
+// Задача, которая уже сохраняет результат, не отменяется.
+func CancelUserAnalysis(userID, jobID string) error {
+	if jobID == "" {
+		return cancelUserJobs(userID)
+	}
+
+	job, err := repositories.GetJob(userID, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status.Finished() {
+		return ErrAnalysisFinished
+	}
+
+	if len(cancelActive(userID, jobID)) == 0 {
+		return ErrAnalysisFinished
+	}
+	return repositories.UpdateJob(job.ID, models.JobCancelled, nil)
+}
 
-	if cancel, exists := activeAnalysis[userID]; exists {
-		cancel()
-		delete(activeAnalysis, userID)
-		delete(userCache, userID)
-		return nil
+// cancelUserJobs останавливает все выполняемые задачи пользователя
+func cancelUserJobs(userID string) error {
+	jobIDs := cancelActive(userID, "")
+	if len(jobIDs) == 0 {
+		return ErrAnalysisFinished
+	}
+	for _, jobID := range jobIDs {
+		if id, err := primitive.ObjectIDFromHex(jobID); err == nil {
+			_ = repositories.UpdateJob(id, models.JobCancelled, nil)
+		}
 	}
-	return fmt.Errorf("анализ не найден или уже завершен")
+	utils.LogInfo(fmt.Sprintf("Отменено задач пользователя: %d", len(jobIDs)))
+	return nil
 }
 
 func ClearUserCache(userID string) error {
@@ -295,19 +612,53 @@ func GetCachedFile(userID string) (string, bool) {
 	return content, exists
 }
 
-func StartAnalysis(ctx context.Context, userID string, fn func()) context.Context {
+// cancelActive отменяет контексты выполняемых задач пользователя: одной задачи
+// или, если jobID пуст, всех. Возвращает ID отменённых задач.
+func cancelActive(userID, jobID string) []string {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	var cancelled []string
+	for id, active := range activeAnalysis {
+		if active.userID == userID && (jobID == "" || id == jobID) {
+			active.cancel()
+			delete(activeAnalysis, id)
+			cancelled = append(cancelled, id)
+		}
+	}
+	delete(userCache, userID)
+	return cancelled
+}
+
+// StartAnalysis запускает fn в фоне с контекстом, который отменяет CancelUserAnalysis
+func StartAnalysis(ctx context.Context, userID, jobID string, fn func(ctx context.Context)) context.Context {
 	cacheMutex.Lock()
 	defer cacheMutex.Unlock()
 
 	ctx, cancel := context.WithCancel(ctx)
-	activeAnalysis[userID] = cancel
+	activeAnalysis[jobID] = activeJob{userID: userID, cancel: cancel}
 
 	go func() {
-		fn()
+		defer cancel()
+		fn(ctx)
 		cacheMutex.Lock()
-		delete(activeAnalysis, userID)
+		delete(activeAnalysis, jobID)
 		cacheMutex.Unlock()
 	}()
 
 	return ctx
-}
\ No newline at end of file
+}
+
+// claimAnalysis снимает задачу с учёта отменяемых перед сохранением результата,
+// чтобы отмена не пришла, когда анализ уже попал в историю.
+// false — задачу уже отменили, и результат сохранять нельзя.
+func claimAnalysis(ctx context.Context, jobID string) bool {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	delete(activeAnalysis, jobID)
+	return true
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/llm"
+	"legally/models"
+	"legally/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testContract = `ДОГОВОР АРЕНДЫ НЕЖИЛОГО ПОМЕЩЕНИЯ № 15
+г. Алматы
+
+1. ПРЕДМЕТ ДОГОВОРА
+1.1. Арендодатель передаёт, а арендатор принимает во временное пользование нежилое помещение площадью 120 кв. м.
+1.2. Помещение используется арендатором для размещения офиса и не может быть передано в субаренду.
+
+2. АРЕНДНАЯ ПЛАТА
+2.1. Арендная плата составляет 350 000 тенге в месяц и вносится не позднее пятого числа месяца.
+2.2. Арендодатель вправе в одностороннем порядке изменить размер арендной платы в разумный срок.
+
+3. ОТВЕТСТВЕННОСТЬ СТОРОН
+3.1. За просрочку внесения арендной платы арендатор уплачивает неустойку в размере 1% за каждый день просрочки.`
+
+// errReduceUnavailable — фейковая модель не сводит результаты, и они сливаются без неё
+var errReduceUnavailable = errors.New("сведение недоступно")
+
+// fakeAnalysisLLM отвечает на запрос анализа части одним риском с уникальным названием;
+// части, в которых встречается failOn, завершаются ошибкой
+func fakeAnalysisLLM(t *testing.T, failOn string) {
+	t.Helper()
+	var n int32
+	llm.SetProvider(&llm.Fake{Respond: func(req llm.Request) (string, error) {
+		prompt := req.Messages[len(req.Messages)-1].Content
+		switch {
+		case strings.Contains(prompt, "Результаты частей:"):
+			return "", errReduceUnavailable
+		case failOn != "" && strings.Contains(prompt, failOn):
+			return "", errors.New("модель недоступна")
+		}
+		return fmt.Sprintf(`{"risks": [{"title": "Риск %d", "description": "Описание риска", "level": "medium", "legal_reference": "ГК РК", "recommendation": "Исправить"}], "conclusion": "Итог части"}`,
+			atomic.AddInt32(&n, 1)), nil
+	}})
+	t.Cleanup(func() { llm.SetProvider(nil) })
+}
+
+// splitIntoParts настраивает разбиение так, чтобы тестовый договор делился на несколько частей
+func splitIntoParts(t *testing.T) {
+	t.Setenv("SPLIT_MAX_TOKENS", "80")
+	t.Setenv("SPLIT_OVERLAP_TOKENS", "0")
+	t.Setenv("RAG_TOP_ARTICLES", "0")
+}
+
+func TestAnalyzeTextParts(t *testing.T) {
+	splitIntoParts(t)
+	fakeAnalysisLLM(t, "")
+
+	analysis, err := AnalyzeText(context.Background(), &utils.UploadedDocument{Filename: "lease.txt", Text: testContract})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := len(utils.SplitDocument(testContract, utils.DefaultSplitOptions()))
+	if parts < 3 {
+		t.Fatalf("test contract split into %d parts, want at least 3", parts)
+	}
+	if len(analysis.Parts) != parts || analysis.Partial {
+		t.Fatalf("parts = %+v, partial = %v", analysis.Parts, analysis.Partial)
+	}
+	for i, p := range analysis.Parts {
+		if p.Index != i+1 || p.Status != models.PartStatusDone {
+			t.Errorf("part %d status = %+v", i+1, p)
+		}
+	}
+	// Риски всех частей сохраняются после сведения без модели
+	if len(analysis.Result.Risks) != parts {
+		t.Errorf("merged %d risks, want %d", len(analysis.Result.Risks), parts)
+	}
+	if analysis.DocType != "Договор аренды" || analysis.Language != utils.LangRussian {
+		t.Errorf("doc type = %q, language = %q", analysis.DocType, analysis.Language)
+	}
+	if analysis.Usage == nil || analysis.Usage.TotalTokens == 0 || analysis.Usage.Models[0].Requests < parts {
+		t.Errorf("usage = %+v", analysis.Usage)
+	}
+}
+
+func TestAnalyzeTextPartialFailure(t *testing.T) {
+	splitIntoParts(t)
+	fakeAnalysisLLM(t, "неустойку")
+
+	analysis, err := AnalyzeText(context.Background(), &utils.UploadedDocument{Filename: "lease.txt", Text: testContract})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Partial {
+		t.Error("analysis with a failed part is not marked partial")
+	}
+
+	failed, done := 0, 0
+	for _, p := range analysis.Parts {
+		switch p.Status {
+		case models.PartStatusFailed:
+			failed++
+			if !strings.Contains(p.Error, "модель недоступна") {
+				t.Errorf("failed part error = %q", p.Error)
+			}
+		case models.PartStatusDone:
+			done++
+		}
+	}
+	if failed == 0 || done == 0 || len(analysis.Result.Risks) != done {
+		t.Errorf("%d parts failed, %d done, %d risks", failed, done, len(analysis.Result.Risks))
+	}
+
+	// Если не удалась ни одна часть, анализ завершается ошибкой: схема есть в каждом запросе
+	fakeAnalysisLLM(t, analysisSchema)
+	if _, err := AnalyzeText(context.Background(), &utils.UploadedDocument{Filename: "lease.txt", Text: testContract}); err == nil {
+		t.Error("AnalyzeText() without a single analyzed part should fail")
+	}
+}
+
+func TestRunPool(t *testing.T) {
+	t.Setenv("ANALYSIS_WORKERS", "2")
+
+	var running, peak int32
+	var mu sync.Mutex
+	var done []int
+	runPool(7, func(i int) {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		done = append(done, i)
+		mu.Unlock()
+	})
+
+	if peak > 2 {
+		t.Errorf("%d parts ran at once, want at most 2", peak)
+	}
+	if len(done) != 7 {
+		t.Errorf("ran %d of 7 parts", len(done))
+	}
+
+	// Пул без заданий не блокируется
+	runPool(0, func(int) { t.Error("fn called for an empty pool") })
+}
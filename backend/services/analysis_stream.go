@@ -0,0 +1,79 @@
+// analysis_stream.go
+
+package services
+
+import (
+	"fmt"
+	"legally/models"
+	"legally/utils"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamAnalyzeDocument анализирует загруженный документ, передавая ход анализа через emit.
+// События: start, part_start, token, part_end, consolidating, result.
+// Ошибка возвращается как до начала потока (файл не принят), так и после — тогда
+// вызывающий сам решает, как сообщить о ней клиенту.
+func StreamAnalyzeDocument(c *gin.Context, emit func(event string, data interface{})) *HttpError {
+	utils.LogAction("Получен запрос на потоковый анализ документа")
+
+	doc, err := utils.ProcessUploadedFile(c)
+	if err != nil {
+		utils.LogError(err.Error())
+		return uploadHttpError(err)
+	}
+	if doc.IsBundle() {
+		return &HttpError{Status: http.StatusBadRequest, Message: "архивы анализируются через /api/analyze", Code: "BUNDLE_NOT_SUPPORTED"}
+	}
+
+	userID, _ := c.Get("userId")
+	ctx := withResponseLanguage(c.Request.Context(), requestedLanguage(c, userID.(string)))
+	ctx = withDocumentType(ctx, requestedDocumentType(c))
+	if !forceReanalysis(c) {
+		if existing := findDuplicate(ctx, userID.(string), doc); existing != nil {
+			emit("result", duplicateResponse(existing))
+			return nil
+		}
+	}
+
+	// emit пишет в один http-ответ, а события приходят из воркеров
+	var mu sync.Mutex
+	send := func(event string, data interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(event, data)
+	}
+
+	ctx, meter := withUsageMeter(ctx)
+	defer recordUserUsage(userID.(string), meter)
+
+	analysis, err := AnalyzeTextStream(ctx, doc, &AnalysisObserver{
+		OnStart: func(parts []utils.DocumentPart) {
+			send("start", gin.H{"filename": doc.Filename, "parts": len(parts)})
+		},
+		OnPartStart: func(part utils.DocumentPart) {
+			send("part_start", gin.H{"part": part.Index + 1, "sections": part.Sections})
+		},
+		OnToken: func(part int, delta string) {
+			send("token", gin.H{"part": part, "delta": delta})
+		},
+		OnPartEnd: func(status models.PartStatus) {
+			send("part_end", status)
+		},
+		OnConsolidate: func() {
+			send("consolidating", gin.H{})
+		},
+	})
+	if err != nil {
+		utils.LogError(err.Error())
+		return analysisHttpError(err)
+	}
+
+	_, _ = storeAnalysis(userID.(string), doc, analysis)
+
+	utils.LogSuccess(fmt.Sprintf("Потоковый анализ завершён, тип документа: %s", analysis.DocType))
+	send("result", analysisResponse(doc.Filename, analysis))
+	return nil
+}
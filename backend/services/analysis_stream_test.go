@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"legally/models"
+	"legally/utils"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAnalyzeTextStreamObserver(t *testing.T) {
+	splitIntoParts(t)
+	fakeAnalysisLLM(t, "")
+
+	var mu sync.Mutex
+	var events []string
+	tokens := map[int]*strings.Builder{}
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	analysis, err := AnalyzeTextStream(context.Background(), &utils.UploadedDocument{Filename: "lease.txt", Text: testContract}, &AnalysisObserver{
+		OnStart:     func(parts []utils.DocumentPart) { record("start") },
+		OnPartStart: func(part utils.DocumentPart) { record("part_start") },
+		OnToken: func(part int, delta string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if tokens[part] == nil {
+				tokens[part] = &strings.Builder{}
+			}
+			tokens[part].WriteString(delta)
+		},
+		OnPartEnd: func(status models.PartStatus) {
+			if status.Status != models.PartStatusDone {
+				t.Errorf("part %d ended with %+v", status.Index, status)
+			}
+			record("part_end")
+		},
+		OnConsolidate: func() { record("consolidating") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := len(analysis.Parts)
+	if events[0] != "start" || events[len(events)-1] != "consolidating" {
+		t.Errorf("events = %v, want start first and consolidating last", events)
+	}
+	if n := strings.Count(strings.Join(events, " "), "part_start"); n != parts {
+		t.Errorf("%d part_start events for %d parts", n, parts)
+	}
+	if n := strings.Count(strings.Join(events, " "), "part_end"); n != parts {
+		t.Errorf("%d part_end events for %d parts", n, parts)
+	}
+
+	// Фрагменты каждой части складываются в полный ответ модели
+	if len(tokens) != parts {
+		t.Fatalf("tokens came for %d parts, want %d", len(tokens), parts)
+	}
+	for part, b := range tokens {
+		if !strings.HasPrefix(b.String(), `{"risks": [{"title": "Риск `) || !strings.HasSuffix(b.String(), `"Итог части"}`) {
+			t.Errorf("part %d streamed %q", part, b.String())
+		}
+	}
+}
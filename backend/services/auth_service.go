@@ -7,6 +7,7 @@ import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 	"legally/db"
 	"legally/models"
@@ -19,6 +20,7 @@ var (
 	ErrInvalidCredentials = errors.New("неверные учетные данные")
 	ErrUserNotFound       = errors.New("пользователь не найден")
 	ErrTokenGeneration    = errors.New("ошибка генерации токена")
+	ErrUnsupportedLang    = errors.New("неподдерживаемый язык")
 )
 
 // Register регистрирует нового пользователя и возвращает пару токенов
@@ -151,3 +153,76 @@ func ValidateUser(userID string) (*models.User, error) {
 
 	return &user, nil
 }
+
+// SetUserLanguage сохраняет язык отчётов пользователя; пустая строка сбрасывает выбор
+func SetUserLanguage(userID, language string) error {
+	if language != "" && !utils.IsSupportedLanguage(language) {
+		return ErrUnsupportedLang
+	}
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"language": language, "updatedAt": time.Now()}}
+	if language == "" {
+		update = bson.M{"$unset": bson.M{"language": ""}, "$set": bson.M{"updatedAt": time.Now()}}
+	}
+
+	res, err := db.GetCollection("users").UpdateOne(context.Background(), bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetUserOrganization привязывает пользователя к организации; пустая строка отвязывает
+func SetUserOrganization(userID, organizationID string) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	update := bson.M{"$set": bson.M{"organization_id": organizationID, "updatedAt": time.Now()}}
+	if organizationID == "" {
+		update = bson.M{"$unset": bson.M{"organization_id": ""}, "$set": bson.M{"updatedAt": time.Now()}}
+	}
+
+	res, err := db.GetCollection("users").UpdateOne(context.Background(), bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// organizationMembers возвращает ID всех пользователей организации
+func organizationMembers(organizationID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := db.GetCollection("users").Find(ctx,
+		bson.M{"organization_id": organizationID},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID.Hex()
+	}
+	return ids, nil
+}
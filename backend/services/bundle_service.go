@@ -0,0 +1,245 @@
+// bundle_service.go
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"legally/models"
+	"legally/repositories"
+	"legally/utils"
+)
+
+// Тип записи истории, объединяющей документы одного архива
+const bundleDocType = "Пакет документов"
+
+// BundleAnalysis — итог анализа всех документов архива
+type BundleAnalysis struct {
+	Files   []*BundleFileAnalysis
+	Partial bool
+	Usage   *models.UsageSummary
+}
+
+// BundleFileAnalysis — итог анализа одного документа архива.
+// Existing заполнен, если документ уже анализировался и анализ взят готовым.
+type BundleFileAnalysis struct {
+	Doc      *utils.UploadedDocument
+	Status   string
+	Error    string
+	Code     string
+	Analysis *TextAnalysis
+	Existing *models.Analysis
+}
+
+// analyzeBundle анализирует документы архива по очереди: каждый документ
+// и так анализируется частями параллельно. Ошибка возвращается, только если
+// не удалось проанализировать ни один документ или анализ отменён.
+// Уже анализировавшиеся документы берутся готовыми, если не задан force.
+func analyzeBundle(ctx context.Context, userID string, doc *utils.UploadedDocument, force bool) (*BundleAnalysis, error) {
+	ctx, meter := withUsageMeter(ctx)
+	bundle := &BundleAnalysis{}
+
+	var firstErr error
+	done := 0
+	for i, file := range doc.Files {
+		item := &BundleFileAnalysis{Doc: file}
+		bundle.Files = append(bundle.Files, item)
+
+		if file.Err != nil {
+			item.Status = models.BundleFileFailed
+			if errors.Is(file.Err, utils.ErrUnsupportedFile) {
+				item.Status = models.BundleFileSkipped
+			}
+			item.Error = file.Err.Error()
+			item.Code = uploadHttpError(file.Err).Code
+			continue
+		}
+
+		if !force {
+			if existing := findDuplicate(ctx, userID, file); existing != nil {
+				item.Status = models.BundleFileDone
+				item.Analysis = textAnalysisFromRecord(existing)
+				item.Existing = existing
+				done++
+				continue
+			}
+		}
+
+		utils.LogAction(fmt.Sprintf("Анализ документа архива %d/%d: %s", i+1, len(doc.Files), file.Filename))
+		fileCtx, _ := withChildUsageMeter(ctx)
+		analysis, err := AnalyzeText(fileCtx, file)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			utils.LogWarning(fmt.Sprintf("Документ %s не проанализирован: %v", file.Filename, err))
+			item.Status = models.BundleFileFailed
+			item.Error = err.Error()
+			item.Code = analysisHttpError(err).Code
+			if firstErr == nil {
+				firstErr = fmt.Errorf("при анализе файла %s: %w", file.Filename, err)
+			}
+			continue
+		}
+
+		item.Status = models.BundleFileDone
+		item.Analysis = analysis
+		done++
+	}
+
+	if done == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("в архиве нет документов для анализа")
+		}
+		return nil, firstErr
+	}
+
+	bundle.Partial = done < len(doc.Files)
+	bundle.Usage = meter.Summary()
+	return bundle, nil
+}
+
+// storeBundle сохраняет проанализированные документы архива и общую запись,
+// которая показывается в истории вместо отдельных документов
+func storeBundle(userID string, doc *utils.UploadedDocument, bundle *BundleAnalysis) (*models.Analysis, error) {
+	bundleID := primitive.NewObjectID()
+
+	files := make([]models.BundleFile, 0, len(bundle.Files))
+	for _, item := range bundle.Files {
+		file := models.BundleFile{
+			Filename: item.Doc.Filename,
+			Status:   item.Status,
+			Error:    item.Error,
+		}
+		if item.Existing != nil {
+			file.DocType = item.Existing.Type
+			file.AnalysisID = &item.Existing.ID
+		} else if item.Analysis != nil {
+			file.DocType = item.Analysis.DocType
+
+			record := newAnalysisRecord(item.Doc, item.Analysis)
+			record.BundleID = &bundleID
+			if _, err := saveAnalysisRecord(userID, record); err == nil {
+				file.AnalysisID = &record.ID
+			}
+		}
+		files = append(files, file)
+	}
+
+	record := &models.Analysis{
+		ID:       bundleID,
+		Filename: doc.Filename,
+		Type:     bundleDocType,
+		Analysis: bundleMarkdown(bundle),
+		Files:    files,
+		Usage:    bundle.Usage,
+	}
+	if err := repositories.SaveAnalysis(userID, record); err != nil {
+		utils.LogWarning(fmt.Sprintf("Ошибка сохранения архива в MongoDB: %v", err))
+		return record, err
+	}
+	return record, nil
+}
+
+// bundleMarkdown собирает отчёты по документам архива в один
+func bundleMarkdown(bundle *BundleAnalysis) string {
+	var b strings.Builder
+	for i, item := range bundle.Files {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", item.Doc.Filename)
+		switch item.Status {
+		case models.BundleFileDone:
+			fmt.Fprintf(&b, "Тип документа: %s\n\n", item.Analysis.DocType)
+			b.WriteString(item.Analysis.Markdown)
+		case models.BundleFileSkipped:
+			fmt.Fprintf(&b, "Документ пропущен: %s\n", item.Error)
+		default:
+			fmt.Fprintf(&b, "Документ не проанализирован: %s\n", item.Error)
+		}
+	}
+	return b.String()
+}
+
+// storedBundleResponse — ответ по сохранённой записи архива. Документы
+// с анализом отдаются целиком (находки, ссылки на нормы и их проверка),
+// как в ответе только что выполненного анализа архива.
+func storedBundleResponse(userID string, record *models.Analysis) gin.H {
+	files := make([]gin.H, 0, len(record.Files))
+	partial := false
+	for _, f := range record.Files {
+		file := gin.H{"document_type": f.DocType}
+		if f.AnalysisID != nil {
+			analysis, err := repositories.GetAnalysis(userID, f.AnalysisID.Hex())
+			if err == nil {
+				file = storedAnalysisResponse(analysis)
+			} else {
+				utils.LogWarning(fmt.Sprintf("Анализ документа архива %s не найден: %v", f.AnalysisID.Hex(), err))
+				file["analysis_id"] = f.AnalysisID.Hex()
+			}
+		}
+		file["filename"] = f.Filename
+		file["status"] = f.Status
+		if f.Error != "" {
+			file["error"] = f.Error
+		}
+		if f.Status != models.BundleFileDone {
+			partial = true
+		}
+		files = append(files, file)
+	}
+
+	return gin.H{
+		"bundle":        true,
+		"files":         files,
+		"analysis":      record.Analysis,
+		"partial":       partial,
+		"usage":         record.Usage,
+		"timestamp":     record.CreatedAt.Format(time.RFC3339),
+		"document_type": bundleDocType,
+		"filename":      record.Filename,
+		"analysis_id":   record.ID.Hex(),
+	}
+}
+
+func bundleResponse(filename string, bundle *BundleAnalysis) gin.H {
+	files := make([]gin.H, 0, len(bundle.Files))
+	for _, item := range bundle.Files {
+		file := gin.H{
+			"filename": item.Doc.Filename,
+			"status":   item.Status,
+		}
+		switch {
+		case item.Existing != nil:
+			file = duplicateResponse(item.Existing)
+			file["filename"] = item.Doc.Filename
+			file["status"] = item.Status
+		case item.Analysis != nil:
+			file = analysisResponse(item.Doc.Filename, item.Analysis)
+			file["status"] = item.Status
+		default:
+			file["error"] = item.Error
+			file["code"] = item.Code
+		}
+		files = append(files, file)
+	}
+
+	return gin.H{
+		"bundle":        true,
+		"files":         files,
+		"analysis":      bundleMarkdown(bundle),
+		"partial":       bundle.Partial,
+		"usage":         bundle.Usage,
+		"timestamp":     time.Now().Format(time.RFC3339),
+		"document_type": bundleDocType,
+		"filename":      filename,
+	}
+}
@@ -0,0 +1,77 @@
+// citations.go
+
+package services
+
+import (
+	"fmt"
+	"legally/laws"
+	"legally/models"
+	"legally/utils"
+	"strings"
+)
+
+// verifyCitations находит в анализе ссылки на нормы и сверяет их с текстами
+// кодексов. Ссылка без названия акта учитывается только в поле legal_reference:
+// в описаниях так обычно ссылаются на пункты самого договора.
+func verifyCitations(result *models.AnalysisResult, supplied []models.LegalSource) []models.Citation {
+	statutes := laws.DefaultStatutes()
+
+	suppliedIDs := map[string]bool{}
+	for _, s := range supplied {
+		suppliedIDs[s.ID] = true
+	}
+
+	var citations []models.Citation
+	index := map[string]int{}
+	result.WalkText(func(path, text string) {
+		for _, ref := range laws.ExtractReferences(text) {
+			if ref.Act == "" && !strings.HasSuffix(path, ".legal_reference") {
+				continue
+			}
+
+			act := ref.Code
+			if act == "" {
+				act = strings.ToLower(ref.Act)
+			}
+			key := strings.Join([]string{act, ref.Article, ref.Point, strings.ToLower(ref.Title)}, "|")
+			if i, ok := index[key]; ok {
+				if fields := citations[i].Fields; fields[len(fields)-1] != path {
+					citations[i].Fields = append(fields, path)
+				}
+				continue
+			}
+
+			v := statutes.Verify(ref)
+			c := models.Citation{
+				Reference: ref.Text,
+				Act:       ref.Act,
+				Code:      ref.Code,
+				Article:   ref.Article,
+				Point:     ref.Point,
+				Status:    v.Status,
+				Reason:    v.Reason,
+				Supplied:  suppliedIDs[ref.Code+"/"+ref.Article],
+				Fields:    []string{path},
+			}
+			if v.Article != nil {
+				c.ArticleTitle = v.Article.Title
+				c.Text = v.Article.Text
+				if v.Point != nil {
+					c.Text = v.Point.Text
+				}
+			}
+			index[key] = len(citations)
+			citations = append(citations, c)
+		}
+	})
+
+	if len(citations) > 0 {
+		counts := map[string]int{}
+		for _, c := range citations {
+			counts[c.Status]++
+		}
+		utils.LogInfo(fmt.Sprintf("Проверено ссылок на нормы: %d (подтверждено %d, не найдено в корпусе %d, не совпадает %d)",
+			len(citations), counts[models.CitationVerified], counts[models.CitationUnknown], counts[models.CitationMismatched]))
+	}
+	return citations
+}
@@ -0,0 +1,254 @@
+// consolidation.go
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"legally/models"
+	"legally/prompts"
+	"legally/utils"
+	"strings"
+	"unicode"
+)
+
+const defaultReduceBatchSize = 4
+
+// consolidateResults сводит результаты частей в один документный результат.
+// Результаты объединяются пачками по REDUCE_BATCH_SIZE, затем пачки пачек и т.д.,
+// поэтому длина документа не ограничена контекстом модели.
+func consolidateResults(ctx context.Context, results []*models.AnalysisResult, tpl *analysisPrompts) *models.AnalysisResult {
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	batchSize := utils.GetEnvInt("REDUCE_BATCH_SIZE", defaultReduceBatchSize)
+	if batchSize < 2 {
+		batchSize = 2
+	}
+
+	level := 1
+	for len(results) > 1 {
+		var batches [][]*models.AnalysisResult
+		for start := 0; start < len(results); start += batchSize {
+			end := start + batchSize
+			if end > len(results) {
+				end = len(results)
+			}
+			batches = append(batches, results[start:end])
+		}
+
+		utils.LogAction(fmt.Sprintf("Сведение результатов, уровень %d: %d → %d", level, len(results), len(batches)))
+
+		reduced := make([]*models.AnalysisResult, len(batches))
+		runPool(len(batches), func(i int) {
+			reduced[i] = reduceBatch(ctx, batches[i], tpl)
+		})
+
+		results = reduced
+		level++
+	}
+
+	return results[0]
+}
+
+// reduceBatch просит модель объединить пачку результатов; при ошибке
+// используется детерминированное слияние
+func reduceBatch(ctx context.Context, batch []*models.AnalysisResult, tpl *analysisPrompts) *models.AnalysisResult {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+
+	merged := mergeResults(batch, tpl.response)
+
+	input, err := json.Marshal(batch)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Не удалось подготовить результаты к сведению: %v", err))
+		return merged
+	}
+
+	prompt, err := tpl.reduce.Render(prompts.Vars{
+		"Schema":           analysisSchema,
+		"Results":          string(input),
+		"ResponseLanguage": tpl.responseLanguage(tpl.reduce),
+	})
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Не удалось подготовить промпт сведения: %v", err))
+		return merged
+	}
+
+	raw, err := queryLLM(ctx, prompt)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Сведение через AI не удалось, используем детерминированное: %v", err))
+		return merged
+	}
+
+	result, _, err := parseAnalysis(raw)
+	if err != nil || result.IsEmpty() {
+		utils.LogWarning(fmt.Sprintf("Ответ AI при сведении не разобран, используем детерминированное: %v", err))
+		return merged
+	}
+
+	return result
+}
+
+// mergeResults объединяет результаты без модели: дубликаты схлопываются,
+// из конфликтующих уровней берётся наивысший, а заключение собирается по шаблону
+// на языке отчёта lang из числа находок и наивысшего уровня
+func mergeResults(results []*models.AnalysisResult, lang string) *models.AnalysisResult {
+	merged := &models.AnalysisResult{}
+	risks := map[string]int{}
+	ambiguities := map[string]int{}
+	violations := map[string]int{}
+	recommendations := map[string]int{}
+
+	for _, r := range results {
+		for _, it := range r.Risks {
+			key := findingKey(it.Title + " " + it.Description)
+			if i, ok := risks[key]; ok {
+				ex := &merged.Risks[i]
+				ex.Level = maxLevel(ex.Level, it.Level)
+				ex.LegalReference = mergeReferences(ex.LegalReference, it.LegalReference)
+				ex.Recommendation = longer(ex.Recommendation, it.Recommendation)
+				continue
+			}
+			risks[key] = len(merged.Risks)
+			merged.Risks = append(merged.Risks, it)
+		}
+		for _, it := range r.Ambiguities {
+			key := findingKey(it.Wording)
+			if i, ok := ambiguities[key]; ok {
+				ex := &merged.Ambiguities[i]
+				ex.Level = maxLevel(ex.Level, it.Level)
+				ex.LegalReference = mergeReferences(ex.LegalReference, it.LegalReference)
+				ex.Recommendation = longer(ex.Recommendation, it.Recommendation)
+				continue
+			}
+			ambiguities[key] = len(merged.Ambiguities)
+			merged.Ambiguities = append(merged.Ambiguities, it)
+		}
+		for _, it := range r.Violations {
+			key := findingKey(it.Description)
+			if i, ok := violations[key]; ok {
+				ex := &merged.Violations[i]
+				ex.Level = maxLevel(ex.Level, it.Level)
+				ex.LegalReference = mergeReferences(ex.LegalReference, it.LegalReference)
+				ex.Consequences = longer(ex.Consequences, it.Consequences)
+				ex.Recommendation = longer(ex.Recommendation, it.Recommendation)
+				continue
+			}
+			violations[key] = len(merged.Violations)
+			merged.Violations = append(merged.Violations, it)
+		}
+		for _, it := range r.Recommendations {
+			key := findingKey(it.Text)
+			if i, ok := recommendations[key]; ok {
+				ex := &merged.Recommendations[i]
+				ex.Level = maxLevel(ex.Level, it.Level)
+				ex.LegalReference = mergeReferences(ex.LegalReference, it.LegalReference)
+				continue
+			}
+			recommendations[key] = len(merged.Recommendations)
+			merged.Recommendations = append(merged.Recommendations, it)
+		}
+	}
+
+	merged.Conclusion = mergedConclusion(merged, lang)
+	return merged
+}
+
+// mergedConclusions — шаблоны заключения, сведённого без модели
+var mergedConclusions = map[string]struct{ summary, highest, clean string }{
+	utils.LangRussian: {
+		summary: "Документ проанализирован по частям и сведён без AI. Выявлено рисков: %d, неясных формулировок: %d, возможных нарушений: %d, рекомендаций: %d.",
+		highest: " Наивысший уровень риска — %s.",
+		clean:   "Документ проанализирован по частям и сведён без AI. Рисков, неясных формулировок и нарушений не выявлено.",
+	},
+	utils.LangKazakh: {
+		summary: "Құжат бөліктер бойынша талданып, AI-сыз біріктірілді. Анықталған тәуекелдер: %d, түсініксіз тұжырымдар: %d, ықтимал бұзушылықтар: %d, ұсынымдар: %d.",
+		highest: " Ең жоғары тәуекел деңгейі — %s.",
+		clean:   "Құжат бөліктер бойынша талданып, AI-сыз біріктірілді. Тәуекелдер, түсініксіз тұжырымдар және бұзушылықтар анықталған жоқ.",
+	},
+	utils.LangEnglish: {
+		summary: "The document was analyzed in parts and consolidated without AI. Found %d risks, %d ambiguities, %d possible violations and %d recommendations.",
+		highest: " The highest risk level is %s.",
+		clean:   "The document was analyzed in parts and consolidated without AI. No risks, ambiguities or violations were found.",
+	},
+}
+
+// mergedConclusion составляет одно заключение по объединённым находкам
+func mergedConclusion(r *models.AnalysisResult, lang string) string {
+	tpl, ok := mergedConclusions[lang]
+	if !ok {
+		tpl = mergedConclusions[utils.LangRussian]
+	}
+	if len(r.Risks)+len(r.Ambiguities)+len(r.Violations) == 0 {
+		return tpl.clean
+	}
+
+	var highest models.RiskLevel
+	for _, it := range r.Risks {
+		highest = maxLevel(highest, it.Level)
+	}
+	for _, it := range r.Ambiguities {
+		highest = maxLevel(highest, it.Level)
+	}
+	for _, it := range r.Violations {
+		highest = maxLevel(highest, it.Level)
+	}
+
+	conclusion := fmt.Sprintf(tpl.summary, len(r.Risks), len(r.Ambiguities), len(r.Violations), len(r.Recommendations))
+	if highest.Valid() {
+		conclusion += fmt.Sprintf(tpl.highest, highest.LabelIn(lang))
+	}
+	return conclusion
+}
+
+// findingKey нормализует текст находки для поиска дубликатов
+func findingKey(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 12 {
+		words = words[:12]
+	}
+	return strings.ReplaceAll(strings.Join(words, " "), "ё", "е")
+}
+
+func levelRank(l models.RiskLevel) int {
+	switch l {
+	case models.LevelHigh:
+		return 3
+	case models.LevelMedium:
+		return 2
+	case models.LevelLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func maxLevel(a, b models.RiskLevel) models.RiskLevel {
+	if levelRank(b) > levelRank(a) {
+		return b
+	}
+	return a
+}
+
+func mergeReferences(a, b string) string {
+	if b == "" || strings.Contains(a, b) {
+		return a
+	}
+	if a == "" || strings.Contains(b, a) {
+		return b
+	}
+	return a + "; " + b
+}
+
+func longer(a, b string) string {
+	if len([]rune(b)) > len([]rune(a)) {
+		return b
+	}
+	return a
+}
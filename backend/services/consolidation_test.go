@@ -0,0 +1,79 @@
+package services
+
+import (
+	"legally/models"
+	"legally/utils"
+	"strings"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	results := []*models.AnalysisResult{
+		{
+			Risks: []models.Risk{
+				{Title: "Неустойка", Description: "Завышенная неустойка", Level: models.LevelMedium, LegalReference: "ГК РК, ст. 293", Recommendation: "Снизить"},
+			},
+			Recommendations: []models.Recommendation{{Text: "Указать срок оплаты", Level: models.LevelLow}},
+			Conclusion:      "Первая часть договора в целом корректна.",
+		},
+		{
+			Risks: []models.Risk{
+				{Title: "НЕУСТОЙКА", Description: "завышенная неустойка!", Level: models.LevelHigh, LegalReference: "ГК РК, ст. 297", Recommendation: "Снизить до 0,1% в день"},
+				{Title: "Расторжение", Description: "Без уведомления", Level: models.LevelLow},
+			},
+			Violations:      []models.Violation{{Description: "Срок выше допустимого", Level: models.LevelMedium}},
+			Recommendations: []models.Recommendation{{Text: "Указать срок оплаты.", Level: models.LevelHigh}},
+			Conclusion:      "Вторая часть требует доработки.",
+		},
+	}
+
+	merged := mergeResults(results, utils.LangRussian)
+
+	if len(merged.Risks) != 2 || len(merged.Violations) != 1 || len(merged.Recommendations) != 1 {
+		t.Fatalf("merged %d risks, %d violations, %d recommendations; want 2, 1, 1",
+			len(merged.Risks), len(merged.Violations), len(merged.Recommendations))
+	}
+	risk := merged.Risks[0]
+	if risk.Level != models.LevelHigh {
+		t.Errorf("risk level = %s, want the highest", risk.Level)
+	}
+	if risk.LegalReference != "ГК РК, ст. 293; ГК РК, ст. 297" {
+		t.Errorf("legal reference = %q", risk.LegalReference)
+	}
+	if risk.Recommendation != "Снизить до 0,1% в день" {
+		t.Errorf("recommendation = %q, want the longer one", risk.Recommendation)
+	}
+	if merged.Recommendations[0].Level != models.LevelHigh {
+		t.Errorf("recommendation level = %s", merged.Recommendations[0].Level)
+	}
+
+	// Заключение одно, а не склейка заключений частей
+	want := "Документ проанализирован по частям и сведён без AI. Выявлено рисков: 2, неясных формулировок: 0, возможных нарушений: 1, рекомендаций: 1. Наивысший уровень риска — высокий."
+	if merged.Conclusion != want {
+		t.Errorf("conclusion = %q, want %q", merged.Conclusion, want)
+	}
+}
+
+func TestMergedConclusion(t *testing.T) {
+	empty := &models.AnalysisResult{}
+	found := &models.AnalysisResult{Ambiguities: []models.Ambiguity{{Wording: "в разумный срок", Level: models.LevelLow}}}
+
+	tests := []struct {
+		name   string
+		result *models.AnalysisResult
+		lang   string
+		want   string
+	}{
+		{"nothing found", empty, utils.LangRussian, "не выявлено"},
+		{"english", found, utils.LangEnglish, "The highest risk level is low."},
+		{"kazakh", found, utils.LangKazakh, "Ең жоғары тәуекел деңгейі — төмен."},
+		{"unknown language falls back to russian", found, "de", "Наивысший уровень риска — низкий."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergedConclusion(tt.result, tt.lang); !strings.Contains(got, tt.want) {
+				t.Errorf("mergedConclusion() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
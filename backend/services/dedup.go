@@ -0,0 +1,112 @@
+// dedup.go
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"legally/models"
+	"legally/repositories"
+	"legally/utils"
+)
+
+// forceReanalysis сообщает, что клиент просит проанализировать документ заново (force=true)
+func forceReanalysis(c *gin.Context) bool {
+	force := c.Query("force")
+	if force == "" {
+		force = c.PostForm("force")
+	}
+	return force == "true" || force == "1"
+}
+
+// findDuplicate возвращает прошлый анализ пользователя с тем же содержимым документа,
+// если он сделан с тем же языком отчёта и типом документа, что запрошены в ctx.
+// Ошибки поиска не мешают анализу и только логируются.
+func findDuplicate(ctx context.Context, userID string, doc *utils.UploadedDocument) *models.Analysis {
+	if doc.ContentHash == "" {
+		return nil
+	}
+
+	existing, err := repositories.FindAnalysisByHash(userID, doc.ContentHash)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrAnalysisNotFound) {
+			utils.LogWarning(fmt.Sprintf("Не удалось проверить повторную загрузку: %v", err))
+		}
+		return nil
+	}
+	if !reusableAnalysis(ctx, existing) {
+		utils.LogInfo(fmt.Sprintf("Документ %s уже анализировался (%s) с другим языком отчёта или типом, анализируем заново", doc.Filename, existing.ID.Hex()))
+		return nil
+	}
+
+	utils.LogInfo(fmt.Sprintf("Документ %s уже анализировался (%s), используем готовый анализ", doc.Filename, existing.ID.Hex()))
+	return existing
+}
+
+// reusableAnalysis сообщает, что сохранённый анализ отвечает запросу: отчёт на
+// запрошенном языке и, если пользователь указал тип документа, того же типа
+func reusableAnalysis(ctx context.Context, record *models.Analysis) bool {
+	stored := record.ResponseLanguage
+	if stored == "" {
+		stored = record.Language
+	}
+	if responseLanguage(ctx, record.Language) != stored {
+		return false
+	}
+
+	override, ok := ctx.Value(docTypeOverrideKey{}).(DocumentType)
+	if !ok {
+		return true
+	}
+	return record.Classification != nil && record.Classification.ID == override.ID
+}
+
+// textAnalysisFromRecord восстанавливает итог анализа из сохранённой записи
+func textAnalysisFromRecord(record *models.Analysis) *TextAnalysis {
+	partial := false
+	for _, p := range record.Parts {
+		if p.Status == models.PartStatusFailed {
+			partial = true
+		}
+	}
+	return &TextAnalysis{
+		Markdown:         record.Analysis,
+		Result:           record.Result,
+		DocType:          record.Type,
+		Parts:            record.Parts,
+		Partial:          partial,
+		Usage:            record.Usage,
+		Prompts:          record.Prompts,
+		Pages:            record.Pages,
+		Language:         record.Language,
+		ResponseLanguage: record.ResponseLanguage,
+		Redaction:        record.Redaction,
+		Metadata:         record.Metadata,
+		Classification:   record.Classification,
+		LegalSources:     record.LegalSources,
+		Citations:        record.Citations,
+	}
+}
+
+// storedAnalysisResponse — ответ по сохранённой записи анализа в том же виде,
+// что и ответ только что выполненного анализа
+func storedAnalysisResponse(record *models.Analysis) gin.H {
+	resp := analysisResponse(record.Filename, textAnalysisFromRecord(record))
+	resp["analysis_id"] = record.ID.Hex()
+	resp["timestamp"] = record.CreatedAt.Format(time.RFC3339)
+	return resp
+}
+
+// duplicateResponse отдаёт готовый анализ повторно загруженного документа.
+// Расход токенов нулевой: модель не вызывалась.
+func duplicateResponse(record *models.Analysis) gin.H {
+	resp := storedAnalysisResponse(record)
+	resp["duplicate"] = true
+	resp["usage"] = &models.UsageSummary{Models: []models.TokenUsage{}}
+	return resp
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"legally/models"
+	"legally/utils"
+	"testing"
+)
+
+func TestReusableAnalysis(t *testing.T) {
+	lease, _ := findDocumentType("lease_contract")
+	labor, _ := findDocumentType("employment_contract")
+
+	record := func(language, responseLanguage, typeID string) *models.Analysis {
+		return &models.Analysis{
+			Language:         language,
+			ResponseLanguage: responseLanguage,
+			Classification:   &models.Classification{DocTypeScore: models.DocTypeScore{ID: typeID}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		record   *models.Analysis
+		language string
+		docType  *DocumentType
+		want     bool
+	}{
+		{"same defaults", record(utils.LangRussian, utils.LangRussian, "lease_contract"), "", nil, true},
+		{"record without response language", record(utils.LangRussian, "", "lease_contract"), utils.LangRussian, nil, true},
+		{"other response language", record(utils.LangRussian, utils.LangRussian, "lease_contract"), utils.LangKazakh, nil, false},
+		{"document language after translated report", record(utils.LangRussian, utils.LangEnglish, "lease_contract"), "", nil, false},
+		{"requested type matches", record(utils.LangRussian, utils.LangRussian, "lease_contract"), "", &lease, true},
+		{"requested type differs", record(utils.LangRussian, utils.LangRussian, "lease_contract"), "", &labor, false},
+		{"requested type, record unclassified", &models.Analysis{Language: utils.LangRussian}, "", &lease, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withDocumentType(withResponseLanguage(context.Background(), tt.language), tt.docType)
+			if got := reusableAnalysis(ctx, tt.record); got != tt.want {
+				t.Errorf("reusableAnalysis() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicateWithoutHash(t *testing.T) {
+	// Без хэша содержимого хранилище не запрашивается
+	if got := findDuplicate(context.Background(), "u1", &utils.UploadedDocument{Filename: "a.txt"}); got != nil {
+		t.Errorf("findDuplicate() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,458 @@
+// doctype.go
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/models"
+	"legally/repositories"
+	"legally/utils"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	unknownDocType = "Неизвестно"
+
+	// Вес признака зависит от того, где он встретился
+	titleWeight   = 3.0
+	headingWeight = 1.0
+	bodyWeight    = 0.25
+	// Повторы в тексте и заголовках учитываются до предела, чтобы длинный
+	// документ не перевешивал название
+	maxBodyHits    = 8
+	maxHeadingHits = 3
+
+	titleLines     = 3
+	titleMaxRunes  = 400
+	maxRunnerUps   = 3
+	minTypeScore   = 1.0
+	confidentScore = 8.0
+)
+
+// DocumentType — тип документа из таксономии. Parent — более общий тип,
+// шаблоны промптов которого подходят, если своих у типа нет.
+type DocumentType struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Parent string `json:"parent,omitempty"`
+}
+
+// docTypeFeatures — признаки типа на одном языке: названия документа и характерные термины.
+// Structure — рубрикация («Статья 1», «Глава 2»), которая встречается и в договорах,
+// поэтому учитывается, только если название документа не указывает на другой тип.
+type docTypeFeatures struct {
+	titles    []string
+	terms     []string
+	structure []string
+}
+
+type docTypeDef struct {
+	DocumentType
+	// weight ослабляет общие типы, чтобы частный тип с тем же словом в названии побеждал
+	weight   float64
+	features map[string]docTypeFeatures
+}
+
+var docTypeTaxonomy = []docTypeDef{
+	{DocumentType{ID: "contract", Label: "Договор"}, 0.5, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор`, `контракт`, `соглашение`}, terms: []string{`стороны`, `предмет договора`, `ответственность сторон`}},
+		utils.LangKazakh:  {titles: []string{`шарт`, `келісім`}, terms: []string{`тараптар`, `шарттың мәні`}},
+		utils.LangEnglish: {titles: []string{`agreement`, `contract`}, terms: []string{`parties`, `subject of the agreement`}},
+	}},
+	{DocumentType{ID: "employment_contract", Label: "Трудовой договор", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`трудовой договор`}, terms: []string{`работник`, `работодател`, `заработн\p{L}* плат`, `трудов\p{L}* обязанност`, `испытательн\p{L}* срок`, `рабоч\p{L}* врем`}},
+		utils.LangKazakh:  {titles: []string{`еңбек шарты`}, terms: []string{`жұмыскер`, `жұмыс беруші`, `жалақы`}},
+		utils.LangEnglish: {titles: []string{`employment (?:agreement|contract)`}, terms: []string{`employee`, `employer`, `salary`, `probation`}},
+	}},
+	{DocumentType{ID: "lease_contract", Label: "Договор аренды", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+(?:\p{L}+\s+)?аренды`, `договор\s+(?:имущественного\s+)?найма`}, terms: []string{`арендатор`, `арендодател`, `арендн\p{L}* плат`, `наниматель`, `наймодатель`}},
+		utils.LangKazakh:  {titles: []string{`жалдау шарты`, `мүліктік жалдау`}, terms: []string{`жалға алушы`, `жалға беруші`, `жалдау ақы`}},
+		utils.LangEnglish: {titles: []string{`lease agreement`, `tenancy agreement`, `rental agreement`}, terms: []string{`lessor`, `lessee`, `landlord`, `tenant`, `rent`}},
+	}},
+	{DocumentType{ID: "sale_contract", Label: "Договор купли-продажи", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+купли[\s-]+продажи`}, terms: []string{`продавец`, `покупател`, `право собственности`, `в собственность`}},
+		utils.LangKazakh:  {titles: []string{`сатып алу[\s-]+сату шарты`}, terms: []string{`сатушы`, `сатып алушы`, `меншік құқығы`}},
+		utils.LangEnglish: {titles: []string{`(?:sale and )?purchase agreement`, `sales? contract`}, terms: []string{`seller`, `buyer`, `title to`}},
+	}},
+	{DocumentType{ID: "supply_contract", Label: "Договор поставки", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+поставки`}, terms: []string{`поставщик`, `поставк`, `накладн`, `парти\p{L}* товара`}},
+		utils.LangKazakh:  {titles: []string{`жеткізу шарты`}, terms: []string{`жеткізуші`, `жеткізу`}},
+		utils.LangEnglish: {titles: []string{`supply agreement`, `supply contract`}, terms: []string{`supplier`, `deliver`, `consignment`}},
+	}},
+	{DocumentType{ID: "services_contract", Label: "Договор оказания услуг", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+(?:возмездного\s+)?оказания\s+(?:\p{L}+\s+)?услуг`, `договор\s+на\s+оказание\s+(?:\p{L}+\s+)?услуг`}, terms: []string{`исполнител`, `заказчик`, `услуг`, `акт\p{L}* оказанных услуг`}},
+		utils.LangKazakh:  {titles: []string{`қызмет\p{L}* көрсету шарты`}, terms: []string{`орындаушы`, `тапсырыс беруші`, `қызмет\p{L}*`}},
+		utils.LangEnglish: {titles: []string{`services? agreement`, `service contract`}, terms: []string{`service provider`, `services`, `customer`}},
+	}},
+	{DocumentType{ID: "work_contract", Label: "Договор подряда", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+(?:\p{L}+\s+)?подряда`}, terms: []string{`подрядчик`, `заказчик`, `выполнен\p{L}* работ`, `смет`}},
+		utils.LangKazakh:  {titles: []string{`мердігерлік шарт`}, terms: []string{`мердігер`, `тапсырыс беруші`, `жұмыстар`}},
+		utils.LangEnglish: {titles: []string{`construction contract`, `works? contract`}, terms: []string{`contractor`, `works`, `completion`}},
+	}},
+	{DocumentType{ID: "loan_contract", Label: "Договор займа", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`договор\s+займа`, `кредитн\p{L}* договор`}, terms: []string{`заемщик`, `заёмщик`, `займодав`, `заимодав`, `сумм\p{L}* займа`, `кредит`}},
+		utils.LangKazakh:  {titles: []string{`қарыз шарты`, `несие шарты`}, terms: []string{`қарыз алушы`, `қарыз беруші`, `несие`}},
+		utils.LangEnglish: {titles: []string{`loan agreement`, `credit agreement`}, terms: []string{`lender`, `borrower`, `interest`}},
+	}},
+	{DocumentType{ID: "nda", Label: "Соглашение о конфиденциальности", Parent: "contract"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`соглашение\s+о\s+(?:конфиденциальности|неразглашении)`}, terms: []string{`конфиденциальн\p{L}* информаци`, `раскрывающ\p{L}* сторон`, `разглашени`}},
+		utils.LangKazakh:  {titles: []string{`құпиялылық туралы келісім`}, terms: []string{`құпия ақпарат`}},
+		utils.LangEnglish: {titles: []string{`non[\s-]disclosure agreement`, `confidentiality agreement`}, terms: []string{`confidential information`, `disclosing party`, `receiving party`}},
+	}},
+	{DocumentType{ID: "power_of_attorney", Label: "Доверенность"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`доверенность`}, terms: []string{`доверяю`, `уполномочива`, `доверител`, `поверенн`, `без права передоверия`}},
+		utils.LangKazakh:  {titles: []string{`сенімхат`}, terms: []string{`сенім білдіремін`, `өкілеттік`}},
+		utils.LangEnglish: {titles: []string{`power of attorney`}, terms: []string{`attorney[\s-]in[\s-]fact`, `hereby authori[sz]e`, `principal`}},
+	}},
+	{DocumentType{ID: "order", Label: "Приказ"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`приказ`}, terms: []string{`приказываю`, `назначить`, `ознакомить`, `контроль за исполнением`}},
+		utils.LangKazakh:  {titles: []string{`бұйрық`}, terms: []string{`бұйырамын`, `тағайындалсын`, `таныстырылсын`}},
+		utils.LangEnglish: {titles: []string{`order(?:[^\p{L}]|$)`}, terms: []string{`i hereby order`, `to appoint`}},
+	}},
+	{DocumentType{ID: "resolution", Label: "Постановление"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`постановление`}, terms: []string{`постановляет`, `постановляю`, `акимат`, `правительство`}},
+		utils.LangKazakh:  {titles: []string{`қаулы`}, terms: []string{`қаулы етеді`, `әкімдік`, `үкімет`}},
+		utils.LangEnglish: {titles: []string{`resolution`, `decree`}, terms: []string{`hereby resolves`, `government`}},
+	}},
+	{DocumentType{ID: "law", Label: "Закон"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`закон\s+республики\s+казахстан`, `кодекс\s+республики\s+казахстан`}, terms: []string{`настоящ\p{L}* закон`}, structure: []string{`статья \d+`, `глава \d+`}},
+		utils.LangKazakh:  {titles: []string{`қазақстан республикасының заңы`, `қазақстан республикасының кодексі`}, terms: []string{`осы заң`}, structure: []string{`\d+-бап`}},
+		utils.LangEnglish: {titles: []string{`law of the republic of kazakhstan`, `code of the republic of kazakhstan`}, terms: []string{`this law`}, structure: []string{`article \d+`}},
+	}},
+	{DocumentType{ID: "decision", Label: "Решение"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`решение`}, terms: []string{`решил`, `решила`, `единственн\p{L}* участник`, `общего собрания`, `именем республики казахстан`}},
+		utils.LangKazakh:  {titles: []string{`шешім`}, terms: []string{`шешті`, `жалғыз қатысушы`, `қазақстан республикасының атынан`}},
+		utils.LangEnglish: {titles: []string{`decision`, `judgment`}, terms: []string{`hereby decided`, `sole participant`, `the court`}},
+	}},
+	{DocumentType{ID: "claim", Label: "Исковое заявление"}, 1, map[string]docTypeFeatures{
+		utils.LangRussian: {titles: []string{`исковое заявление`, `иск(?:[^\p{L}]|$)`}, terms: []string{`истец`, `ответчик`, `прошу суд`, `взыскать`}},
+		utils.LangKazakh:  {titles: []string{`талап арыз`}, terms: []string{`талапкер`, `жауапкер`, `соттан сұраймын`}},
+		utils.LangEnglish: {titles: []string{`statement of claim`, `complaint`}, terms: []string{`plaintiff`, `defendant`, `claimant`}},
+	}},
+}
+
+type compiledFeatures struct {
+	titles    []*regexp.Regexp
+	terms     []*regexp.Regexp
+	structure []*regexp.Regexp
+}
+
+var (
+	compiledTaxonomy     map[string]map[string]compiledFeatures
+	compiledTaxonomyOnce sync.Once
+)
+
+// taxonomyFeatures компилирует признаки один раз; слова ищутся с границей слова по буквам
+func taxonomyFeatures() map[string]map[string]compiledFeatures {
+	compiledTaxonomyOnce.Do(func() {
+		compile := func(patterns []string) []*regexp.Regexp {
+			var res []*regexp.Regexp
+			for _, p := range patterns {
+				res = append(res, regexp.MustCompile(`(?i)(?:^|[^\p{L}])`+p))
+			}
+			return res
+		}
+		compiledTaxonomy = map[string]map[string]compiledFeatures{}
+		for _, t := range docTypeTaxonomy {
+			byLang := map[string]compiledFeatures{}
+			for lang, f := range t.features {
+				byLang[lang] = compiledFeatures{titles: compile(f.titles), terms: compile(f.terms), structure: compile(f.structure)}
+			}
+			compiledTaxonomy[t.ID] = byLang
+		}
+	})
+	return compiledTaxonomy
+}
+
+// DocumentTypes возвращает таксономию типов документов
+func DocumentTypes() []DocumentType {
+	types := make([]DocumentType, len(docTypeTaxonomy))
+	for i, t := range docTypeTaxonomy {
+		types[i] = t.DocumentType
+	}
+	return types
+}
+
+// findDocumentType ищет тип по ID или названию без учёта регистра
+func findDocumentType(value string) (DocumentType, bool) {
+	value = strings.TrimSpace(value)
+	for _, t := range docTypeTaxonomy {
+		if strings.EqualFold(t.ID, value) || strings.EqualFold(t.Label, value) {
+			return t.DocumentType, true
+		}
+	}
+	return DocumentType{}, false
+}
+
+// docTypeDescendants — названия типа и всех его подтипов («Договор» включает трудовой договор, аренду…)
+func docTypeDescendants(t DocumentType) []string {
+	labels := []string{t.Label}
+	for _, candidate := range docTypeTaxonomy {
+		for parent := candidate.Parent; parent != ""; {
+			p, ok := findDocumentType(parent)
+			if !ok {
+				break
+			}
+			if p.ID == t.ID {
+				labels = append(labels, candidate.Label)
+				break
+			}
+			parent = p.Parent
+		}
+	}
+	return labels
+}
+
+// docTypeCandidates — тип документа и его родители: по ним ищутся шаблоны промптов
+func docTypeCandidates(label string) []string {
+	candidates := []string{label}
+	t, ok := findDocumentType(label)
+	for ok && t.Parent != "" {
+		t, ok = findDocumentType(t.Parent)
+		if ok {
+			candidates = append(candidates, t.Label)
+		}
+	}
+	return candidates
+}
+
+// classifyDocument взвешивает признаки типов в названии документа, заголовках
+// разделов и тексте. Признаки языка документа дополняются русскими, так как
+// двуязычные документы обычно содержат и русский текст.
+//
+// Уверенность — доля очков лучшего типа среди всех кандидатов, уменьшенная,
+// если очков мало. Общий «Договор» уступает частному виду договора, набравшему
+// не меньше очков.
+func classifyDocument(text, language string) *models.Classification {
+	title, headings, body := documentZones(text)
+
+	languages := []string{language}
+	if language != utils.LangRussian {
+		languages = append(languages, utils.LangRussian)
+	}
+
+	features := taxonomyFeatures()
+
+	// Типы, названные в заголовке документа: рубрикация других типов при них не учитывается
+	titled := map[string]bool{}
+	for _, t := range docTypeTaxonomy {
+		for _, lang := range languages {
+			for _, re := range features[t.ID][lang].titles {
+				if re.MatchString(title) {
+					titled[t.ID] = true
+				}
+			}
+		}
+	}
+	titledOther := func(id string) bool {
+		for other := range titled {
+			if other != id {
+				return true
+			}
+		}
+		return false
+	}
+
+	scores := map[string]float64{}
+	for _, t := range docTypeTaxonomy {
+		score := 0.0
+		for _, lang := range languages {
+			f, ok := features[t.ID][lang]
+			if !ok {
+				continue
+			}
+			for _, re := range f.titles {
+				if re.MatchString(title) {
+					score += 2 * titleWeight
+				}
+				score += headingWeight * float64(countMatching(re, headings, maxHeadingHits))
+			}
+			terms := f.terms
+			if !titledOther(t.ID) {
+				terms = append(terms[:len(terms):len(terms)], f.structure...)
+			}
+			for _, re := range terms {
+				if re.MatchString(title) {
+					score += titleWeight / 2
+				}
+				score += headingWeight / 2 * float64(countMatching(re, headings, maxHeadingHits))
+				score += bodyWeight * float64(len(re.FindAllStringIndex(body, maxBodyHits)))
+			}
+		}
+		if score > 0 {
+			scores[t.ID] = score * t.weight
+		}
+	}
+
+	ranked := make([]docTypeDef, 0, len(scores))
+	total := 0.0
+	for _, t := range docTypeTaxonomy {
+		if s, ok := scores[t.ID]; ok {
+			ranked = append(ranked, t)
+			total += s
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i].ID] > scores[ranked[j].ID] })
+
+	if len(ranked) == 0 || scores[ranked[0].ID] < minTypeScore {
+		return &models.Classification{DocTypeScore: models.DocTypeScore{ID: "unknown", Label: unknownDocType}}
+	}
+
+	confidence := func(id string) float64 {
+		s := scores[id]
+		strength := math.Min(1, s/confidentScore)
+		return math.Round(s/total*strength*100) / 100
+	}
+
+	top := ranked[0]
+	result := &models.Classification{
+		DocTypeScore: models.DocTypeScore{ID: top.ID, Label: top.Label, Confidence: confidence(top.ID)},
+	}
+	for _, t := range ranked[1:] {
+		if len(result.RunnerUps) == maxRunnerUps {
+			break
+		}
+		result.RunnerUps = append(result.RunnerUps, models.DocTypeScore{ID: t.ID, Label: t.Label, Confidence: confidence(t.ID)})
+	}
+	return result
+}
+
+// documentZones делит документ на название (первые непустые строки),
+// заголовки разделов и весь текст
+func documentZones(text string) (string, []string, string) {
+	var titleParts []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		titleParts = append(titleParts, line)
+		if len(titleParts) == titleLines {
+			break
+		}
+	}
+	title := strings.Join(titleParts, "\n")
+	if runes := []rune(title); len(runes) > titleMaxRunes {
+		title = string(runes[:titleMaxRunes])
+	}
+
+	return title, utils.DocumentHeadings(text), text
+}
+
+func countMatching(re *regexp.Regexp, lines []string, limit int) int {
+	n := 0
+	for _, l := range lines {
+		if n == limit {
+			break
+		}
+		if re.MatchString(l) {
+			n++
+		}
+	}
+	return n
+}
+
+type docTypeOverrideKey struct{}
+
+// withDocumentType задаёт тип документа, выбранный пользователем вместо классификатора
+func withDocumentType(ctx context.Context, t *DocumentType) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, docTypeOverrideKey{}, *t)
+}
+
+// documentClassification определяет тип документа; тип, указанный пользователем,
+// заменяет ответ классификатора, который сохраняется в Detected
+func documentClassification(ctx context.Context, text, language string) *models.Classification {
+	detected := classifyDocument(text, language)
+	override, ok := ctx.Value(docTypeOverrideKey{}).(DocumentType)
+	if !ok {
+		return detected
+	}
+	return overrideClassification(detected, override)
+}
+
+func overrideClassification(c *models.Classification, t DocumentType) *models.Classification {
+	detected := c.DocTypeScore
+	if c.Detected != nil {
+		detected = *c.Detected
+	}
+	return &models.Classification{
+		DocTypeScore: models.DocTypeScore{ID: t.ID, Label: t.Label, Confidence: 1},
+		RunnerUps:    c.RunnerUps,
+		Overridden:   true,
+		Detected:     &detected,
+	}
+}
+
+// requestedDocumentType читает тип документа из параметра doc_type запроса (ID или название)
+func requestedDocumentType(c *gin.Context) *DocumentType {
+	value := c.Query("doc_type")
+	if value == "" {
+		value = c.PostForm("doc_type")
+	}
+	if value == "" {
+		return nil
+	}
+	t, ok := findDocumentType(value)
+	if !ok {
+		utils.LogWarning(fmt.Sprintf("Неизвестный тип документа %q, используем классификатор", value))
+		return nil
+	}
+	return &t
+}
+
+var (
+	// ErrUnknownDocType — тип документа не из таксономии
+	ErrUnknownDocType = errors.New("неизвестный тип документа")
+	// ErrBundleDocType — у записи архива нет своего типа: типы у документов внутри
+	ErrBundleDocType = errors.New("тип архива документов не меняется")
+)
+
+// SetAnalysisType меняет тип документа сохранённого анализа по выбору пользователя.
+// Ответ классификатора остаётся в Detected.
+func SetAnalysisType(userID, analysisID, value string) (*models.Classification, error) {
+	t, ok := findDocumentType(value)
+	if !ok {
+		return nil, ErrUnknownDocType
+	}
+
+	analysis, err := repositories.GetAnalysis(userID, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.IsBundle() {
+		return nil, ErrBundleDocType
+	}
+
+	detected := analysis.Classification
+	if detected == nil {
+		// Записи до появления классификатора хранят только название типа
+		detected = &models.Classification{DocTypeScore: models.DocTypeScore{Label: analysis.Type}}
+		if known, ok := findDocumentType(analysis.Type); ok {
+			detected.ID = known.ID
+		}
+	}
+	classification := overrideClassification(detected, t)
+
+	if err := repositories.UpdateAnalysisType(analysis.ID, t.Label, classification); err != nil {
+		return nil, err
+	}
+	// Тип хранится и в метаданных векторного хранилища, по нему фильтруется поиск похожих
+	previous := analysis.Type
+	analysis.Type = t.Label
+	if err := retypeAnalysis(userID, analysis); err != nil {
+		utils.LogWarning(fmt.Sprintf("Ошибка обновления документа в векторном хранилище: %v", err))
+	}
+	utils.LogInfo(fmt.Sprintf("Тип документа %s изменён пользователем: %s → %s", analysisID, previous, t.Label))
+	return classification, nil
+}
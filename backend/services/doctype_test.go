@@ -0,0 +1,116 @@
+package services
+
+import (
+	"legally/utils"
+	"testing"
+)
+
+func TestClassifyDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		language string
+		want     string
+	}{
+		{
+			name:     "lease contract",
+			language: utils.LangRussian,
+			text: `ДОГОВОР АРЕНДЫ НЕЖИЛОГО ПОМЕЩЕНИЯ
+г. Алматы
+
+Арендодатель передаёт, а арендатор принимает помещение. Арендная плата вносится ежемесячно.`,
+			want: "lease_contract",
+		},
+		{
+			name:     "contract with article headings",
+			language: utils.LangRussian,
+			text: `ДОГОВОР
+
+Статья 1. Предмет
+Статья 2. Цена
+Статья 3. Сроки
+Статья 4. Прочие условия`,
+			want: "contract",
+		},
+		{
+			name:     "law",
+			language: utils.LangRussian,
+			text: `ЗАКОН РЕСПУБЛИКИ КАЗАХСТАН
+О жилищных отношениях
+
+Глава 1. Общие положения
+Статья 1. Основные понятия
+Настоящий Закон регулирует жилищные отношения.
+Статья 2. Сфера действия`,
+			want: "law",
+		},
+		{
+			name:     "claim titled ИСК",
+			language: utils.LangRussian,
+			text: `ИСК
+к ТОО «Альфа»
+
+Требую вернуть уплаченные деньги.`,
+			want: "claim",
+		},
+		{
+			name:     "english order",
+			language: utils.LangEnglish,
+			text: `ORDER
+of 1 March 2024
+
+J. Smith is transferred to the sales department.`,
+			want: "order",
+		},
+		{
+			name:     "employment agreement",
+			language: utils.LangEnglish,
+			text: `EMPLOYMENT AGREEMENT
+
+The employer hires the employee for a probation period of three months. The salary is paid monthly.`,
+			want: "employment_contract",
+		},
+		{
+			name:     "kazakh power of attorney",
+			language: utils.LangKazakh,
+			text: `СЕНІМХАТ
+
+Осы сенімхатпен мен өкілеттік беремін және сенім білдіремін.`,
+			want: "power_of_attorney",
+		},
+		{
+			name:     "unrelated text",
+			language: utils.LangEnglish,
+			text:     "Shopping list: milk, bread, eggs.",
+			want:     "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyDocument(tt.text, tt.language)
+			if got.ID != tt.want {
+				t.Errorf("classifyDocument() = %s (%.2f), runner-ups %+v; want %s", got.ID, got.Confidence, got.RunnerUps, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocTypeCandidates(t *testing.T) {
+	got := docTypeCandidates("Договор аренды")
+	if len(got) != 2 || got[0] != "Договор аренды" || got[1] != "Договор" {
+		t.Errorf("docTypeCandidates() = %v", got)
+	}
+}
+
+func TestDocTypeDescendants(t *testing.T) {
+	contract, _ := findDocumentType("contract")
+	got := docTypeDescendants(contract)
+	if len(got) != 9 || got[0] != "Договор" {
+		t.Errorf("docTypeDescendants(contract) = %v", got)
+	}
+	order, _ := findDocumentType("order")
+	if got := docTypeDescendants(order); len(got) != 1 {
+		t.Errorf("docTypeDescendants(order) = %v", got)
+	}
+}
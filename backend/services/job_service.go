@@ -0,0 +1,207 @@
+// job_service.go
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/models"
+	"legally/repositories"
+	"legally/utils"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const defaultJobWorkers = 2
+
+var (
+	ErrAnalysisFinished = errors.New("анализ не найден или уже завершен")
+	ErrJobNotReady      = errors.New("анализ ещё не завершён")
+
+	jobSlots     chan struct{}
+	jobSlotsOnce sync.Once
+)
+
+// InitJobs помечает задачи, оборванные прошлой остановкой сервера
+func InitJobs() {
+	n, err := repositories.FailInterruptedJobs()
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Не удалось обновить прерванные задачи: %v", err))
+		return
+	}
+	if n > 0 {
+		utils.LogWarning(fmt.Sprintf("Помечено %d задач, прерванных перезапуском", n))
+	}
+}
+
+// slots ограничивает число одновременно выполняемых задач значением JOB_WORKERS
+func slots() chan struct{} {
+	jobSlotsOnce.Do(func() {
+		n := utils.GetEnvInt("JOB_WORKERS", defaultJobWorkers)
+		if n < 1 {
+			n = 1
+		}
+		jobSlots = make(chan struct{}, n)
+	})
+	return jobSlots
+}
+
+// SubmitAnalysisJob извлекает текст из загруженного файла и ставит анализ в очередь
+func SubmitAnalysisJob(c *gin.Context) (*models.Job, *HttpError) {
+	utils.LogAction("Получен запрос на асинхронный анализ документа")
+
+	doc, err := utils.ProcessUploadedFile(c)
+	if err != nil {
+		utils.LogError(err.Error())
+		return nil, uploadHttpError(err)
+	}
+
+	userID := c.GetString("userId")
+	force := forceReanalysis(c)
+	job, err := repositories.CreateJob(userID, doc.Filename)
+	if err != nil {
+		return nil, &HttpError{Status: http.StatusInternalServerError, Message: "ошибка создания задачи", Code: "JOB_ERROR"}
+	}
+
+	// Задача живёт дольше запроса, поэтому её контекст не наследует контекст запроса
+	ctx := withResponseLanguage(context.Background(), requestedLanguage(c, userID))
+	ctx = withDocumentType(ctx, requestedDocumentType(c))
+
+	// Повторная загрузка: задача сразу завершается ссылкой на готовый анализ
+	if !doc.IsBundle() && !force {
+		if existing := findDuplicate(ctx, userID, doc); existing != nil {
+			if err := repositories.UpdateJob(job.ID, models.JobDone, bson.M{"analysis_id": existing.ID, "duplicate": true}); err == nil {
+				job.Status = models.JobDone
+				job.AnalysisID = existing.ID
+				job.Duplicate = true
+				return job, nil
+			}
+		}
+	}
+
+	StartAnalysis(ctx, userID, job.ID.Hex(), func(ctx context.Context) {
+		runAnalysisJob(ctx, userID, job, doc, force)
+	})
+
+	return job, nil
+}
+
+func runAnalysisJob(ctx context.Context, userID string, job *models.Job, doc *utils.UploadedDocument, force bool) {
+	select {
+	case slots() <- struct{}{}:
+		defer func() { <-slots() }()
+	case <-ctx.Done():
+		return
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	utils.LogAction(fmt.Sprintf("Задача %s: анализ начат", job.ID.Hex()))
+	_ = repositories.UpdateJob(job.ID, models.JobRunning, nil)
+
+	ctx, meter := withUsageMeter(ctx)
+	defer recordUserUsage(userID, meter)
+
+	var record *models.Analysis
+	var parts []models.PartStatus
+	if doc.IsBundle() {
+		bundle, err := analyzeBundle(ctx, userID, doc, force)
+		if finishFailedJob(ctx, job, err) || !claimJob(ctx, job) {
+			return
+		}
+		record, err = storeBundle(userID, doc, bundle)
+		if err != nil {
+			failStoredJob(job, nil)
+			return
+		}
+	} else {
+		analysis, err := AnalyzeText(ctx, doc)
+		if finishFailedJob(ctx, job, err) || !claimJob(ctx, job) {
+			return
+		}
+		parts = analysis.Parts
+		record, err = storeAnalysis(userID, doc, analysis)
+		if err != nil {
+			failStoredJob(job, parts)
+			return
+		}
+	}
+
+	_ = repositories.UpdateJob(job.ID, models.JobDone, bson.M{
+		"analysis_id": record.ID,
+		"parts":       parts,
+	})
+	utils.LogSuccess(fmt.Sprintf("Задача %s завершена", job.ID.Hex()))
+}
+
+func GetAnalysisJob(userID, jobID string) (*models.Job, error) {
+	return repositories.GetJob(userID, jobID)
+}
+
+// finishFailedJob завершает задачу при отмене или ошибке анализа и сообщает, что продолжать не нужно
+func finishFailedJob(ctx context.Context, job *models.Job, err error) bool {
+	if ctx.Err() != nil {
+		utils.LogInfo(fmt.Sprintf("Задача %s отменена", job.ID.Hex()))
+		_ = repositories.UpdateJob(job.ID, models.JobCancelled, nil)
+		return true
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Задача %s: %v", job.ID.Hex(), err))
+		_ = repositories.UpdateJob(job.ID, models.JobFailed, bson.M{
+			"error":      err.Error(),
+			"error_code": analysisHttpError(err).Code,
+		})
+		return true
+	}
+	return false
+}
+
+// claimJob закрепляет результат за задачей перед сохранением; отменённая
+// к этому моменту задача не попадает в историю и не индексируется
+func claimJob(ctx context.Context, job *models.Job) bool {
+	if claimAnalysis(ctx, job.ID.Hex()) {
+		return true
+	}
+	finishFailedJob(ctx, job, nil)
+	return false
+}
+
+func failStoredJob(job *models.Job, parts []models.PartStatus) {
+	_ = repositories.UpdateJob(job.ID, models.JobFailed, bson.M{
+		"error":      "ошибка сохранения анализа",
+		"error_code": "STORAGE_ERROR",
+		"parts":      parts,
+	})
+}
+
+// GetAnalysisJobResult возвращает ответ по сохранённому анализу завершённой задачи
+// в том же виде, что и синхронный анализ
+func GetAnalysisJobResult(userID, jobID string) (*models.Job, gin.H, error) {
+	job, err := repositories.GetJob(userID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.JobDone {
+		return job, nil, ErrJobNotReady
+	}
+
+	analysis, err := repositories.GetAnalysis(userID, job.AnalysisID.Hex())
+	if err != nil {
+		return job, nil, err
+	}
+
+	var resp gin.H
+	if analysis.IsBundle() {
+		resp = storedBundleResponse(userID, analysis)
+	} else {
+		resp = storedAnalysisResponse(analysis)
+	}
+	resp["duplicate"] = job.Duplicate
+	return job, resp, nil
+}
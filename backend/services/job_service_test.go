@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+// startBlocked запускает задачу, которая ждёт отмены или конца теста
+func startBlocked(t *testing.T, userID, jobID string) context.Context {
+	t.Helper()
+	release := make(chan struct{})
+	ctx := StartAnalysis(context.Background(), userID, jobID, func(ctx context.Context) {
+		select {
+		case <-ctx.Done():
+		case <-release:
+		}
+	})
+	t.Cleanup(func() {
+		close(release)
+		waitFinished(t, jobID)
+	})
+	return ctx
+}
+
+// waitFinished ждёт, пока задача снимется с учёта выполняемых
+func waitFinished(t *testing.T, jobID string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		cacheMutex.Lock()
+		_, active := activeAnalysis[jobID]
+		cacheMutex.Unlock()
+		if !active {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s is still active", jobID)
+}
+
+func TestCancelActiveJob(t *testing.T) {
+	ctx := startBlocked(t, "user-a", "job-1")
+	other := startBlocked(t, "user-a", "job-2")
+
+	if got := cancelActive("user-b", "job-1"); len(got) != 0 {
+		t.Errorf("another user cancelled %v", got)
+	}
+	if got := cancelActive("user-a", "job-1"); len(got) != 1 || got[0] != "job-1" {
+		t.Fatalf("cancelActive() = %v, want [job-1]", got)
+	}
+	if ctx.Err() == nil {
+		t.Error("job-1 context is not cancelled")
+	}
+	if other.Err() != nil {
+		t.Error("job-2 context is cancelled")
+	}
+	if claimAnalysis(ctx, "job-1") {
+		t.Error("cancelled job was claimed for storing")
+	}
+	if got := cancelActive("user-a", "job-1"); len(got) != 0 {
+		t.Errorf("second cancel = %v, want none", got)
+	}
+}
+
+func TestCancelAllUserJobs(t *testing.T) {
+	first := startBlocked(t, "user-c", "job-3")
+	second := startBlocked(t, "user-c", "job-4")
+	foreign := startBlocked(t, "user-d", "job-5")
+
+	got := cancelActive("user-c", "")
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "job-3" || got[1] != "job-4" {
+		t.Fatalf("cancelActive() = %v, want [job-3 job-4]", got)
+	}
+	if first.Err() == nil || second.Err() == nil {
+		t.Error("user jobs are not cancelled")
+	}
+	if foreign.Err() != nil {
+		t.Error("another user's job is cancelled")
+	}
+}
+
+func TestClaimedJobIsNotCancelled(t *testing.T) {
+	ctx := startBlocked(t, "user-e", "job-6")
+
+	if !claimAnalysis(ctx, "job-6") {
+		t.Fatal("claimAnalysis() = false for a running job")
+	}
+	// Сохранение уже началось: отмена не находит задачу
+	if got := cancelActive("user-e", "job-6"); len(got) != 0 {
+		t.Errorf("claimed job was cancelled: %v", got)
+	}
+	if ctx.Err() != nil {
+		t.Error("claimed job context is cancelled")
+	}
+}
+
+func TestFinishedJobLeavesActiveSet(t *testing.T) {
+	done := make(chan struct{})
+	StartAnalysis(context.Background(), "user-f", "job-7", func(ctx context.Context) { close(done) })
+	<-done
+	waitFinished(t, "job-7")
+	if got := cancelActive("user-f", ""); len(got) != 0 {
+		t.Errorf("finished job was cancelled: %v", got)
+	}
+}
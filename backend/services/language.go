@@ -0,0 +1,69 @@
+// language.go
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"legally/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// languageNames — названия языков отчёта на языке шаблона промпта
+var languageNames = map[string]map[string]string{
+	utils.LangRussian: {utils.LangRussian: "русский", utils.LangKazakh: "казахский", utils.LangEnglish: "английский"},
+	utils.LangKazakh:  {utils.LangRussian: "орыс тілі", utils.LangKazakh: "қазақ тілі", utils.LangEnglish: "ағылшын тілі"},
+	utils.LangEnglish: {utils.LangRussian: "Russian", utils.LangKazakh: "Kazakh", utils.LangEnglish: "English"},
+}
+
+// languageName возвращает название языка code так, как его пишут на языке in
+func languageName(code, in string) string {
+	names, ok := languageNames[in]
+	if !ok {
+		names = languageNames[utils.LangRussian]
+	}
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+type responseLanguageKey struct{}
+
+// withResponseLanguage задаёт язык отчёта, выбранный пользователем
+func withResponseLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, responseLanguageKey{}, language)
+}
+
+// responseLanguage возвращает язык отчёта: выбранный пользователем или язык документа
+func responseLanguage(ctx context.Context, docLanguage string) string {
+	if lang, ok := ctx.Value(responseLanguageKey{}).(string); ok && lang != "" {
+		return lang
+	}
+	return docLanguage
+}
+
+// requestedLanguage читает язык отчёта из параметра language запроса, а если
+// его нет — из настроек пользователя. Пустая строка означает язык документа.
+func requestedLanguage(c *gin.Context, userID string) string {
+	lang := c.Query("language")
+	if lang == "" {
+		lang = c.PostForm("language")
+	}
+	if lang != "" {
+		if utils.IsSupportedLanguage(lang) {
+			return lang
+		}
+		utils.LogWarning(fmt.Sprintf("Неподдерживаемый язык отчёта %q, используем язык документа", lang))
+	}
+
+	user, err := ValidateUser(userID)
+	if err != nil {
+		return ""
+	}
+	return user.Language
+}
@@ -0,0 +1,162 @@
+// pages.go
+
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"legally/models"
+	"legally/utils"
+)
+
+const (
+	// Короче этого цитата слишком часто встречается в тексте случайно
+	minLocateRunes = 12
+	locateWords    = 6
+)
+
+// attributePartPage проставляет страницу находкам части, если часть целиком лежит на одной странице
+func attributePartPage(result *models.AnalysisResult, part utils.DocumentPart, pages []models.Page) {
+	if len(pages) == 0 || part.End <= part.Start {
+		return
+	}
+	first := models.PageAt(pages, part.Start)
+	if first != 0 && first == models.PageAt(pages, part.End-1) {
+		result.AttributePage(first)
+	}
+}
+
+// locatePages уточняет страницы находок по тексту документа: цитата неясной
+// формулировки точнее всего, затем заголовок раздела. Страницы, которых нет
+// в документе (например, придуманные моделью при сведении), сбрасываются.
+func locatePages(result *models.AnalysisResult, text string, pages []models.Page) {
+	valid := make(map[int]bool, len(pages))
+	for _, p := range pages {
+		valid[p.Number] = true
+	}
+	check := func(page int) int {
+		if valid[page] {
+			return page
+		}
+		return 0
+	}
+
+	if len(pages) == 0 {
+		for i := range result.Risks {
+			result.Risks[i].Page = 0
+		}
+		for i := range result.Ambiguities {
+			result.Ambiguities[i].Page = 0
+		}
+		for i := range result.Violations {
+			result.Violations[i].Page = 0
+		}
+		return
+	}
+
+	idx := newTextIndex(text)
+	bySection := func(page int, section string) int {
+		if page != 0 || section == "" {
+			return page
+		}
+		if offset := idx.find(section); offset >= 0 {
+			return models.PageAt(pages, offset)
+		}
+		return 0
+	}
+
+	for i := range result.Risks {
+		it := &result.Risks[i]
+		it.Page = bySection(check(it.Page), it.Section)
+	}
+	for i := range result.Ambiguities {
+		it := &result.Ambiguities[i]
+		if offset := idx.find(it.Wording); offset >= 0 {
+			if page := models.PageAt(pages, offset); page != 0 {
+				it.Page = page
+				continue
+			}
+		}
+		it.Page = bySection(check(it.Page), it.Section)
+	}
+	for i := range result.Violations {
+		it := &result.Violations[i]
+		it.Page = bySection(check(it.Page), it.Section)
+	}
+}
+
+// textIndex ищет цитаты без учёта регистра, кавычек и пробелов
+// и возвращает смещение в символах исходного текста
+type textIndex struct {
+	norm    string
+	offsets []int // offsets[i] — позиция i-го символа norm в исходном тексте
+}
+
+func newTextIndex(text string) *textIndex {
+	var b strings.Builder
+	var offsets []int
+	space := false
+
+	pos := 0
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+		default:
+			if space && len(offsets) > 0 {
+				b.WriteRune(' ')
+				offsets = append(offsets, pos)
+			}
+			space = false
+			b.WriteRune(normalizeQuoteRune(r))
+			offsets = append(offsets, pos)
+		}
+		pos++
+	}
+	return &textIndex{norm: b.String(), offsets: offsets}
+}
+
+// find ищет цитату целиком, затем её начало и конец, если модель изменила середину
+func (idx *textIndex) find(quote string) int {
+	words := strings.Fields(normalizeQuote(quote))
+	if len(words) == 0 {
+		return -1
+	}
+
+	candidates := []string{strings.Join(words, " ")}
+	if len(words) > locateWords*2 {
+		candidates = append(candidates,
+			strings.Join(words[:locateWords], " "),
+			strings.Join(words[len(words)-locateWords:], " "))
+	}
+
+	for _, c := range candidates {
+		if utf8.RuneCountInString(c) < minLocateRunes {
+			continue
+		}
+		if i := strings.Index(idx.norm, c); i >= 0 {
+			return idx.offsets[utf8.RuneCountInString(idx.norm[:i])]
+		}
+	}
+	return -1
+}
+
+func normalizeQuote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteRune(normalizeQuoteRune(r))
+	}
+	return strings.Trim(b.String(), "\" .,;:…")
+}
+
+func normalizeQuoteRune(r rune) rune {
+	switch r {
+	case '«', '»', '“', '”', '„', '\'', '‘', '’':
+		return '"'
+	case 'ё', 'Ё':
+		return 'е'
+	}
+	return unicode.ToLower(r)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"legally/models"
+	"legally/utils"
+	"testing"
+	"unicode/utf8"
+)
+
+// pagedText склеивает страницы так же, как извлечение PDF, и возвращает их границы.
+// Вторая страница PDF считается пустой и пропущенной: номера идут 1, 3, 4…
+func pagedText(pages ...string) (string, []models.Page) {
+	var text string
+	var out []models.Page
+	for i, p := range pages {
+		if i > 0 {
+			text += "\n\n"
+		}
+		start := utf8.RuneCountInString(text)
+		text += p
+		number := i + 1
+		if i > 0 {
+			number++
+		}
+		out = append(out, models.Page{Number: number, Start: start, End: start + utf8.RuneCountInString(p)})
+	}
+	return text, out
+}
+
+func TestPageAt(t *testing.T) {
+	pages := []models.Page{{Number: 1, Start: 0, End: 10}, {Number: 3, Start: 12, End: 20}}
+	tests := []struct {
+		offset, want int
+	}{
+		{0, 1}, {9, 1}, {10, 0}, {11, 0}, {12, 3}, {19, 3}, {20, 0}, {-1, 0},
+	}
+	for _, tt := range tests {
+		if got := models.PageAt(pages, tt.offset); got != tt.want {
+			t.Errorf("PageAt(%d) = %d, want %d", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestLocatePages(t *testing.T) {
+	text, pages := pagedText(
+		"1. ПРЕДМЕТ ДОГОВОРА Арендодатель передаёт помещение во временное пользование.",
+		"2. ОТВЕТСТВЕННОСТЬ СТОРОН Арендатор уплачивает «неустойку в размере 1% в день» за просрочку.",
+	)
+
+	result := &models.AnalysisResult{
+		Risks: []models.Risk{
+			{Title: "Неустойка", Section: "2. Ответственность сторон"},
+			{Title: "Страница от модели", Page: 1},
+			{Title: "Несуществующая страница", Page: 7},
+		},
+		Ambiguities: []models.Ambiguity{
+			// Кавычки, регистр и ё отличаются от текста документа
+			{Wording: `"Неустойку в размере 1% в день"`, Page: 1},
+			{Wording: "коротко", Section: "1. Предмет договора"},
+		},
+		Violations: []models.Violation{{Description: "Нет раздела", Section: "9. Прочее"}},
+	}
+	locatePages(result, text, pages)
+
+	wantRisks := []int{3, 1, 0}
+	for i, want := range wantRisks {
+		if got := result.Risks[i].Page; got != want {
+			t.Errorf("risk %q page = %d, want %d", result.Risks[i].Title, got, want)
+		}
+	}
+	if got := result.Ambiguities[0].Page; got != 3 {
+		t.Errorf("quoted ambiguity page = %d, want 3", got)
+	}
+	if got := result.Ambiguities[1].Page; got != 1 {
+		t.Errorf("short ambiguity page = %d, want 1 by its section", got)
+	}
+	if got := result.Violations[0].Page; got != 0 {
+		t.Errorf("violation page = %d, want 0", got)
+	}
+
+	// Без страниц (не PDF) номера от модели сбрасываются
+	result = &models.AnalysisResult{Risks: []models.Risk{{Title: "Риск", Page: 2}}}
+	locatePages(result, text, nil)
+	if result.Risks[0].Page != 0 {
+		t.Errorf("page without pages = %d, want 0", result.Risks[0].Page)
+	}
+}
+
+func TestAttributePartPage(t *testing.T) {
+	_, pages := pagedText("первая страница", "вторая страница")
+
+	onePage := &models.AnalysisResult{Risks: []models.Risk{{Title: "Риск"}}}
+	attributePartPage(onePage, utils.DocumentPart{Start: 17, End: 32}, pages)
+	if onePage.Risks[0].Page != 3 {
+		t.Errorf("part on one page: page = %d, want 3", onePage.Risks[0].Page)
+	}
+
+	twoPages := &models.AnalysisResult{Risks: []models.Risk{{Title: "Риск"}}}
+	attributePartPage(twoPages, utils.DocumentPart{Start: 0, End: 32}, pages)
+	if twoPages.Risks[0].Page != 0 {
+		t.Errorf("part across pages: page = %d, want 0", twoPages.Risks[0].Page)
+	}
+}
@@ -0,0 +1,178 @@
+// retrieval.go
+
+package services
+
+import (
+	"fmt"
+	"legally/laws"
+	"legally/models"
+	"legally/utils"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultRAGArticles     = 5
+	defaultRAGArticleChars = 1500
+	// Фрагментов запрашивается с запасом: соседние фрагменты часто относятся к одной статье
+	ragHitsPerArticle = 4
+	ragQueryChars     = 200
+	// Сглаживание reciprocal rank fusion: первые места отрывка весят не намного больше следующих
+	ragFusionK = 60
+)
+
+var sentenceRe = regexp.MustCompile(`[^.!?;\n]+[.!?;\n]*`)
+
+// retrieveLegalContext подбирает каждой части документа статьи из локального
+// корпуса законов: текст части служит запросом к BM25-индексу. RAG_TOP_ARTICLES
+// задаёт число статей на часть, 0 отключает подбор.
+func retrieveLegalContext(parts []utils.DocumentPart) [][]models.LegalSource {
+	limit := utils.GetEnvInt("RAG_TOP_ARTICLES", defaultRAGArticles)
+	perPart := make([][]models.LegalSource, len(parts))
+	if limit <= 0 {
+		return perPart
+	}
+
+	for i, p := range parts {
+		perPart[i] = retrieveArticles(p.Text, limit)
+		for j := range perPart[i] {
+			perPart[i][j].Parts = []int{i + 1}
+		}
+	}
+	return perPart
+}
+
+// retrieveArticles возвращает до limit статей кодексов, лучше всего отвечающих тексту.
+// Длинный текст затрагивает много тем, и одним запросом находились бы статьи
+// только самой частой из них, поэтому поиск идёт по отрывкам, а места статей
+// в выдаче отрывков складываются (reciprocal rank fusion).
+func retrieveArticles(text string, limit int) []models.LegalSource {
+	index := laws.Default()
+	statutes := laws.DefaultStatutes()
+
+	fused := map[string]float64{}
+	found := map[string]models.LegalSource{}
+	for _, query := range ragQueries(text) {
+		hits := index.Search(query, laws.SearchOptions{Limit: limit * ragHitsPerArticle, Kind: laws.KindLaw})
+		rank := 0
+		seen := map[string]bool{}
+		for _, h := range hits {
+			for _, number := range h.Chunk.Articles {
+				id := h.Chunk.Source.ID + "/" + number
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				rank++
+				fused[id] += 1 / float64(ragFusionK+rank)
+				if _, ok := found[id]; ok {
+					continue
+				}
+				article, ok := statutes.Article(h.Chunk.Source.ID, number)
+				if !ok {
+					continue
+				}
+				found[id] = models.LegalSource{
+					ID:       id,
+					Code:     h.Chunk.Source.ID,
+					Article:  article.Number,
+					Title:    article.Title,
+					Citation: citation(h.Chunk.Source, article.Number),
+				}
+			}
+		}
+	}
+
+	sources := make([]models.LegalSource, 0, len(found))
+	for id, s := range found {
+		s.Score = math.Round(fused[id]*10000) / 10000
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Score != sources[j].Score {
+			return sources[i].Score > sources[j].Score
+		}
+		return sources[i].ID < sources[j].ID
+	})
+	if len(sources) > limit {
+		sources = sources[:limit]
+	}
+	return sources
+}
+
+// ragQueries делит текст на отрывки из целых предложений длиной около ragQueryChars символов
+func ragQueries(text string) []string {
+	var queries []string
+	var current strings.Builder
+	for _, sentence := range sentenceRe.FindAllString(text, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(sentence) > ragQueryChars {
+			queries = append(queries, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(" ")
+		}
+		current.WriteString(sentence)
+	}
+	if current.Len() > 0 {
+		queries = append(queries, current.String())
+	}
+	return queries
+}
+
+// citation — ссылка на статью в виде «ст. 401 ГК РК»
+func citation(source laws.Source, article string) string {
+	return fmt.Sprintf("ст. %s %s", article, source.Short)
+}
+
+// legalContextPrompt — статьи для подстановки в промпт: ссылка, название и текст,
+// обрезанный до RAG_ARTICLE_CHARS символов. Пустая строка — статей нет.
+func legalContextPrompt(sources []models.LegalSource) string {
+	maxChars := utils.GetEnvInt("RAG_ARTICLE_CHARS", defaultRAGArticleChars)
+	statutes := laws.DefaultStatutes()
+
+	var b strings.Builder
+	for _, s := range sources {
+		article, ok := statutes.Article(s.Code, s.Article)
+		if !ok {
+			continue
+		}
+		text := article.Text
+		if runes := []rune(text); maxChars > 0 && len(runes) > maxChars {
+			text = string(runes[:maxChars]) + "…"
+		}
+		fmt.Fprintf(&b, "[%s] %s\n%s\n\n", s.Citation, s.Title, text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// mergeLegalSources объединяет статьи всех частей для сохранения с анализом:
+// у повторяющейся статьи остаётся лучшая оценка и список всех частей
+func mergeLegalSources(perPart [][]models.LegalSource) []models.LegalSource {
+	var merged []models.LegalSource
+	index := map[string]int{}
+	for _, sources := range perPart {
+		for _, s := range sources {
+			i, ok := index[s.ID]
+			if !ok {
+				index[s.ID] = len(merged)
+				s.Parts = append([]int{}, s.Parts...)
+				merged = append(merged, s)
+				continue
+			}
+			merged[i].Parts = append(merged[i].Parts, s.Parts...)
+			if s.Score > merged[i].Score {
+				merged[i].Score = s.Score
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	return merged
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"legally/llm"
+	"legally/models"
+	"legally/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// testCivilCode — фрагмент кодекса для корпуса законов в тестах
+const testCivilCode = `Статья 293. Неустойка
+  1. Неустойкой признается определенная законодательством или договором денежная сумма, которую должник обязан уплатить кредитору в случае неисполнения или ненадлежащего исполнения обязательства, в частности в случае просрочки исполнения.
+Статья 540. Договор имущественного найма (аренды)
+  По договору имущественного найма (аренды) наймодатель обязуется предоставить нанимателю имущество за плату во временное владение и пользование.
+Статья 562. Арендная плата
+  1. Наниматель обязан своевременно вносить плату за пользование имуществом (арендную плату).
+  2. Размер арендной платы может изменяться по соглашению сторон не чаще одного раза в год.
+`
+
+// TestMain подключает к тестам маленький корпус законов вместо rag/data:
+// индекс и кодексы загружаются один раз на процесс
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "legally-laws-")
+	if err != nil {
+		panic(err)
+	}
+	for path, content := range map[string]string{
+		"chunks/civil_code_kz_chunk_1.txt": testCivilCode,
+		"raw/civil_code_kz.txt":            testCivilCode,
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			panic(err)
+		}
+	}
+	os.Setenv("LAWS_CHUNKS_DIR", filepath.Join(dir, "chunks"))
+	os.Setenv("LAWS_RAW_DIR", filepath.Join(dir, "raw"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRetrieveArticles(t *testing.T) {
+	sources := retrieveArticles("За просрочку арендатор уплачивает неустойку в размере 1% в день.", 2)
+	if len(sources) == 0 || len(sources) > 2 {
+		t.Fatalf("retrieveArticles() = %+v", sources)
+	}
+	if s := sources[0]; s.ID != "civil_code_kz/293" || s.Citation != "ст. 293 ГК РК" || s.Title != "Неустойка" || s.Score <= 0 {
+		t.Errorf("best source = %+v", s)
+	}
+}
+
+func TestLegalContextPrompt(t *testing.T) {
+	sources := []models.LegalSource{
+		{Code: "civil_code_kz", Article: "562", Title: "Арендная плата", Citation: "ст. 562 ГК РК"},
+		{Code: "civil_code_kz", Article: "9999", Citation: "ст. 9999 ГК РК"},
+	}
+
+	prompt := legalContextPrompt(sources)
+	if !strings.HasPrefix(prompt, "[ст. 562 ГК РК] Арендная плата\n") || !strings.Contains(prompt, "не чаще одного раза в год") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if strings.Contains(prompt, "9999") {
+		t.Error("prompt contains an article missing from the code")
+	}
+
+	t.Setenv("RAG_ARTICLE_CHARS", "20")
+	if short := legalContextPrompt(sources[:1]); !strings.HasSuffix(short, "…") || utf8.RuneCountInString(short) > 60 {
+		t.Errorf("truncated prompt = %q", short)
+	}
+	if legalContextPrompt(nil) != "" {
+		t.Error("prompt without sources should be empty")
+	}
+}
+
+func TestMergeLegalSources(t *testing.T) {
+	merged := mergeLegalSources([][]models.LegalSource{
+		{{ID: "civil_code_kz/540", Score: 0.02, Parts: []int{1}}, {ID: "civil_code_kz/562", Score: 0.01, Parts: []int{1}}},
+		{{ID: "civil_code_kz/562", Score: 0.03, Parts: []int{2}}},
+		nil,
+	})
+
+	if len(merged) != 2 {
+		t.Fatalf("merged = %+v", merged)
+	}
+	if m := merged[0]; m.ID != "civil_code_kz/562" || m.Score != 0.03 || len(m.Parts) != 2 {
+		t.Errorf("first source = %+v, want 562 with the best score and both parts", m)
+	}
+	if merged[1].ID != "civil_code_kz/540" {
+		t.Errorf("second source = %+v", merged[1])
+	}
+}
+
+func TestRagQueries(t *testing.T) {
+	sentence := "Арендатор обязан своевременно вносить арендную плату. "
+	queries := ragQueries(strings.Repeat(sentence, 10))
+	if len(queries) < 2 {
+		t.Fatalf("ragQueries() = %d windows, want several", len(queries))
+	}
+	for _, q := range queries {
+		if utf8.RuneCountInString(q) > ragQueryChars || !strings.HasSuffix(q, ".") {
+			t.Errorf("window %q is longer than %d runes or cuts a sentence", q, ragQueryChars)
+		}
+	}
+}
+
+func TestAnalyzeTextLegalContext(t *testing.T) {
+	splitIntoParts(t)
+	t.Setenv("RAG_TOP_ARTICLES", "2")
+
+	var mu sync.Mutex
+	var prompts []string
+	llm.SetProvider(&llm.Fake{Respond: func(req llm.Request) (string, error) {
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if strings.Contains(prompt, "Результаты частей:") {
+			return "", errReduceUnavailable
+		}
+		mu.Lock()
+		prompts = append(prompts, prompt)
+		mu.Unlock()
+		return `{"risks": [{"title": "Неустойка", "description": "Завышена", "level": "high", "legal_reference": "ст. 293 ГК РК", "recommendation": "Снизить"}], "conclusion": "Итог"}`, nil
+	}})
+	t.Cleanup(func() { llm.SetProvider(nil) })
+
+	analysis, err := AnalyzeText(context.Background(), &utils.UploadedDocument{Filename: "lease.txt", Text: testContract})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Статья о неустойке подставлена в промпт части, где о ней речь
+	injected := false
+	for _, p := range prompts {
+		if strings.Contains(p, "неустойку") && strings.Contains(p, "[ст. 293 ГК РК] Неустойка") {
+			injected = true
+		}
+	}
+	if !injected {
+		t.Error("article 293 was not injected into the prompt of the part about the penalty")
+	}
+
+	var penalty *models.LegalSource
+	for i, s := range analysis.LegalSources {
+		if s.ID == "civil_code_kz/293" {
+			penalty = &analysis.LegalSources[i]
+		}
+	}
+	if penalty == nil || len(penalty.Parts) == 0 {
+		t.Fatalf("legal sources = %+v, want article 293 with its parts", analysis.LegalSources)
+	}
+	if len(analysis.Citations) == 0 || analysis.Citations[0].Status != models.CitationVerified {
+		t.Errorf("citations = %+v, want the reference to article 293 verified", analysis.Citations)
+	}
+}
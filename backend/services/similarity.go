@@ -0,0 +1,208 @@
+// similarity.go
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/laws"
+	"legally/models"
+	"legally/redact"
+	"legally/utils"
+	"legally/vectors"
+	"math"
+	"strings"
+	"time"
+)
+
+const vectorTimeout = 30 * time.Second
+
+// indexAnalysis кладёт текст сохранённого анализа в векторное хранилище под ID анализа;
+// персональные данные замаскированы
+func indexAnalysis(userID string, record *models.Analysis) error {
+	store := vectors.Default()
+	// Записи архивов не содержат текста: их документы индексируются по отдельности
+	if store == nil || record.ID.IsZero() || strings.TrimSpace(record.Text) == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vectorTimeout)
+	defer cancel()
+	return store.Upsert(ctx, []vectors.Record{{
+		ID:   record.ID.Hex(),
+		Text: redact.Text(record.Text),
+		Metadata: map[string]interface{}{
+			"user_id":    userID,
+			"filename":   record.Filename,
+			"type":       record.Type,
+			"language":   record.Language,
+			"created_at": record.CreatedAt.Unix(),
+		},
+	}})
+}
+
+// retypeAnalysis меняет тип анализа в метаданных векторного хранилища без
+// пересчёта эмбеддинга: текст документа не менялся
+func retypeAnalysis(userID string, record *models.Analysis) error {
+	store := vectors.Default()
+	if store == nil || record.ID.IsZero() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vectorTimeout)
+	defer cancel()
+	return store.UpdateMetadata(ctx, record.ID.Hex(), map[string]interface{}{"type": record.Type},
+		vectors.Filter{Equals: map[string]string{"user_id": userID}})
+}
+
+// forgetAnalyses удаляет из векторного хранилища документы пользователя
+func forgetAnalyses(userID string, analysisIDs []string) error {
+	store := vectors.Default()
+	if store == nil || len(analysisIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vectorTimeout)
+	defer cancel()
+	return store.Delete(ctx, analysisIDs, vectors.Filter{Equals: map[string]string{"user_id": userID}})
+}
+
+var (
+	ErrVectorStoreDisabled = errors.New("векторное хранилище не настроено")
+	ErrNoOrganization      = errors.New("пользователь не состоит в организации")
+)
+
+const similaritySnippetChars = 300
+
+// SearchSimilar ищет документы, похожие на текст, только среди документов
+// пользователя или его организации, с учётом фильтров запроса
+func SearchSimilar(userID string, q models.SimilarityQuery) ([]models.SimilarDocument, error) {
+	store := vectors.Default()
+	if store == nil {
+		return nil, ErrVectorStoreDisabled
+	}
+
+	owners, err := similarityOwners(userID, q.Scope)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := similarityFilter(owners, q)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vectorTimeout)
+	defer cancel()
+	text := redact.Text(q.Text)
+	matches, err := store.Query(ctx, vectors.Query{Text: text, TopK: q.TopK, MinScore: q.MinScore, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]models.SimilarDocument, 0, len(matches))
+	for _, m := range matches {
+		doc := models.SimilarDocument{
+			AnalysisID: m.ID,
+			Score:      math.Round(m.Score*1000) / 1000,
+			Snippet:    similaritySnippet(m.Text, text),
+			Own:        fmt.Sprint(m.Metadata["user_id"]) == userID,
+		}
+		doc.Filename, _ = m.Metadata["filename"].(string)
+		doc.Type, _ = m.Metadata["type"].(string)
+		doc.Language, _ = m.Metadata["language"].(string)
+		if ts, ok := m.Number("created_at"); ok {
+			created := time.Unix(int64(ts), 0).UTC()
+			doc.CreatedAt = &created
+		}
+		docs = append(docs, doc)
+	}
+	utils.LogInfo(fmt.Sprintf("Найдено %d похожих документов (область: %s)", len(docs), q.Scope))
+	return docs, nil
+}
+
+// similarityOwners возвращает пользователей, среди документов которых идёт поиск:
+// самого пользователя или всех участников его организации
+func similarityOwners(userID, scope string) ([]string, error) {
+	if scope != models.SimilarityScopeOrganization {
+		return []string{userID}, nil
+	}
+	user, err := ValidateUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.OrganizationID == "" {
+		return nil, ErrNoOrganization
+	}
+	return organizationMembers(user.OrganizationID)
+}
+
+// similarityFilter собирает фильтр метаданных: область поиска всегда
+// ограничена владельцами документов owners
+func similarityFilter(owners []string, q models.SimilarityQuery) (vectors.Filter, error) {
+	filter := vectors.Filter{
+		Equals:   map[string]string{},
+		In:       map[string][]string{},
+		Range:    map[string]vectors.Range{},
+		Contains: map[string]string{},
+	}
+
+	switch len(owners) {
+	case 0:
+		return filter, ErrNoOrganization
+	case 1:
+		filter.Equals["user_id"] = owners[0]
+	default:
+		filter.In["user_id"] = owners
+	}
+
+	if q.DocType != "" {
+		t, ok := findDocumentType(q.DocType)
+		if !ok {
+			return filter, ErrUnknownDocType
+		}
+		filter.In["type"] = docTypeDescendants(t)
+	}
+	if q.From != nil || q.To != nil {
+		var r vectors.Range
+		if q.From != nil {
+			from := float64(q.From.Unix())
+			r.Min = &from
+		}
+		if q.To != nil {
+			to := float64(q.To.Unix())
+			r.Max = &to
+		}
+		filter.Range["created_at"] = r
+	}
+	if q.Filename != "" {
+		filter.Contains["filename"] = q.Filename
+	}
+	return filter, nil
+}
+
+// similaritySnippet возвращает отрывок документа, в котором больше всего слов запроса
+func similaritySnippet(text, query string) string {
+	terms := map[string]bool{}
+	for _, t := range laws.Tokenize(query) {
+		terms[t] = true
+	}
+
+	best, bestScore := "", -1
+	for _, window := range ragQueries(text) {
+		score := 0
+		for _, t := range laws.Tokenize(window) {
+			if terms[t] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = window, score
+		}
+	}
+	best = strings.Join(strings.Fields(best), " ")
+	if runes := []rune(best); len(runes) > similaritySnippetChars {
+		best = string(runes[:similaritySnippetChars]) + "…"
+	}
+	return best
+}
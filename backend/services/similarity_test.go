@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"legally/models"
+	"legally/vectors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimilarityOwnersUserScope(t *testing.T) {
+	// Поиск по своим документам не обращается к базе пользователей
+	for _, scope := range []string{"", models.SimilarityScopeUser} {
+		owners, err := similarityOwners("u1", scope)
+		if err != nil || len(owners) != 1 || owners[0] != "u1" {
+			t.Errorf("similarityOwners(%q) = %v, %v", scope, owners, err)
+		}
+	}
+}
+
+func TestSimilarityFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	own, err := similarityFilter([]string{"u1"}, models.SimilarityQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.Equals["user_id"] != "u1" || len(own.In) != 0 {
+		t.Errorf("own filter = %+v", own)
+	}
+
+	org, err := similarityFilter([]string{"u1", "u2"}, models.SimilarityQuery{DocType: "contract", From: &from, Filename: "аренд"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := org.Equals["user_id"]; ok || strings.Join(org.In["user_id"], ",") != "u1,u2" {
+		t.Errorf("organization filter = %+v", org)
+	}
+	if !containsLabel(org.In["type"], "Договор") || !containsLabel(org.In["type"], "Договор аренды") {
+		t.Errorf("type filter %v should include the type and its subtypes", org.In["type"])
+	}
+	if r := org.Range["created_at"]; r.Min == nil || *r.Min != float64(from.Unix()) || r.Max != nil {
+		t.Errorf("created_at range = %+v", r)
+	}
+	if org.Contains["filename"] != "аренд" {
+		t.Errorf("filename filter = %+v", org.Contains)
+	}
+
+	if _, err := similarityFilter(nil, models.SimilarityQuery{}); !errors.Is(err, ErrNoOrganization) {
+		t.Errorf("filter without owners error = %v", err)
+	}
+	if _, err := similarityFilter([]string{"u1"}, models.SimilarityQuery{DocType: "нет такого"}); !errors.Is(err, ErrUnknownDocType) {
+		t.Errorf("unknown doc type error = %v", err)
+	}
+}
+
+func TestSimilarityFilterScopesStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := vectors.OpenLocal(t.TempDir(), vectors.NewHashEmbedder(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(id, user, docType string) vectors.Record {
+		return vectors.Record{ID: id, Text: "Договор аренды нежилого помещения", Metadata: map[string]interface{}{"user_id": user, "type": docType}}
+	}
+	err = store.Upsert(ctx, []vectors.Record{
+		record("own", "u1", "Договор аренды"),
+		record("colleague", "u2", "Договор"),
+		record("stranger", "u3", "Договор аренды"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		owners []string
+		query  models.SimilarityQuery
+		want   string
+	}{
+		{"own documents", []string{"u1"}, models.SimilarityQuery{}, "own"},
+		{"organization", []string{"u1", "u2"}, models.SimilarityQuery{}, "colleague,own"},
+		{"organization by subtype", []string{"u1", "u2"}, models.SimilarityQuery{DocType: "lease_contract"}, "own"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := similarityFilter(tt.owners, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches, err := store.Query(ctx, vectors.Query{Text: "аренда помещения", TopK: 10, Filter: filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+			}
+			sort.Strings(ids)
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// usage_service.go
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"legally/llm"
+	"legally/models"
+	"legally/repositories"
+	"legally/utils"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ModelPrice — цена модели в долларах за миллион токенов
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+var (
+	modelPrices     map[string]ModelPrice
+	modelPricesOnce sync.Once
+)
+
+// prices читает LLM_PRICES, например {"gpt-4o-mini": {"prompt": 0.15, "completion": 0.6}}.
+// Модели без цены учитываются с нулевой стоимостью.
+func prices() map[string]ModelPrice {
+	modelPricesOnce.Do(func() {
+		modelPrices = parsePrices(os.Getenv("LLM_PRICES"))
+	})
+	return modelPrices
+}
+
+func parsePrices(raw string) map[string]ModelPrice {
+	parsed := map[string]ModelPrice{}
+	if raw == "" {
+		return parsed
+	}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		utils.LogWarning(fmt.Sprintf("Некорректный LLM_PRICES: %v", err))
+		return map[string]ModelPrice{}
+	}
+	return parsed
+}
+
+func usageCost(model string, u llm.Usage) float64 {
+	p, ok := prices()[model]
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion) / 1e6
+}
+
+// usageMeter копит расход токенов всех запросов одного анализа
+type usageMeter struct {
+	mu      sync.Mutex
+	byModel map[string]*models.TokenUsage
+	parent  *usageMeter
+}
+
+type usageMeterKey struct{}
+
+// withUsageMeter возвращает контекст, в котором queryLLM учитывает расход токенов
+func withUsageMeter(ctx context.Context) (context.Context, *usageMeter) {
+	if m := usageMeterFrom(ctx); m != nil {
+		return ctx, m
+	}
+	m := &usageMeter{byModel: map[string]*models.TokenUsage{}}
+	return context.WithValue(ctx, usageMeterKey{}, m), m
+}
+
+// withChildUsageMeter заводит отдельный счётчик, например для одного файла архива;
+// расход по-прежнему попадает и во внешний счётчик
+func withChildUsageMeter(ctx context.Context) (context.Context, *usageMeter) {
+	m := &usageMeter{byModel: map[string]*models.TokenUsage{}, parent: usageMeterFrom(ctx)}
+	return context.WithValue(ctx, usageMeterKey{}, m), m
+}
+
+func usageMeterFrom(ctx context.Context) *usageMeter {
+	m, _ := ctx.Value(usageMeterKey{}).(*usageMeter)
+	return m
+}
+
+func (m *usageMeter) add(model string, u llm.Usage) {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	if m.parent != nil {
+		m.parent.add(model, u)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.byModel[model]
+	if !ok {
+		t = &models.TokenUsage{Model: model}
+		m.byModel[model] = t
+	}
+	t.Requests++
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.TotalTokens += u.TotalTokens
+	t.Cost += usageCost(model, u)
+}
+
+func (m *usageMeter) Summary() *models.UsageSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary := &models.UsageSummary{Models: []models.TokenUsage{}}
+	for _, t := range m.byModel {
+		summary.PromptTokens += t.PromptTokens
+		summary.CompletionTokens += t.CompletionTokens
+		summary.TotalTokens += t.TotalTokens
+		summary.Cost += t.Cost
+		summary.Models = append(summary.Models, *t)
+	}
+	sort.Slice(summary.Models, func(i, j int) bool { return summary.Models[i].Model < summary.Models[j].Model })
+	return summary
+}
+
+// recordUserUsage добавляет расход к дневной статистике пользователя, в том числе для неудачных анализов
+func recordUserUsage(userID string, meter *usageMeter) {
+	summary := meter.Summary()
+	if len(summary.Models) == 0 {
+		return
+	}
+	if err := repositories.RecordUsage(userID, summary, time.Now()); err != nil {
+		utils.LogWarning(fmt.Sprintf("Не удалось сохранить расход токенов: %v", err))
+	}
+}
+
+// GetUserUsage возвращает расход пользователя по дням и итог за период
+func GetUserUsage(userID string, from, to time.Time) ([]models.DailyUsage, models.UsageSummary, error) {
+	days, err := repositories.GetUserUsage(userID, from, to)
+	if err != nil {
+		return nil, models.UsageSummary{}, err
+	}
+	return days, summarizeUsage(days), nil
+}
+
+// summarizeUsage складывает дневные записи в итог по всем моделям и по каждой модели
+func summarizeUsage(days []models.DailyUsage) models.UsageSummary {
+	total := models.UsageSummary{Models: []models.TokenUsage{}}
+	byModel := map[string]int{}
+	for _, d := range days {
+		total.PromptTokens += d.PromptTokens
+		total.CompletionTokens += d.CompletionTokens
+		total.TotalTokens += d.TotalTokens
+		total.Cost += d.Cost
+
+		i, ok := byModel[d.Model]
+		if !ok {
+			i = len(total.Models)
+			byModel[d.Model] = i
+			total.Models = append(total.Models, models.TokenUsage{Model: d.Model})
+		}
+		t := &total.Models[i]
+		t.Requests += d.Requests
+		t.PromptTokens += d.PromptTokens
+		t.CompletionTokens += d.CompletionTokens
+		t.TotalTokens += d.TotalTokens
+		t.Cost += d.Cost
+	}
+	return total
+}
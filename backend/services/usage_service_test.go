@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"legally/llm"
+	"legally/models"
+	"math"
+	"testing"
+)
+
+// withPrices подменяет цены моделей на время теста
+func withPrices(t *testing.T, raw string) {
+	t.Helper()
+	prices()
+	saved := modelPrices
+	modelPrices = parsePrices(raw)
+	t.Cleanup(func() { modelPrices = saved })
+}
+
+func TestUsageCost(t *testing.T) {
+	withPrices(t, `{"gpt-4o-mini": {"prompt": 0.15, "completion": 0.6}}`)
+
+	if got := usageCost("gpt-4o-mini", llm.Usage{PromptTokens: 2_000_000, CompletionTokens: 500_000}); math.Abs(got-0.6) > 1e-9 {
+		t.Errorf("usageCost() = %v, want 0.6", got)
+	}
+	if got := usageCost("unknown", llm.Usage{PromptTokens: 1000}); got != 0 {
+		t.Errorf("usageCost() of a model without price = %v, want 0", got)
+	}
+	if got := parsePrices(`not json`); len(got) != 0 {
+		t.Errorf("parsePrices() of invalid JSON = %v, want empty", got)
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	withPrices(t, `{"gpt-4o-mini": {"prompt": 1, "completion": 2}}`)
+
+	ctx, meter := withUsageMeter(context.Background())
+	if same, m := withUsageMeter(ctx); same != ctx || m != meter {
+		t.Error("withUsageMeter() replaced an existing meter")
+	}
+	fileCtx, file := withChildUsageMeter(ctx)
+	if usageMeterFrom(fileCtx) != file {
+		t.Fatal("child meter is not in the context")
+	}
+
+	meter.add("gpt-4o-mini", llm.Usage{PromptTokens: 100, CompletionTokens: 50})
+	file.add("gpt-4o-mini", llm.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500})
+	file.add("local", llm.Usage{PromptTokens: 10, CompletionTokens: 5})
+
+	total := meter.Summary()
+	if total.PromptTokens != 1110 || total.CompletionTokens != 555 || total.TotalTokens != 1665 {
+		t.Errorf("total = %+v", total)
+	}
+	if len(total.Models) != 2 || total.Models[0].Model != "gpt-4o-mini" || total.Models[0].Requests != 2 {
+		t.Fatalf("models = %+v", total.Models)
+	}
+	if math.Abs(total.Cost-(1100*1+550*2)/1e6) > 1e-12 {
+		t.Errorf("cost = %v", total.Cost)
+	}
+
+	// Счётчик файла видит только свой расход
+	if own := file.Summary(); own.TotalTokens != 1515 || len(own.Models) != 2 {
+		t.Errorf("file usage = %+v", own)
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	days := []models.DailyUsage{
+		{Date: "2026-10-01", Model: "gpt-4o-mini", Requests: 3, PromptTokens: 300, CompletionTokens: 100, TotalTokens: 400, Cost: 0.1},
+		{Date: "2026-10-01", Model: "local", Requests: 1, PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		{Date: "2026-10-02", Model: "gpt-4o-mini", Requests: 2, PromptTokens: 200, CompletionTokens: 50, TotalTokens: 250, Cost: 0.05},
+	}
+
+	total := summarizeUsage(days)
+	if total.TotalTokens != 665 || total.PromptTokens != 510 || math.Abs(total.Cost-0.15) > 1e-9 {
+		t.Errorf("total = %+v", total)
+	}
+	if len(total.Models) != 2 {
+		t.Fatalf("models = %+v", total.Models)
+	}
+	if m := total.Models[0]; m.Model != "gpt-4o-mini" || m.Requests != 5 || m.TotalTokens != 650 {
+		t.Errorf("gpt-4o-mini = %+v", m)
+	}
+
+	if empty := summarizeUsage(nil); empty.Models == nil || empty.TotalTokens != 0 {
+		t.Errorf("empty summary = %+v, want zero totals and an empty model list", empty)
+	}
+}
@@ -0,0 +1,190 @@
+// bundle.go
+
+package utils
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// FileTypeZIP — архив с несколькими документами (договор, приложения, акты)
+const FileTypeZIP = "zip"
+
+const (
+	defaultBundleMaxFiles   = 20
+	defaultBundleMaxTotalMB = 50
+	// Степень сжатия выше этой у документов не встречается, зато типична для zip-бомб
+	maxBundleRatio = 100
+)
+
+var ErrBundleLimit = errors.New("архив превышает допустимые ограничения")
+
+// BundleLimits — ограничения распаковки архива
+type BundleLimits struct {
+	MaxFiles     int
+	MaxFileSize  int64
+	MaxTotalSize int64
+	MaxRatio     uint64
+}
+
+// DefaultBundleLimits читает BUNDLE_MAX_FILES и BUNDLE_MAX_TOTAL_MB;
+// отдельный файл архива ограничен так же, как обычная загрузка
+func DefaultBundleLimits() BundleLimits {
+	files := GetEnvInt("BUNDLE_MAX_FILES", defaultBundleMaxFiles)
+	if files <= 0 {
+		files = defaultBundleMaxFiles
+	}
+	total := GetEnvInt("BUNDLE_MAX_TOTAL_MB", defaultBundleMaxTotalMB)
+	if total <= 0 {
+		total = defaultBundleMaxTotalMB
+	}
+	return BundleLimits{
+		MaxFiles:     files,
+		MaxFileSize:  MaxUploadSize(),
+		MaxTotalSize: int64(total) << 20,
+		MaxRatio:     maxBundleRatio,
+	}
+}
+
+// ExtractBundle извлекает текст из каждого документа ZIP-архива.
+// Ошибка отдельного файла записывается в его Err, а нарушение ограничений
+// архива целиком (похожее на zip-бомбу) прерывает распаковку.
+func ExtractBundle(filePath string, limits BundleLimits) ([]*UploadedDocument, error) {
+	LogAction(fmt.Sprintf("Распаковка архива: %s", filePath))
+
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: повреждённый архив", ErrUnsupportedFile)
+	}
+	defer zr.Close()
+
+	var members []*zip.File
+	var declared uint64
+	for _, f := range zr.File {
+		if skipBundleEntry(f) {
+			continue
+		}
+		members = append(members, f)
+		declared += f.UncompressedSize64
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("архив не содержит файлов")
+	}
+	if len(members) > limits.MaxFiles {
+		return nil, fmt.Errorf("%w: файлов в архиве %d, допускается не больше %d", ErrBundleLimit, len(members), limits.MaxFiles)
+	}
+	if declared > uint64(limits.MaxTotalSize) {
+		return nil, fmt.Errorf("%w: распакованный размер больше %dMB", ErrBundleLimit, limits.MaxTotalSize>>20)
+	}
+
+	var docs []*UploadedDocument
+	var total int64
+	for _, f := range members {
+		doc := &UploadedDocument{Filename: bundleEntryName(f)}
+		docs = append(docs, doc)
+
+		if f.UncompressedSize64 > uint64(limits.MaxFileSize) {
+			doc.Err = fmt.Errorf("%w: размер файла больше %dMB", ErrFileTooLarge, limits.MaxFileSize>>20)
+			continue
+		}
+		if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > limits.MaxRatio {
+			return nil, fmt.Errorf("%w: подозрительная степень сжатия файла %s", ErrBundleLimit, doc.Filename)
+		}
+
+		written, err := extractBundleEntry(f, doc, limits.MaxFileSize)
+		total += written
+		if total > limits.MaxTotalSize {
+			return nil, fmt.Errorf("%w: распакованный размер больше %dMB", ErrBundleLimit, limits.MaxTotalSize>>20)
+		}
+		if errors.Is(err, ErrBundleLimit) {
+			return nil, err
+		}
+		if err != nil {
+			LogWarning(fmt.Sprintf("Файл архива %s не обработан: %v", doc.Filename, err))
+			doc.Err = err
+		}
+	}
+
+	LogSuccess(fmt.Sprintf("Архив распакован: документов %d", len(docs)))
+	return docs, nil
+}
+
+// extractBundleEntry распаковывает файл во временный и извлекает из него текст.
+// Возвращает число распакованных байт.
+func extractBundleEntry(f *zip.File, doc *UploadedDocument, maxSize int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось распаковать файл: %v", err)
+	}
+	defer rc.Close()
+
+	tempFile, err := Temp().Create()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания временного файла")
+	}
+	tempPath := tempFile.Name()
+	defer Temp().Remove(tempPath)
+
+	// Заголовку архива не доверяем: читаем не больше лимита
+	written, err := io.Copy(tempFile, io.LimitReader(rc, maxSize+1))
+	tempFile.Close()
+	if err != nil {
+		return written, fmt.Errorf("не удалось распаковать файл: %v", err)
+	}
+	if written > maxSize || uint64(written) > f.UncompressedSize64 {
+		return written, fmt.Errorf("%w: размер файла %s не совпадает с заголовком архива", ErrBundleLimit, doc.Filename)
+	}
+
+	fileType, err := SniffFileType(tempPath, doc.Filename)
+	if err != nil {
+		return written, err
+	}
+	if fileType == FileTypeZIP {
+		return written, fmt.Errorf("%w: вложенные архивы не поддерживаются", ErrUnsupportedFile)
+	}
+
+	text, pages, err := SafeExtractDocument(tempPath, fileType, extractTimeout)
+	if err != nil {
+		return written, fmt.Errorf("ошибка извлечения текста: %v", err)
+	}
+
+	doc.FileType = fileType
+	doc.Text = text
+	doc.ContentHash = ContentHash(text)
+	doc.Language = documentLanguage(doc.Filename, text)
+	doc.Pages = pages
+	return written, nil
+}
+
+// skipBundleEntry пропускает каталоги и служебные файлы архиваторов
+func skipBundleEntry(f *zip.File) bool {
+	if f.FileInfo().IsDir() {
+		return true
+	}
+	name := strings.ReplaceAll(f.Name, "\\", "/")
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	base := path.Base(name)
+	return strings.HasPrefix(base, ".") || strings.HasPrefix(base, "~$") || base == "Thumbs.db"
+}
+
+// bundleEntryName возвращает безопасное имя файла архива. Архиваторы Windows
+// пишут кириллические имена в CP866 без флага UTF-8.
+func bundleEntryName(f *zip.File) string {
+	name := f.Name
+	if !utf8.ValidString(name) {
+		if decoded, err := charmap.CodePage866.NewDecoder().String(name); err == nil {
+			name = decoded
+		}
+	}
+	return SanitizeFilename(name)
+}
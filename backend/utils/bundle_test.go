@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain направляет временные файлы распаковки в отдельный каталог:
+// Temp() читает TEMP_DIR один раз на процесс
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "legally-test-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("TEMP_DIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type zipEntry struct {
+	name    string
+	content []byte
+}
+
+// writeZip собирает архив из файлов в каталоге теста
+func writeZip(t *testing.T, entries ...zipEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func text(s string) []byte { return []byte(s) }
+
+func TestExtractBundle(t *testing.T) {
+	limits := BundleLimits{MaxFiles: 3, MaxFileSize: 1 << 20, MaxTotalSize: 2 << 20, MaxRatio: maxBundleRatio}
+
+	path := writeZip(t,
+		zipEntry{"contract.txt", text("Договор аренды нежилого помещения")},
+		zipEntry{"docs/annex.txt", text("Приложение 1. Акт приёма-передачи")},
+		zipEntry{"__MACOSX/._contract.txt", text("metadata")},
+		zipEntry{".DS_Store", text("metadata")},
+		zipEntry{"nested.zip", zipBytes(t)},
+	)
+	docs, err := ExtractBundle(path, limits)
+	if err != nil {
+		t.Fatalf("ExtractBundle() error = %v", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("extracted %d documents, want 3", len(docs))
+	}
+	if docs[0].Filename != "contract.txt" || docs[0].Err != nil || !strings.Contains(docs[0].Text, "Договор аренды") {
+		t.Errorf("contract = %+v", docs[0])
+	}
+	if docs[1].Filename != "annex.txt" || docs[1].Err != nil {
+		t.Errorf("annex = %+v", docs[1])
+	}
+	if !errors.Is(docs[2].Err, ErrUnsupportedFile) {
+		t.Errorf("nested archive error = %v, want unsupported file", docs[2].Err)
+	}
+}
+
+func TestExtractBundleLimits(t *testing.T) {
+	limits := BundleLimits{MaxFiles: 2, MaxFileSize: 64 << 10, MaxTotalSize: 96 << 10, MaxRatio: maxBundleRatio}
+	zeros := func(n int) []byte { return make([]byte, n) }
+	filler := func(n int) []byte { return bytes.Repeat([]byte("Договор. "), n/len("Договор. ")+1)[:n] }
+
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		wantErr error
+	}{
+		{"too many files", []zipEntry{{"a.txt", text("a")}, {"b.txt", text("b")}, {"c.txt", text("c")}}, ErrBundleLimit},
+		{"declared total too large", []zipEntry{{"a.txt", filler(60 << 10)}, {"b.txt", filler(60 << 10)}}, ErrBundleLimit},
+		{"compression ratio", []zipEntry{{"bomb.txt", zeros(60 << 10)}}, ErrBundleLimit},
+		{"empty archive", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractBundle(writeZip(t, tt.entries...), limits)
+			if err == nil {
+				t.Fatal("ExtractBundle() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ExtractBundle() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Слишком большой файл пропускается, остальные документы обрабатываются
+	docs, err := ExtractBundle(writeZip(t,
+		zipEntry{"big.txt", filler(80 << 10)},
+		zipEntry{"small.txt", text("Доверенность")},
+	), BundleLimits{MaxFiles: 2, MaxFileSize: 64 << 10, MaxTotalSize: 1 << 20, MaxRatio: maxBundleRatio})
+	if err != nil {
+		t.Fatalf("ExtractBundle() error = %v", err)
+	}
+	if !errors.Is(docs[0].Err, ErrFileTooLarge) || docs[1].Err != nil {
+		t.Errorf("errors = %v, %v; want file too large, nil", docs[0].Err, docs[1].Err)
+	}
+}
+
+func zipBytes(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(writeZip(t, zipEntry{"inner.txt", text("inner")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,409 @@
+// docx.go
+
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxDOCXPartSize = 50 << 20 // защита от распакованных частей чрезмерного размера
+
+// ExtractTextFromDOCX извлекает текст из документа Word: абзацы с нумерацией
+// списков и таблицы (ячейки через " | ", строка таблицы — строка текста), а за
+// ними колонтитулы — чтобы верхний колонтитул не принимался за название документа
+func ExtractTextFromDOCX(filePath string) (string, error) {
+	LogAction(fmt.Sprintf("Извлечение текста из DOCX: %s", filePath))
+
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть документ: %v", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	body, ok := files["word/document.xml"]
+	if !ok {
+		return "", fmt.Errorf("документ не содержит word/document.xml")
+	}
+
+	numbering := &docxNumbering{}
+	if f, ok := files["word/numbering.xml"]; ok {
+		if err := readDOCXPart(f, numbering.parse); err != nil {
+			LogWarning(fmt.Sprintf("Не удалось прочитать нумерацию DOCX: %v", err))
+		}
+	}
+
+	var headers, footers []string
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		base := path.Base(name)
+		if path.Dir(name) != "word" || path.Ext(base) != ".xml" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(base, "header"):
+			headers = appendDOCXPart(headers, files[name])
+		case strings.HasPrefix(base, "footer"):
+			footers = appendDOCXPart(footers, files[name])
+		}
+	}
+
+	var lines []string
+	err = readDOCXPart(body, func(r io.Reader) error {
+		var err error
+		lines, err = parseDOCXText(r, numbering)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения документа: %v", err)
+	}
+
+	sections := []string{strings.Join(lines, "\n")}
+	sections = append(sections, headers...)
+	sections = append(sections, footers...)
+
+	text := normalizeText(strings.Join(sections, "\n\n"))
+	if text == "" {
+		return "", fmt.Errorf("файл пуст")
+	}
+
+	LogInfo(fmt.Sprintf("Извлечено %d символов из DOCX", len(text)))
+	return text, nil
+}
+
+// appendDOCXPart добавляет текст колонтитула, пропуская повторы
+// (у разных разделов документа колонтитулы часто одинаковые)
+func appendDOCXPart(parts []string, f *zip.File) []string {
+	var lines []string
+	err := readDOCXPart(f, func(r io.Reader) error {
+		var err error
+		lines, err = parseDOCXText(r, nil)
+		return err
+	})
+	if err != nil {
+		LogWarning(fmt.Sprintf("Не удалось прочитать колонтитул %s: %v", f.Name, err))
+		return parts
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return parts
+	}
+	for _, p := range parts {
+		if p == text {
+			return parts
+		}
+	}
+	return append(parts, text)
+}
+
+func readDOCXPart(f *zip.File, parse func(io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return parse(io.LimitReader(rc, maxDOCXPartSize))
+}
+
+// docxTable — состояние разбора таблицы (таблицы бывают вложенными)
+type docxTable struct {
+	row  []string
+	cell []string
+}
+
+// parseDOCXText проходит по WordprocessingML и собирает строки текста
+func parseDOCXText(r io.Reader, numbering *docxNumbering) ([]string, error) {
+	dec := xml.NewDecoder(r)
+
+	var lines []string
+	var tables []*docxTable
+	var para strings.Builder
+	var numID, ilvl string
+	inText := false
+
+	emit := func(line string) {
+		if len(tables) > 0 {
+			top := tables[len(tables)-1]
+			top.cell = append(top.cell, line)
+			return
+		}
+		lines = append(lines, line)
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				numID, ilvl = "", "0"
+			case "numId":
+				numID = docxAttr(t, "val")
+			case "ilvl":
+				ilvl = docxAttr(t, "val")
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				tables = append(tables, &docxTable{})
+			}
+
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				prefix := ""
+				if numbering != nil && numID != "" {
+					prefix = numbering.next(numID, ilvl)
+				}
+				if text != "" {
+					if prefix != "" {
+						text = prefix + " " + text
+					}
+					emit(text)
+				}
+				para.Reset()
+			case "tc":
+				if len(tables) > 0 {
+					top := tables[len(tables)-1]
+					top.row = append(top.row, strings.Join(top.cell, " "))
+					top.cell = nil
+				}
+			case "tr":
+				if len(tables) > 0 {
+					top := tables[len(tables)-1]
+					row := top.row
+					top.row = nil
+					if strings.TrimSpace(strings.Join(row, "")) == "" {
+						continue
+					}
+					tables = tables[:len(tables)-1]
+					emit(strings.Join(row, " | "))
+					tables = append(tables, top)
+				}
+			case "tbl":
+				if len(tables) > 0 {
+					tables = tables[:len(tables)-1]
+				}
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+func docxAttr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxLevel — формат одного уровня списка из numbering.xml
+type docxLevel struct {
+	format string // decimal, lowerLetter, upperRoman, bullet...
+	text   string // шаблон вида "%1.%2."
+	start  int
+}
+
+// docxNumbering восстанавливает номера пунктов автоматических списков Word
+type docxNumbering struct {
+	abstract map[string]map[int]docxLevel // abstractNumId -> уровни
+	nums     map[string]string            // numId -> abstractNumId
+	counters map[string][]int             // numId -> текущие значения по уровням
+}
+
+func (n *docxNumbering) parse(r io.Reader) error {
+	n.abstract = map[string]map[int]docxLevel{}
+	n.nums = map[string]string{}
+	n.counters = map[string][]int{}
+
+	dec := xml.NewDecoder(r)
+	var abstractID, numID string
+	var lvlIdx = -1
+	var lvl docxLevel
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "abstractNum":
+				abstractID = docxAttr(t, "abstractNumId")
+				n.abstract[abstractID] = map[int]docxLevel{}
+			case "lvl":
+				lvlIdx, _ = strconv.Atoi(docxAttr(t, "ilvl"))
+				lvl = docxLevel{format: "decimal", start: 1}
+			case "start":
+				if v, err := strconv.Atoi(docxAttr(t, "val")); err == nil {
+					lvl.start = v
+				}
+			case "numFmt":
+				lvl.format = docxAttr(t, "val")
+			case "lvlText":
+				lvl.text = docxAttr(t, "val")
+			case "num":
+				numID = docxAttr(t, "numId")
+			case "abstractNumId":
+				if numID != "" {
+					n.nums[numID] = docxAttr(t, "val")
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "lvl":
+				if abstractID != "" && lvlIdx >= 0 {
+					n.abstract[abstractID][lvlIdx] = lvl
+				}
+				lvlIdx = -1
+			case "abstractNum":
+				abstractID = ""
+			case "num":
+				numID = ""
+			}
+		}
+	}
+}
+
+// next увеличивает счётчик уровня и возвращает текст номера пункта
+func (n *docxNumbering) next(numID, ilvlStr string) string {
+	levels := n.abstract[n.nums[numID]]
+	if levels == nil {
+		return ""
+	}
+	ilvl, err := strconv.Atoi(ilvlStr)
+	if err != nil || ilvl < 0 || ilvl > 8 {
+		ilvl = 0
+	}
+
+	counters := n.counters[numID]
+	if counters == nil {
+		counters = make([]int, 9)
+		for i := range counters {
+			counters[i] = levels[i].start - 1
+		}
+	}
+	counters[ilvl]++
+	// более глубокие уровни начинаются заново
+	for i := ilvl + 1; i < len(counters); i++ {
+		counters[i] = levels[i].start - 1
+	}
+	n.counters[numID] = counters
+
+	lvl := levels[ilvl]
+	if lvl.format == "bullet" || lvl.format == "none" {
+		if lvl.format == "bullet" {
+			return "•"
+		}
+		return ""
+	}
+
+	text := lvl.text
+	for i := 0; i <= ilvl; i++ {
+		value := counters[i]
+		if value < levels[i].start {
+			value = levels[i].start
+		}
+		text = strings.ReplaceAll(text, "%"+strconv.Itoa(i+1), formatDOCXNumber(value, levels[i].format))
+	}
+	return strings.TrimSpace(text)
+}
+
+func formatDOCXNumber(n int, format string) string {
+	switch format {
+	case "lowerLetter":
+		return strings.ToLower(docxLetters(n))
+	case "upperLetter":
+		return docxLetters(n)
+	case "lowerRoman":
+		return strings.ToLower(docxRoman(n))
+	case "upperRoman":
+		return docxRoman(n)
+	case "russianLower":
+		return strings.ToLower(docxCyrillic(n))
+	case "russianUpper":
+		return docxCyrillic(n)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// docxLetters: 1 -> A, 26 -> Z, 27 -> AA (так нумерует Word)
+func docxLetters(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	letter := string(rune('A' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+func docxCyrillic(n int) string {
+	const alphabet = "АБВГДЕЖЗИКЛМНОПРСТУФХЦЧШЩЭЮЯ"
+	letters := []rune(alphabet)
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	letter := string(letters[(n-1)%len(letters)])
+	return strings.Repeat(letter, (n-1)/len(letters)+1)
+}
+
+func docxRoman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
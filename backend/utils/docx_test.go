@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDOCX собирает минимальный документ Word из частей word/*.xml
+func writeDOCX(t *testing.T, parts map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.docx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>` + body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return path
+}
+
+func docxParagraphs(root string, paras ...string) string {
+	var b strings.Builder
+	b.WriteString(`<w:` + root + ` xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	if root == "document" {
+		b.WriteString(`<w:body>`)
+	}
+	for _, p := range paras {
+		b.WriteString(`<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`)
+	}
+	if root == "document" {
+		b.WriteString(`</w:body>`)
+	}
+	b.WriteString(`</w:` + root + `>`)
+	return b.String()
+}
+
+func TestExtractTextFromDOCXHeadersAfterBody(t *testing.T) {
+	path := writeDOCX(t, map[string]string{
+		"word/document.xml": docxParagraphs("document", "ДОГОВОР АРЕНДЫ", "Арендодатель передаёт помещение."),
+		"word/header1.xml":  docxParagraphs("hdr", "ТОО «Ромашка» — конфиденциально"),
+		"word/header2.xml":  docxParagraphs("hdr", "ТОО «Ромашка» — конфиденциально"),
+		"word/footer1.xml":  docxParagraphs("ftr", "Страница 1"),
+	})
+
+	text, err := ExtractTextFromDOCX(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, "ДОГОВОР АРЕНДЫ") {
+		t.Errorf("text does not start with the body: %q", text)
+	}
+	header := strings.Index(text, "конфиденциально")
+	if header < strings.Index(text, "Арендодатель") || header > strings.Index(text, "Страница 1") {
+		t.Errorf("header is not placed between the body and the footer: %q", text)
+	}
+	if strings.Count(text, "конфиденциально") != 1 {
+		t.Errorf("repeated header is not deduplicated: %q", text)
+	}
+}
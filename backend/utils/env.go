@@ -0,0 +1,31 @@
+// env.go
+
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GetEnv возвращает значение переменной окружения или значение по умолчанию
+func GetEnv(name, def string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return def
+}
+
+// GetEnvInt возвращает целое из переменной окружения; некорректные значения игнорируются
+func GetEnvInt(name string, def int) int {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		LogWarning("Некорректное значение " + name + ": " + v)
+		return def
+	}
+	return n
+}
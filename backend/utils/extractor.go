@@ -0,0 +1,167 @@
+// extractor.go
+
+package utils
+
+import (
+	"fmt"
+	"legally/models"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Типы документов, которые умеем разбирать
+const (
+	FileTypePDF  = "pdf"
+	FileTypeDOCX = "docx"
+	FileTypeTXT  = "txt"
+	FileTypeRTF  = "rtf"
+	FileTypeHTML = "html"
+)
+
+const extractTimeout = 30 * time.Second
+
+// Extractor извлекает текст из файла определённого типа
+type Extractor interface {
+	Extract(path string) (string, error)
+}
+
+// PagedExtractor — извлекатель, который сохраняет границы страниц документа
+type PagedExtractor interface {
+	Extractor
+	ExtractPages(path string) (string, []models.Page, error)
+}
+
+// ExtractorFunc позволяет зарегистрировать обычную функцию как Extractor
+type ExtractorFunc func(path string) (string, error)
+
+func (f ExtractorFunc) Extract(path string) (string, error) {
+	return f(path)
+}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]Extractor{}
+
+	fileExtensions = map[string]string{
+		".pdf":  FileTypePDF,
+		".docx": FileTypeDOCX,
+		".txt":  FileTypeTXT,
+		".text": FileTypeTXT,
+		".rtf":  FileTypeRTF,
+		".html": FileTypeHTML,
+		".htm":  FileTypeHTML,
+	}
+
+	blankLinesRe = regexp.MustCompile(`\n{3,}`)
+	innerSpaceRe = regexp.MustCompile(`[ \t\x{00A0}]+`)
+)
+
+func init() {
+	RegisterExtractor(FileTypePDF, pdfExtractor{})
+	RegisterExtractor(FileTypeDOCX, ExtractorFunc(ExtractTextFromDOCX))
+	RegisterExtractor(FileTypeTXT, ExtractorFunc(ExtractTextFromTXT))
+	RegisterExtractor(FileTypeRTF, ExtractorFunc(ExtractTextFromRTF))
+	RegisterExtractor(FileTypeHTML, ExtractorFunc(ExtractTextFromHTML))
+}
+
+// RegisterExtractor регистрирует извлекатель текста для типа файла
+func RegisterExtractor(fileType string, e Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[fileType] = e
+}
+
+// SupportedFileTypes возвращает зарегистрированные типы файлов
+func SupportedFileTypes() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	types := make([]string, 0, len(extractors))
+	for t := range extractors {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// DetectFileType определяет тип файла по имени.
+// Возвращает пустую строку, если для типа нет извлекателя.
+func DetectFileType(filename string) string {
+	fileType, ok := fileExtensions[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return ""
+	}
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	if _, ok := extractors[fileType]; !ok {
+		return ""
+	}
+	return fileType
+}
+
+// IsSupportedFile сообщает, можно ли извлечь текст из файла с таким именем
+func IsSupportedFile(filename string) bool {
+	return DetectFileType(filename) != ""
+}
+
+// ExtractText извлекает текст извлекателем, зарегистрированным для типа файла
+func ExtractText(path, fileType string) (string, error) {
+	text, _, err := ExtractDocument(path, fileType)
+	return text, err
+}
+
+// ExtractDocument извлекает текст и, если формат это позволяет, границы страниц
+func ExtractDocument(path, fileType string) (string, []models.Page, error) {
+	extractorsMu.RLock()
+	e, ok := extractors[fileType]
+	extractorsMu.RUnlock()
+	if !ok {
+		return "", nil, fmt.Errorf("неподдерживаемый формат файла: %s", fileType)
+	}
+
+	if paged, ok := e.(PagedExtractor); ok {
+		return paged.ExtractPages(path)
+	}
+	text, err := e.Extract(path)
+	return text, nil, err
+}
+
+// SafeExtractDocument извлекает текст и страницы с ограничением по времени
+func SafeExtractDocument(path, fileType string, timeout time.Duration) (string, []models.Page, error) {
+	type extraction struct {
+		text  string
+		pages []models.Page
+		err   error
+	}
+	done := make(chan extraction, 1)
+
+	go func() {
+		text, pages, err := ExtractDocument(path, fileType)
+		done <- extraction{text, pages, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.text, res.pages, res.err
+	case <-time.After(timeout):
+		return "", nil, fmt.Errorf("таймаут извлечения текста (%v)", timeout)
+	}
+}
+
+// normalizeText убирает лишние пробелы, сохраняя абзацы
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(innerSpaceRe.ReplaceAllString(line, " "))
+	}
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(text, "\n\n"))
+}
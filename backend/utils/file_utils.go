@@ -3,155 +3,235 @@
 package utils
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ledongthuc/pdf"
 	"io"
+	"legally/models"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
-	maxFileSize    = 10 << 20 // 10MB
 	tempFilePrefix = "temp_"
-	pdfTimeout     = 30 * time.Second
+	pageSeparator  = "\n\n"
 )
 
-func ProcessUploadedFile(c *gin.Context) (string, string, error) {
+// MultipartOverhead — запас на заголовки multipart-формы сверх размера самого файла
+const MultipartOverhead = 1 << 20
+
+// UploadedDocument — текст загруженного документа.
+// Pages заполняется только для форматов со страницами (PDF).
+// Для ZIP-архива текст пуст, а документы архива лежат в Files;
+// Err у документа архива означает, что его текст извлечь не удалось.
+type UploadedDocument struct {
+	Filename    string
+	FileType    string
+	Text        string
+	ContentHash string
+	Language    string
+	Pages       []models.Page
+	Files       []*UploadedDocument
+	Err         error
+}
+
+// IsBundle сообщает, что загружен архив с несколькими документами
+func (d *UploadedDocument) IsBundle() bool {
+	return d.FileType == FileTypeZIP
+}
+
+func ProcessUploadedFile(c *gin.Context) (*UploadedDocument, error) {
 	LogAction("Начало обработки загруженного файла")
 
 	// Ensure we don't process files that are too large
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize)
-	if err := c.Request.ParseMultipartForm(maxFileSize); err != nil {
-		LogError(fmt.Sprintf("Превышен максимальный размер файла (10MB): %v", err))
-		return "", "", fmt.Errorf("размер файла не должен превышать 10MB")
+	maxSize := MaxUploadSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+MultipartOverhead)
+	if err := c.Request.ParseMultipartForm(maxSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			LogError(fmt.Sprintf("Превышен максимальный размер файла (%dMB): %v", maxSize>>20, err))
+			return nil, fmt.Errorf("%w: размер файла не должен превышать %dMB", ErrFileTooLarge, maxSize>>20)
+		}
+		LogError(fmt.Sprintf("Ошибка разбора формы: %v", err))
+		return nil, fmt.Errorf("файл не получен")
 	}
 
 	file, header, err := c.Request.FormFile("document")
 	if err != nil {
 		LogError(fmt.Sprintf("Ошибка получения файла: %v", err))
-		return "", "", fmt.Errorf("файл не получен")
+		return nil, fmt.Errorf("файл не получен")
 	}
 	defer file.Close()
 
-	// Validate file extension
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".pdf" {
-		LogError(fmt.Sprintf("Неподдерживаемый формат файла: %s", ext))
-		return "", "", fmt.Errorf("поддерживаются только PDF файлы")
+	if header.Size > maxSize {
+		return nil, fmt.Errorf("%w: размер файла не должен превышать %dMB", ErrFileTooLarge, maxSize>>20)
 	}
+	filename := SanitizeFilename(header.Filename)
 
-	// Create temp file
-	tempPath := filepath.Join("./temp", tempFilePrefix+fmt.Sprintf("%d_%s", time.Now().Unix(), header.Filename))
-	LogInfo(fmt.Sprintf("Создание временного файла: %s", tempPath))
-
-	tempFile, err := os.Create(tempPath)
+	// Имя временного файла случайное и не зависит от имени, присланного клиентом
+	tempFile, err := Temp().Create()
 	if err != nil {
 		LogError(fmt.Sprintf("Ошибка создания временного файла: %v", err))
-		return "", "", fmt.Errorf("ошибка создания временного файла")
+		return nil, fmt.Errorf("ошибка создания временного файла")
 	}
+	tempPath := tempFile.Name()
+	defer Temp().Remove(tempPath)
 
 	// Copy file contents
-	if _, err := io.Copy(tempFile, file); err != nil {
-		tempFile.Close()
+	_, err = io.Copy(tempFile, file)
+	tempFile.Close()
+	if err != nil {
 		LogError(fmt.Sprintf("Ошибка сохранения файла: %v", err))
-		return "", "", fmt.Errorf("ошибка сохранения файла")
+		return nil, fmt.Errorf("ошибка сохранения файла")
+	}
+
+	// Validate file type by content
+	fileType, err := SniffFileType(tempPath, filename)
+	if err != nil {
+		LogError(fmt.Sprintf("Неподдерживаемый формат файла %s: %v", filename, err))
+		return nil, fmt.Errorf("%w: поддерживаются только файлы %s", ErrUnsupportedFile, strings.ToUpper(strings.Join(append(SupportedFileTypes(), FileTypeZIP), ", ")))
 	}
-	tempFile.Close()
 
-	// Extract text from PDF
-	text, err := SafeExtractTextFromPDF(tempPath, pdfTimeout)
+	if fileType == FileTypeZIP {
+		return processBundle(tempPath, filename)
+	}
+
+	// Extract text with the extractor registered for the file type
+	text, pages, err := SafeExtractDocument(tempPath, fileType, extractTimeout)
 	if err != nil {
-		os.Remove(tempPath)
 		LogError(fmt.Sprintf("Ошибка извлечения текста: %v", err))
-		return "", "", fmt.Errorf("ошибка извлечения текста: %v", err)
+		return nil, fmt.Errorf("ошибка извлечения текста: %v", err)
 	}
 
 	if len(text) == 0 {
-		os.Remove(tempPath)
 		LogWarning("Документ не содержит текста")
-		return "", "", fmt.Errorf("документ не содержит текста")
+		return nil, fmt.Errorf("документ не содержит текста")
 	}
 
-	// Clean up temp file
-	if err := os.Remove(tempPath); err != nil {
-		LogWarning(fmt.Sprintf("Не удалось удалить временный файл: %v", err))
-	}
+	lang := documentLanguage(filename, text)
+	LogSuccess(fmt.Sprintf("Успешно обработан файл: %s (%s, %s, символов: %d)", filename, fileType, lang, len(text)))
+	return &UploadedDocument{
+		Filename:    filename,
+		FileType:    fileType,
+		Text:        text,
+		ContentHash: ContentHash(text),
+		Language:    lang,
+		Pages:       pages,
+	}, nil
+}
 
-	LogSuccess(fmt.Sprintf("Успешно обработан файл: %s (символов: %d)", header.Filename, len(text)))
-	return text, header.Filename, nil
+// ContentHash — SHA-256 извлечённого текста без учёта пробелов. Одинаковый документ,
+// пересохранённый в другом файле или формате, даёт тот же хеш.
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
 }
 
-func SafeExtractTextFromPDF(path string, timeout time.Duration) (string, error) {
-	result := make(chan string)
-	errChan := make(chan error)
+// processBundle извлекает документы архива; архив без единого читаемого документа отклоняется
+func processBundle(tempPath, filename string) (*UploadedDocument, error) {
+	files, err := ExtractBundle(tempPath, DefaultBundleLimits())
+	if err != nil {
+		LogError(fmt.Sprintf("Ошибка распаковки архива %s: %v", filename, err))
+		return nil, err
+	}
 
-	go func() {
-		text, err := ExtractTextFromPDF(path)
-		if err != nil {
-			errChan <- err
-			return
+	extracted := 0
+	for _, f := range files {
+		if f.Err == nil {
+			extracted++
 		}
-		result <- text
-	}()
-
-	select {
-	case text := <-result:
-		return text, nil
-	case err := <-errChan:
-		return "", err
-	case <-time.After(timeout):
-		return "", fmt.Errorf("таймаут извлечения текста (%v)", timeout)
 	}
+	if extracted == 0 {
+		return nil, fmt.Errorf("%w: в архиве нет документов, из которых удалось извлечь текст", ErrUnsupportedFile)
+	}
+
+	LogSuccess(fmt.Sprintf("Успешно обработан архив: %s (документов: %d из %d)", filename, extracted, len(files)))
+	return &UploadedDocument{
+		Filename: filename,
+		FileType: FileTypeZIP,
+		Files:    files,
+	}, nil
+}
+
+func SafeExtractTextFromPDF(path string, timeout time.Duration) (string, error) {
+	text, _, err := SafeExtractDocument(path, FileTypePDF, timeout)
+	return text, err
+}
+
+// pdfExtractor извлекает текст PDF постранично
+type pdfExtractor struct{}
+
+func (pdfExtractor) Extract(path string) (string, error) {
+	return ExtractTextFromPDF(path)
+}
+
+func (pdfExtractor) ExtractPages(path string) (string, []models.Page, error) {
+	return ExtractPagesFromPDF(path)
 }
 
 func ExtractTextFromPDF(path string) (string, error) {
+	text, _, err := ExtractPagesFromPDF(path)
+	return text, err
+}
+
+// ExtractPagesFromPDF извлекает текст по страницам. Страницы разделяются пустой строкой,
+// для каждой непустой страницы возвращаются её границы в итоговом тексте.
+func ExtractPagesFromPDF(path string) (string, []models.Page, error) {
 	LogAction(fmt.Sprintf("Извлечение текста из PDF: %s", path))
 
 	f, r, err := pdf.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("не удалось открыть документ: %v", err)
+		return "", nil, fmt.Errorf("не удалось открыть документ: %v", err)
 	}
 	defer f.Close()
 
-	var buf bytes.Buffer
-	b, err := r.GetPlainText()
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения документа: %v", err)
-	}
-
-	if _, err := io.Copy(&buf, b); err != nil {
-		return "", fmt.Errorf("ошибка копирования текста: %v", err)
-	}
+	var buf strings.Builder
+	var pages []models.Page
+	offset := 0
+	fonts := make(map[string]*pdf.Font)
 
-	text := buf.String()
-	if len(text) == 0 {
-		return "", fmt.Errorf("файл пуст")
-	}
-
-	text = strings.Join(strings.Fields(text), " ")
-	LogInfo(fmt.Sprintf("Извлечено %d символов из PDF", len(text)))
-	return text, nil
-}
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		// шрифты кэшируются, чтобы не разбирать таблицы символов на каждой странице
+		for _, name := range p.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := p.Font(name)
+				fonts[name] = &font
+			}
+		}
 
-func SplitText(text string, maxChars int) []string {
-	LogAction(fmt.Sprintf("Разделение текста (макс. %d символов на часть)", maxChars))
+		raw, err := p.GetPlainText(fonts)
+		if err != nil {
+			return "", nil, fmt.Errorf("ошибка чтения страницы %d: %v", i, err)
+		}
 
-	runes := []rune(text)
-	var parts []string
+		text := strings.Join(strings.Fields(raw), " ")
+		if text == "" {
+			continue
+		}
 
-	for start := 0; start < len(runes); start += maxChars {
-		end := start + maxChars
-		if end > len(runes) {
-			end = len(runes)
+		if buf.Len() > 0 {
+			buf.WriteString(pageSeparator)
+			offset += utf8.RuneCountInString(pageSeparator)
 		}
-		parts = append(parts, string(runes[start:end]))
+		length := utf8.RuneCountInString(text)
+		pages = append(pages, models.Page{Number: i, Start: offset, End: offset + length})
+		buf.WriteString(text)
+		offset += length
+	}
+
+	if buf.Len() == 0 {
+		return "", nil, fmt.Errorf("файл пуст")
 	}
 
-	LogInfo(fmt.Sprintf("Текст разделен на %d частей", len(parts)))
-	return parts
+	LogInfo(fmt.Sprintf("Извлечено %d символов из PDF (%d стр.)", buf.Len(), len(pages)))
+	return buf.String(), pages, nil
 }
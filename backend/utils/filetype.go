@@ -0,0 +1,138 @@
+// filetype.go
+
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	sniffLength         = 512
+	maxFilenameBytes    = 200
+	defaultUploadSizeMB = 10
+)
+
+var (
+	ErrFileTooLarge    = errors.New("файл слишком большой")
+	ErrUnsupportedFile = errors.New("неподдерживаемый формат файла")
+)
+
+// MaxUploadSize — максимальный размер загружаемого файла в байтах (MAX_UPLOAD_MB)
+func MaxUploadSize() int64 {
+	mb := GetEnvInt("MAX_UPLOAD_MB", defaultUploadSizeMB)
+	if mb <= 0 {
+		mb = defaultUploadSizeMB
+	}
+	return int64(mb) << 20
+}
+
+// SniffFileType определяет тип файла по содержимому. Расширение имени
+// учитывается только для выбора между HTML и простым текстом.
+func SniffFileType(filePath, filename string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+	if n == 0 {
+		return "", fmt.Errorf("%w: файл пуст", ErrUnsupportedFile)
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte{0xEF, 0xBB, 0xBF}), " \t\r\n")
+	byName := DetectFileType(filename)
+
+	var fileType string
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		fileType = FileTypePDF
+	case bytes.HasPrefix(trimmed, []byte(`{\rtf`)):
+		fileType = FileTypeRTF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		fileType, err = sniffZip(filePath)
+		if err != nil {
+			return "", err
+		}
+	default:
+		contentType := http.DetectContentType(head)
+		switch {
+		case strings.HasPrefix(contentType, "text/html"):
+			fileType = FileTypeHTML
+		case strings.HasPrefix(contentType, "text/plain"):
+			fileType = FileTypeTXT
+			if byName == FileTypeHTML {
+				fileType = FileTypeHTML
+			}
+		default:
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedFile, contentType)
+		}
+	}
+
+	if byName != "" && byName != fileType {
+		LogWarning(fmt.Sprintf("Расширение файла %s не соответствует содержимому (%s)", filename, fileType))
+	}
+	return fileType, nil
+}
+
+// sniffZip отличает документ Word от архива с документами
+func sniffZip(filePath string) (string, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: повреждённый архив", ErrUnsupportedFile)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return FileTypeDOCX, nil
+		}
+	}
+	return FileTypeZIP, nil
+}
+
+// SanitizeFilename оставляет от имени, присланного клиентом, только безопасное
+// базовое имя: без каталогов, управляющих и зарезервированных символов
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+
+	if len(name) > maxFilenameBytes {
+		ext := path.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		base := strings.TrimSuffix(name, ext)
+		for len(base)+len(ext) > maxFilenameBytes {
+			_, size := utf8.DecodeLastRuneInString(base)
+			base = base[:len(base)-size]
+		}
+		name = base + ext
+	}
+
+	if name == "" {
+		return "document"
+	}
+	return name
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	long := strings.Repeat("договор", 40) + ".pdf"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Договор аренды.pdf", "Договор аренды.pdf"},
+		{"unix path", "../../etc/passwd", "passwd"},
+		{"windows path", `C:\Users\user\contract.docx`, "contract.docx"},
+		{"reserved characters", `a<b>c:d"e|f?g*.txt`, "a_b_c_d_e_f_g_.txt"},
+		{"control characters", "file\x00name\n.txt", "file_name_.txt"},
+		{"dots and spaces trimmed", " ..hidden. ", "hidden"},
+		{"empty", "", "document"},
+		{"only dots", "..", "document"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.in); got != tt.want {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	got := SanitizeFilename(long)
+	if len(got) > maxFilenameBytes || !strings.HasSuffix(got, ".pdf") || !strings.HasPrefix(got, "договор") {
+		t.Errorf("long name sanitized to %d bytes: %q", len(got), got)
+	}
+}
+
+func TestSniffFileType(t *testing.T) {
+	write := func(name string, content []byte) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	docx := writeZip(t, zipEntry{"word/document.xml", text("<w:document/>")})
+	bundle := writeZip(t, zipEntry{"a.txt", text("Договор")})
+
+	tests := []struct {
+		name     string
+		path     string
+		filename string
+		want     string
+		wantErr  bool
+	}{
+		{"pdf", write("a", []byte("%PDF-1.7\n...")), "contract.docx", FileTypePDF, false},
+		{"rtf with BOM and spaces", write("b", []byte("\xEF\xBB\xBF \n{\\rtf1\\ansi Договор}")), "contract.rtf", FileTypeRTF, false},
+		{"docx", docx, "contract.pdf", FileTypeDOCX, false},
+		{"zip bundle", bundle, "documents.zip", FileTypeZIP, false},
+		{"html", write("c", []byte("<!DOCTYPE html><html><body>Договор</body></html>")), "contract.txt", FileTypeHTML, false},
+		{"html without markers by extension", write("d", []byte("Договор аренды")), "contract.html", FileTypeHTML, false},
+		{"plain text", write("e", []byte("Договор аренды нежилого помещения")), "contract", FileTypeTXT, false},
+		{"executable", write("f", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff")), "contract.pdf", "", true},
+		{"broken zip", write("g", []byte("PK\x03\x04 not really a zip")), "contract.docx", "", true},
+		{"empty", write("h", nil), "contract.txt", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SniffFileType(tt.path, tt.filename)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedFile) {
+					t.Errorf("SniffFileType() = %q, %v; want ErrUnsupportedFile", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("SniffFileType() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTempFilesSweep(t *testing.T) {
+	temp := &TempFiles{dir: t.TempDir(), active: map[string]struct{}{}}
+
+	inUse, err := temp.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inUse.Close()
+	forgotten, err := temp.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgotten.Close()
+	temp.mu.Lock()
+	delete(temp.active, forgotten.Name())
+	temp.mu.Unlock()
+	other := filepath.Join(temp.dir, "keep.txt")
+	os.WriteFile(other, nil, 0o600)
+
+	// maxAge = 0: забытым считается любой файл, который сейчас не в работе
+	if removed := temp.Sweep(); removed != 1 {
+		t.Errorf("Sweep() removed %d files, want 1", removed)
+	}
+	for path, want := range map[string]bool{inUse.Name(): true, forgotten.Name(): false, other: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	temp.Remove(inUse.Name())
+	if _, err := os.Stat(inUse.Name()); !os.IsNotExist(err) {
+		t.Error("Remove() left the file")
+	}
+}
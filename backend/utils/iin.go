@@ -0,0 +1,45 @@
+// iin.go
+
+package utils
+
+// Виды 12-значных идентификаторов Казахстана
+const (
+	IdentifierIIN = "iin"
+	IdentifierBIN = "bin"
+)
+
+// ValidIIN проверяет контрольный разряд ИИН или БИН
+func ValidIIN(value string) bool {
+	if len(value) != 12 {
+		return false
+	}
+	digits := make([]int, 12)
+	for i := 0; i < 12; i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+		digits[i] = int(value[i] - '0')
+	}
+
+	control := func(weights []int) int {
+		sum := 0
+		for i, w := range weights {
+			sum += digits[i] * w
+		}
+		return sum % 11
+	}
+	c := control([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+	if c == 10 {
+		c = control([]int{3, 4, 5, 6, 7, 8, 9, 10, 11, 1, 2})
+	}
+	return c != 10 && c == digits[11]
+}
+
+// IdentifierKind отличает БИН от ИИН: у БИН пятая цифра — тип организации
+// (4, 5 или 6), у ИИН — первая цифра дня рождения
+func IdentifierKind(value string) string {
+	if len(value) == 12 && value[4] >= '4' && value[4] <= '6' {
+		return IdentifierBIN
+	}
+	return IdentifierIIN
+}
@@ -0,0 +1,152 @@
+// language.go
+
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Языки документов, которые различает анализ
+const (
+	LangRussian = "ru"
+	LangKazakh  = "kk"
+	LangEnglish = "en"
+)
+
+const (
+	languageWindowWords = 40
+	minWindowLetters    = 20
+	// Доля букв ә, ғ, қ, ң, ө, ұ, ү, һ, і среди кириллицы, начиная с которой фрагмент казахский
+	kazakhLetterShare = 0.02
+	// Доля второго языка, начиная с которой документ считается двуязычным
+	bilingualShare = 0.2
+)
+
+var (
+	kazakhLetters = "әғқңөұүһіӘҒҚҢӨҰҮҺІ"
+
+	kazakhStopwords = wordSet("және", "мен", "бен", "пен", "үшін", "бойынша", "туралы", "осы", "бұл",
+		"немесе", "тиіс", "болып", "табылады", "жөнінде", "арасында", "сәйкес", "екі", "тарап", "тараптар")
+	russianStopwords = wordSet("и", "в", "на", "не", "что", "с", "по", "для", "или", "от", "к", "при",
+		"о", "из", "это", "также", "является", "между", "согласно", "стороны")
+)
+
+// LanguageInfo — результат определения языка текста
+type LanguageInfo struct {
+	Code      string             `json:"code"`
+	Shares    map[string]float64 `json:"shares"`
+	Bilingual bool               `json:"bilingual"`
+}
+
+// SupportedLanguages возвращает коды языков, которые понимает анализ
+func SupportedLanguages() []string {
+	return []string{LangRussian, LangKazakh, LangEnglish}
+}
+
+// IsSupportedLanguage сообщает, что код языка поддерживается
+func IsSupportedLanguage(code string) bool {
+	for _, l := range SupportedLanguages() {
+		if l == code {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectLanguage определяет основной язык текста. Текст делится на фрагменты
+// по languageWindowWords слов, каждый фрагмент относится к одному языку,
+// доли языков считаются по числу букв. Так двуязычные документы, где русский
+// и казахский текст идут параллельно, получают обе доли.
+func DetectLanguage(text string) LanguageInfo {
+	words := strings.Fields(text)
+	letters := map[string]int{}
+	total := 0
+
+	for start := 0; start < len(words); start += languageWindowWords {
+		end := start + languageWindowWords
+		if end > len(words) {
+			end = len(words)
+		}
+		lang, n := windowLanguage(words[start:end])
+		if n < minWindowLetters {
+			continue
+		}
+		letters[lang] += n
+		total += n
+	}
+
+	info := LanguageInfo{Code: LangRussian, Shares: map[string]float64{}}
+	if total == 0 {
+		return info
+	}
+
+	var langs []string
+	for lang, n := range letters {
+		info.Shares[lang] = float64(n) / float64(total)
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool { return letters[langs[i]] > letters[langs[j]] })
+
+	info.Code = langs[0]
+	info.Bilingual = len(langs) > 1 && info.Shares[langs[1]] >= bilingualShare
+	return info
+}
+
+// documentLanguage определяет язык извлечённого текста и пишет в лог доли языков
+func documentLanguage(filename, text string) string {
+	info := DetectLanguage(text)
+	if info.Bilingual {
+		LogInfo(fmt.Sprintf("Документ %s двуязычный: %v, основной язык %s", filename, info.Shares, info.Code))
+	}
+	return info.Code
+}
+
+// windowLanguage определяет язык фрагмента и возвращает число букв в нём
+func windowLanguage(words []string) (string, int) {
+	var latin, cyrillic, kazakh, kkWords, ruWords int
+
+	for _, w := range words {
+		for _, r := range w {
+			switch {
+			case unicode.In(r, unicode.Cyrillic):
+				cyrillic++
+				if strings.ContainsRune(kazakhLetters, r) {
+					kazakh++
+				}
+			case unicode.In(r, unicode.Latin):
+				latin++
+			}
+		}
+
+		w = strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }))
+		if kazakhStopwords[w] {
+			kkWords++
+		}
+		if russianStopwords[w] {
+			ruWords++
+		}
+	}
+
+	n := latin + cyrillic
+	switch {
+	case latin > cyrillic:
+		return LangEnglish, n
+	case cyrillic > 0 && float64(kazakh)/float64(cyrillic) >= kazakhLetterShare:
+		return LangKazakh, n
+	case kkWords >= 2 && kkWords > ruWords:
+		return LangKazakh, n
+	default:
+		return LangRussian, n
+	}
+}
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
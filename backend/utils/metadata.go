@@ -0,0 +1,404 @@
+// metadata.go
+
+package utils
+
+import (
+	"legally/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Номер, город и дата подписания ищутся в шапке договора
+	metadataHeaderBytes = 2000
+	maxParties          = 6
+	maxAmounts          = 20
+	maxTermTextRunes    = 300
+)
+
+var (
+	monthNames = map[string]time.Month{
+		"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+		"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+		"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+		"қаңтар": time.January, "ақпан": time.February, "наурыз": time.March, "сәуір": time.April,
+		"мамыр": time.May, "маусым": time.June, "шілде": time.July, "тамыз": time.August,
+		"қыркүйек": time.September, "қазан": time.October, "қараша": time.November, "желтоқсан": time.December,
+		"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+		"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+		"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+	}
+
+	numericDateRe = regexp.MustCompile(`\b(\d{1,2})[./](\d{1,2})[./](\d{4})\b`)
+	isoDateRe     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	ruDateRe      = regexp.MustCompile(`(?i)[«"“]?(\d{1,2})[»"”]?\s+(января|февраля|марта|апреля|мая|июня|июля|августа|сентября|октября|ноября|декабря)\s+(\d{4})`)
+	kkDateRe      = regexp.MustCompile(`(?i)(\d{4})\s*(?:ж\.|жылғы)\s*[«"“]?(\d{1,2})[»"”]?\s*(қаңтар|ақпан|наурыз|сәуір|мамыр|маусым|шілде|тамыз|қыркүйек|қазан|қараша|желтоқсан)`)
+	enDateRe      = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(january|february|march|april|may|june|july|august|september|october|november|december),?\s+(\d{4})`)
+	enUSDateRe    = regexp.MustCompile(`(?i)\b(january|february|march|april|may|june|july|august|september|october|november|december)\s+(\d{1,2}),?\s+(\d{4})`)
+
+	contractNumberRe = regexp.MustCompile(`(?i)(?:договор|контракт|соглашени\p{L}*|шарт\p{L}*|agreement|contract)[^№\n]{0,80}?(?:№|No\.?)\s*([0-9A-Za-zА-Яа-я][0-9A-Za-zА-Яа-я\-/._]*)`)
+	cityRe           = regexp.MustCompile(`(?:^|[^\p{L}])(?:г\.|город|гор\.)\s*(\p{Lu}[\p{L}\-]+)`)
+	kkCityRe         = regexp.MustCompile(`(\p{Lu}[\p{L}\-]+)\s+(?:қ\.|қаласы)`)
+	enCityRe         = regexp.MustCompile(`\bCity of ([A-Z][A-Za-z\-]+)`)
+
+	termRe     = regexp.MustCompile(`(?i)срок\p{L}*\s+действия|действует\s+до|вступает\s+в\s+силу|сроком\s+на|қолданылу\s+мерзімі|күшіне\s+енеді|term\s+of\s+(?:this\s+)?(?:agreement|contract)|remain\s+in\s+(?:full\s+)?force`)
+	durationRe = regexp.MustCompile(`(?i)(\d+)\s*(?:\([^)]{0,60}\)\s*)?(календарных\s+дн\p{L}*|рабочих\s+дн\p{L}*|лет|год\p{L}*|месяц\p{L}*|дн\p{L}*|день|жыл\p{L}*|ай|күн\p{L}*|years?|months?|days?)`)
+	untilRe    = regexp.MustCompile(`(?i)(?:до|по|until|till|through|to)\s*$`)
+
+	amountRe = regexp.MustCompile(`(?:^|[^\d.,])(\d{1,3}(?:[ \x{00A0}]\d{3})+|\d+)(?:[,.](\d{1,2}))?\s*(?:\([^)]{0,200}\)\s*)?` +
+		`(тенге|теңге|тг\.?|₸|KZT|рубл\p{L}*|руб\.|₽|RUB|доллар\p{L}*(?:\s+США)?|USD|\$|евро|EUR|€)`)
+	amountPrefixRe = regexp.MustCompile(`(KZT|USD|EUR|RUB|\$|€|₸)\s?(\d{1,3}(?:[ ,\x{00A0}]\d{3})+|\d+)(?:\.(\d{1,2}))?`)
+
+	labeledIDRe = regexp.MustCompile(`(?:ИИН|БИН|ЖСН|БСН|IIN|BIN)[\s:№/]*(\d{12})\b`)
+	bareIDRe    = regexp.MustCompile(`\b\d{12}\b`)
+
+	partyMarkerRe = regexp.MustCompile(`(?i:именуем\p{L}*\s+(?:в\s+дальнейшем\s+)?|далее\s*(?:по\s+тексту\s*)?[-–—:]?\s*|бұдан\s+әрі\s*[-–—]?\s*|hereinafter\s+(?:referred\s+to\s+as\s+)?(?:the\s+)?)` +
+		`(?:[«"“]([^»"”\n]{2,40})[»"”]|(\p{Lu}[\p{L}\-]{2,}))`)
+	partyOrgRe = regexp.MustCompile(`(?:^|[^\p{L}])(?:ТОО|АО|ИП|ОО|ПК|ГУ|РГП|ГКП|КГП|ЖШС|АҚ|ЖК|LLP|LLC|JSC|Ltd|Inc|GmbH)(?:[^\p{L}]|$)|[«"“]`)
+
+	// Пункт договора («1.1. Арендодатель передаёт Помещение (далее — Помещение)»)
+	// вводит термин, а не сторону
+	partyClauseRe = regexp.MustCompile(`^\d{1,2}(?:\.\d{1,2})*\.?\s`)
+
+	// «Далее — Договор» и подобные ссылки вводят не стороны, а термины
+	notPartyRoleRe = regexp.MustCompile(`(?i)^(?:договор\p{L}*|соглашени\p{L}*|контракт\p{L}*|сторон\p{L}*|шарт\p{L}*|келісім\p{L}*|тарап\p{L}*|agreement|contract|part(?:y|ies))$`)
+)
+
+// ExtractContractMetadata извлекает реквизиты договора из текста без обращения к модели.
+// Возвращает nil, если ничего не найдено.
+func ExtractContractMetadata(text string) *models.ContractMetadata {
+	header := truncateBytes(text, metadataHeaderBytes)
+
+	meta := &models.ContractMetadata{
+		Number:      contractNumber(header),
+		City:        contractCity(header),
+		Term:        contractTerm(text),
+		Amounts:     extractAmounts(text),
+		Identifiers: extractIdentifiers(text),
+	}
+	if dates := findDates(header); len(dates) > 0 {
+		meta.SignedAt = &dates[0].t
+	}
+	meta.Parties = extractParties(text)
+
+	if meta.IsEmpty() {
+		return nil
+	}
+	return meta
+}
+
+type dateMatch struct {
+	t          time.Time
+	start, end int
+}
+
+// findDates находит даты в числовом, русском, казахском и английском написании в порядке следования
+func findDates(text string) []dateMatch {
+	var dates []dateMatch
+	add := func(m []int, year, month, day string, monthName bool) {
+		y, _ := strconv.Atoi(year)
+		d, _ := strconv.Atoi(day)
+		var mo time.Month
+		if monthName {
+			mo = monthNames[strings.ToLower(month)]
+		} else {
+			n, _ := strconv.Atoi(month)
+			mo = time.Month(n)
+		}
+		if mo < time.January || mo > time.December || d < 1 || y < 1900 || y > 2200 {
+			return
+		}
+		t := time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+		if t.Day() != d {
+			return
+		}
+		dates = append(dates, dateMatch{t: t, start: m[0], end: m[1]})
+	}
+
+	for _, m := range numericDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[6]:m[7]], text[m[4]:m[5]], text[m[2]:m[3]], false)
+	}
+	for _, m := range isoDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[2]:m[3]], text[m[4]:m[5]], text[m[6]:m[7]], false)
+	}
+	for _, m := range ruDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[6]:m[7]], text[m[4]:m[5]], text[m[2]:m[3]], true)
+	}
+	for _, m := range kkDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[2]:m[3]], text[m[6]:m[7]], text[m[4]:m[5]], true)
+	}
+	for _, m := range enDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[6]:m[7]], text[m[4]:m[5]], text[m[2]:m[3]], true)
+	}
+	for _, m := range enUSDateRe.FindAllStringSubmatchIndex(text, -1) {
+		add(m, text[m[6]:m[7]], text[m[2]:m[3]], text[m[4]:m[5]], true)
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].start < dates[j].start })
+	return dates
+}
+
+func contractNumber(header string) string {
+	m := contractNumberRe.FindStringSubmatch(header)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimRight(m[1], "._-/")
+}
+
+func contractCity(header string) string {
+	for _, re := range []*regexp.Regexp{cityRe, kkCityRe, enCityRe} {
+		if m := re.FindStringSubmatch(header); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// contractTerm берёт первую фразу о сроке действия, из которой удаётся извлечь даты или длительность
+func contractTerm(text string) *models.ContractTerm {
+	for _, loc := range termRe.FindAllStringIndex(text, -1) {
+		phrase := text[loc[0]:]
+		if end := strings.IndexAny(phrase, "\n;"); end >= 0 {
+			phrase = phrase[:end]
+		}
+		phrase = truncateRunes(phrase, maxTermTextRunes)
+
+		term := &models.ContractTerm{Text: strings.TrimSpace(phrase)}
+		dates := findDates(phrase)
+		switch {
+		case len(dates) >= 2:
+			term.From, term.Until = &dates[0].t, &dates[1].t
+		case len(dates) == 1:
+			before := phrase[:dates[0].start]
+			after := phrase[dates[0].end:]
+			if untilRe.MatchString(strings.TrimRight(before, " «\"")) || strings.HasPrefix(strings.TrimSpace(after), "дейін") {
+				term.Until = &dates[0].t
+			} else {
+				term.From = &dates[0].t
+			}
+		}
+		// Год в дате («31.12.2024 года») не должен читаться как длительность
+		undated := phrase
+		for i := len(dates) - 1; i >= 0; i-- {
+			undated = undated[:dates[i].start] + undated[dates[i].end:]
+		}
+		if m := durationRe.FindStringSubmatch(undated); m != nil {
+			term.Duration = m[1] + " " + strings.ToLower(m[2])
+		}
+
+		if term.From != nil || term.Until != nil || term.Duration != "" {
+			return term
+		}
+	}
+	return nil
+}
+
+// extractAmounts находит денежные суммы с валютой; одинаковые суммы не повторяются
+func extractAmounts(text string) []models.Amount {
+	var amounts []models.Amount
+	seen := map[string]bool{}
+	add := func(whole, fraction, currency, raw string) {
+		value, err := strconv.ParseFloat(strings.NewReplacer(" ", "", " ", "", ",", "").Replace(whole)+"."+fraction+"0", 64)
+		if err != nil || value == 0 {
+			return
+		}
+		code := currencyCode(currency)
+		key := code + strconv.FormatFloat(value, 'f', 2, 64)
+		if seen[key] || len(amounts) >= maxAmounts {
+			return
+		}
+		seen[key] = true
+		amounts = append(amounts, models.Amount{Value: value, Currency: code, Text: strings.TrimSpace(raw)})
+	}
+
+	for _, m := range amountRe.FindAllStringSubmatchIndex(text, -1) {
+		fraction := ""
+		if m[4] >= 0 {
+			fraction = text[m[4]:m[5]]
+		}
+		add(text[m[2]:m[3]], fraction, text[m[6]:m[7]], text[m[2]:m[7]])
+	}
+	for _, m := range amountPrefixRe.FindAllStringSubmatchIndex(text, -1) {
+		fraction := ""
+		if m[6] >= 0 {
+			fraction = text[m[6]:m[7]]
+		}
+		add(text[m[4]:m[5]], fraction, text[m[2]:m[3]], text[m[0]:m[1]])
+	}
+	return amounts
+}
+
+func currencyCode(currency string) string {
+	c := strings.ToLower(currency)
+	switch {
+	case strings.HasPrefix(c, "тенге"), strings.HasPrefix(c, "теңге"), strings.HasPrefix(c, "тг"), c == "₸", c == "kzt":
+		return "KZT"
+	case strings.HasPrefix(c, "руб"), c == "₽", c == "rub":
+		return "RUB"
+	case strings.HasPrefix(c, "доллар"), c == "$", c == "usd":
+		return "USD"
+	case c == "евро", c == "€", c == "eur":
+		return "EUR"
+	default:
+		return strings.ToUpper(currency)
+	}
+}
+
+// extractIdentifiers собирает ИИН/БИН: подписанные — всегда, без подписи — только с верным контрольным разрядом
+func extractIdentifiers(text string) []models.Identifier {
+	var ids []models.Identifier
+	seen := map[string]bool{}
+	add := func(value string) {
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		ids = append(ids, models.Identifier{Kind: IdentifierKind(value), Value: value})
+	}
+
+	for _, m := range labeledIDRe.FindAllStringSubmatch(text, -1) {
+		add(m[1])
+	}
+	for _, value := range bareIDRe.FindAllString(text, -1) {
+		if ValidIIN(value) {
+			add(value)
+		}
+	}
+	return ids
+}
+
+// extractParties находит стороны по оборотам «именуемое в дальнейшем», «далее —»,
+// «бұдан әрі», «hereinafter». Имя стороны — фрагмент перед оборотом,
+// ИИН/БИН — в её части преамбулы или в реквизитах после названия роли.
+func extractParties(text string) []models.Party {
+	markers := partyMarkerRe.FindAllStringSubmatchIndex(text, -1)
+
+	var parties []models.Party
+	seen := map[string]bool{}
+	for i, m := range markers {
+		role := ""
+		if m[2] >= 0 {
+			role = text[m[2]:m[3]]
+		} else {
+			role = text[m[4]:m[5]]
+		}
+		role = strings.TrimSpace(role)
+		if !isPartyRole(role) || seen[strings.ToLower(role)] {
+			continue
+		}
+
+		name, nameStart := partyName(text, m[0])
+		if name == "" || partyClauseRe.MatchString(name) || !partyOrgRe.MatchString(name) && countCapitalized(name) < 2 {
+			continue
+		}
+		seen[strings.ToLower(role)] = true
+
+		party := models.Party{Name: name, Role: role}
+		segmentEnd := len(text)
+		if i+1 < len(markers) {
+			segmentEnd = markers[i+1][0]
+		}
+		party.ID = partyIdentifier(text, nameStart, segmentEnd, role)
+		if party.ID != "" {
+			party.IDKind = IdentifierKind(party.ID)
+		}
+
+		parties = append(parties, party)
+		if len(parties) == maxParties {
+			break
+		}
+	}
+	return parties
+}
+
+func isPartyRole(role string) bool {
+	return !notPartyRoleRe.MatchString(role)
+}
+
+// partyName возвращает название стороны, стоящее перед оборотом с её ролью, и его смещение
+func partyName(text string, markerStart int) (string, int) {
+	start := markerStart - 300
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	seg := text[start:markerStart]
+
+	// Начало фрагмента: новая строка, «;» или окончание предыдущей стороны
+	for _, sep := range []string{"\n", ";", "стороны,", ", и ", "жағынан,", "және ", "and "} {
+		if i := strings.LastIndex(seg, sep); i >= 0 {
+			start += i + len(sep)
+			seg = seg[i+len(sep):]
+		}
+	}
+
+	lead := len(seg)
+	seg = strings.TrimLeftFunc(seg, unicode.IsSpace)
+	for _, prefix := range []string{"и ", "Между ", "между ", "between ", "Between ", "Мы, "} {
+		seg = strings.TrimPrefix(seg, prefix)
+	}
+	start += lead - len(seg)
+
+	seg = strings.TrimRight(seg, " \t,(—–-")
+	for _, cut := range []string{",", " в лице ", " действующ", " (", " ИИН", " БИН"} {
+		if i := strings.Index(seg, cut); i > 0 {
+			seg = seg[:i]
+		}
+	}
+	return strings.TrimSpace(seg), start
+}
+
+// partyIdentifier ищет ИИН/БИН стороны в её части преамбулы, затем в реквизитах после названия роли
+func partyIdentifier(text string, start, end int, role string) string {
+	if m := labeledIDRe.FindStringSubmatch(text[start:end]); m != nil {
+		return m[1]
+	}
+
+	roleRe, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(role))
+	if err != nil {
+		return ""
+	}
+	if locs := roleRe.FindAllStringIndex(text[end:], -1); len(locs) > 0 {
+		block := truncateBytes(text[end+locs[len(locs)-1][0]:], 600)
+		if m := labeledIDRe.FindStringSubmatch(block); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func countCapitalized(s string) int {
+	n := 0
+	for _, w := range strings.Fields(s) {
+		if r, _ := utf8.DecodeRuneInString(w); unicode.IsUpper(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// truncateBytes обрезает строку до n байт, не разрывая символ
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+const testLeaseContract = `ДОГОВОР АРЕНДЫ № 15/2024-А
+г. Алматы «12» марта 2024 г.
+
+ТОО «Ромашка», БИН 050140000011, именуемое в дальнейшем «Арендодатель», в лице директора Иванова И.И., действующего на основании Устава, с одной стороны, и
+Петров Пётр Петрович, именуемый в дальнейшем «Арендатор», с другой стороны, заключили настоящий договор о нижеследующем.
+
+1. ПРЕДМЕТ ДОГОВОРА
+1.1. Арендодатель передаёт Арендатору нежилое помещение (далее — Помещение).
+
+2. ЦЕНА
+2.1. Арендная плата составляет 350 000 (триста пятьдесят тысяч) тенге в месяц.
+2.2. Обеспечительный платёж — 700 000,50 тенге; штраф — USD 1,500.
+2.3. Повторно: 350 000 тенге.
+
+3. СРОК
+3.1. Срок действия договора — до 31.12.2024 года.
+
+РЕКВИЗИТЫ
+Арендатор: ИИН 900101300126`
+
+func TestExtractContractMetadata(t *testing.T) {
+	meta := ExtractContractMetadata(testLeaseContract)
+	if meta == nil {
+		t.Fatal("ExtractContractMetadata() = nil")
+	}
+
+	if meta.Number != "15/2024-А" {
+		t.Errorf("number = %q", meta.Number)
+	}
+	if meta.City != "Алматы" {
+		t.Errorf("city = %q", meta.City)
+	}
+	if meta.SignedAt == nil || !meta.SignedAt.Equal(time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("signed at = %v", meta.SignedAt)
+	}
+	if meta.Term == nil || meta.Term.Until == nil || meta.Term.Until.Format("2006-01-02") != "2024-12-31" || meta.Term.From != nil || meta.Term.Duration != "" {
+		t.Errorf("term = %+v", meta.Term)
+	}
+
+	wantAmounts := []struct {
+		value    float64
+		currency string
+	}{{350000, "KZT"}, {700000.5, "KZT"}, {1500, "USD"}}
+	if len(meta.Amounts) != len(wantAmounts) {
+		t.Fatalf("amounts = %+v", meta.Amounts)
+	}
+	for i, want := range wantAmounts {
+		if got := meta.Amounts[i]; got.Value != want.value || got.Currency != want.currency {
+			t.Errorf("amount %d = %+v, want %v %s", i, got, want.value, want.currency)
+		}
+	}
+
+	if len(meta.Parties) != 2 {
+		t.Fatalf("parties = %+v", meta.Parties)
+	}
+	if p := meta.Parties[0]; p.Name != "ТОО «Ромашка»" || p.Role != "Арендодатель" || p.ID != "050140000011" || p.IDKind != IdentifierBIN {
+		t.Errorf("lessor = %+v", p)
+	}
+	// ИИН арендатора берётся из реквизитов после названия роли
+	if p := meta.Parties[1]; p.Name != "Петров Пётр Петрович" || p.Role != "Арендатор" || p.ID != "900101300126" || p.IDKind != IdentifierIIN {
+		t.Errorf("lessee = %+v", p)
+	}
+
+	if len(meta.Identifiers) != 2 {
+		t.Errorf("identifiers = %+v", meta.Identifiers)
+	}
+}
+
+func TestExtractContractMetadataLanguages(t *testing.T) {
+	t.Run("kk", func(t *testing.T) {
+		meta := ExtractContractMetadata("ЖАЛДАУ ШАРТЫ № 7\nАстана қаласы 2024 жылғы «5» сәуір\nШарттың қолданылу мерзімі 11 ай.")
+		if meta == nil || meta.Number != "7" || meta.City != "Астана" {
+			t.Fatalf("metadata = %+v", meta)
+		}
+		if meta.SignedAt == nil || meta.SignedAt.Format("2006-01-02") != "2024-04-05" {
+			t.Errorf("signed at = %v", meta.SignedAt)
+		}
+		if meta.Term == nil || meta.Term.Duration != "11 ай" {
+			t.Errorf("term = %+v", meta.Term)
+		}
+	})
+
+	t.Run("en", func(t *testing.T) {
+		meta := ExtractContractMetadata("SERVICES AGREEMENT No. SA-2024/03\nCity of Almaty, March 3, 2024\nThe term of this Agreement is 2 years from 01.04.2024.")
+		if meta == nil || meta.Number != "SA-2024/03" || meta.City != "Almaty" {
+			t.Fatalf("metadata = %+v", meta)
+		}
+		if meta.SignedAt == nil || meta.SignedAt.Format("2006-01-02") != "2024-03-03" {
+			t.Errorf("signed at = %v", meta.SignedAt)
+		}
+		if meta.Term == nil || meta.Term.Duration != "2 years" || meta.Term.From == nil || meta.Term.From.Format("2006-01-02") != "2024-04-01" {
+			t.Errorf("term = %+v", meta.Term)
+		}
+	})
+}
+
+func TestExtractContractMetadataNothingFound(t *testing.T) {
+	if meta := ExtractContractMetadata("Просто заметка без реквизитов. Номер телефона 123456789012 не является ИИН."); meta != nil {
+		t.Errorf("ExtractContractMetadata() = %+v, want nil", meta)
+	}
+}
+
+func TestFindDates(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"31.12.2024", "2024-12-31"},
+		{"2024-02-29", "2024-02-29"},
+		{"«1» января 2025 года", "2025-01-01"},
+		{"2023 ж. 15 қазан", "2023-10-15"},
+		{"1st June, 2024", "2024-06-01"},
+		{"31.02.2024", ""},
+		{"12.13.2024", ""},
+	}
+	for _, tt := range tests {
+		dates := findDates(tt.text)
+		got := ""
+		if len(dates) > 0 {
+			got = dates[0].t.Format("2006-01-02")
+		}
+		if got != tt.want {
+			t.Errorf("findDates(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// splitter.go
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultSplitMaxTokens     = 4000
+	defaultSplitOverlapTokens = 200
+	maxSectionTitleRunes      = 120
+	// Для кириллицы токенизаторы дают примерно токен на 3 символа
+	runesPerToken = 3
+)
+
+// SplitOptions — параметры разбиения документа
+type SplitOptions struct {
+	MaxTokens     int
+	OverlapTokens int
+}
+
+// DefaultSplitOptions читает SPLIT_MAX_TOKENS и SPLIT_OVERLAP_TOKENS
+func DefaultSplitOptions() SplitOptions {
+	return SplitOptions{
+		MaxTokens:     GetEnvInt("SPLIT_MAX_TOKENS", defaultSplitMaxTokens),
+		OverlapTokens: GetEnvInt("SPLIT_OVERLAP_TOKENS", defaultSplitOverlapTokens),
+	}
+}
+
+// DocumentPart — часть документа для анализа.
+// Start и End — смещения в символах исходного текста без учёта перекрытия.
+type DocumentPart struct {
+	Index    int
+	Text     string
+	Sections []string
+	Start    int
+	End      int
+}
+
+type blockKind int
+
+const (
+	blockText blockKind = iota
+	blockHeading
+	blockClause
+)
+
+type textBlock struct {
+	kind  blockKind
+	title string
+	start int
+	end   int
+}
+
+var (
+	// "Статья 15.", "Статья 15-1. Название"
+	articleHeadingRe = regexp.MustCompile(`(?:^|\s)((?:Статья|СТАТЬЯ)\s+\d+(?:-\d+)?\.?)`)
+	// "Раздел 2", "ГЛАВА IV"
+	chapterHeadingRe = regexp.MustCompile(`(?:^|\s)((?:Раздел|РАЗДЕЛ|Глава|ГЛАВА)\s+[\dIVXLC]+\.?)`)
+	// "1. ПРЕДМЕТ ДОГОВОРА" — нумерованный заголовок прописными буквами
+	upperHeadingRe = regexp.MustCompile(`(?:^|\s)(\d{1,2}\.\s*(?:[А-ЯЁA-Z]{2,}[ ,\-]*)+)`)
+	// "1. Предмет договора 1.1. ..." — заголовок раздела, за которым идёт его первый пункт
+	numberedSectionRe = regexp.MustCompile(`(?:^|\s)((\d{1,2})\.\s*[А-ЯЁA-Z][^.\d\n]{2,80}?)\s+(\d{1,2})\.1(?:\.|\s)`)
+	// пункты "2.1.", "3.2.1"
+	clauseRe = regexp.MustCompile(`(?:^|\s)(\d{1,2}\.\d{1,2}(?:\.\d{1,2})?\.?)\s`)
+	// конец предложения для разбиения слишком длинных блоков
+	sentenceEndRe = regexp.MustCompile(`[.!?;]\s`)
+)
+
+// SplitDocument разбивает текст по структуре документа: разделам, статьям и пунктам.
+// Части укладываются в бюджет токенов, соседние части перекрываются,
+// а каждая часть несёт заголовки разделов, к которым относится.
+func SplitDocument(text string, opts SplitOptions) []DocumentPart {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultSplitMaxTokens
+	}
+	if opts.OverlapTokens < 0 || opts.OverlapTokens >= opts.MaxTokens {
+		opts.OverlapTokens = 0
+	}
+	LogAction(fmt.Sprintf("Разделение текста по структуре (бюджет %d токенов, перекрытие %d)", opts.MaxTokens, opts.OverlapTokens))
+
+	// Части после первой начинаются с перекрытия, поэтому их собственный текст
+	// укладывается в бюджет за вычетом OverlapTokens
+	bodyTokens := opts.MaxTokens - opts.OverlapTokens
+	blocks := splitOversized(text, findBlocks(text), bodyTokens*runesPerToken)
+
+	var parts []DocumentPart
+	var sections []string
+	currentSection := ""
+	partStart, partEnd := -1, -1
+
+	flush := func() {
+		if partStart < 0 {
+			return
+		}
+		body := strings.TrimSpace(text[partStart:partEnd])
+		if body != "" {
+			parts = append(parts, DocumentPart{
+				Index:    len(parts),
+				Text:     body,
+				Sections: sections,
+				Start:    utf8.RuneCountInString(text[:partStart]),
+				End:      utf8.RuneCountInString(text[:partEnd]),
+			})
+		}
+		partStart, partEnd = -1, -1
+		sections = nil
+	}
+
+	for _, b := range blocks {
+		budget := opts.MaxTokens
+		if len(parts) > 0 {
+			budget = bodyTokens
+		}
+		if partStart >= 0 && estimateTokens(text[partStart:b.end]) > budget {
+			flush()
+		}
+		if partStart < 0 {
+			partStart = b.start
+			if currentSection != "" && b.kind != blockHeading {
+				sections = append(sections, currentSection)
+			}
+		}
+		if b.kind == blockHeading {
+			currentSection = b.title
+			if len(sections) == 0 || sections[len(sections)-1] != b.title {
+				sections = append(sections, b.title)
+			}
+		}
+		partEnd = b.end
+	}
+	flush()
+
+	if opts.OverlapTokens > 0 {
+		for i := len(parts) - 1; i > 0; i-- {
+			if tail := overlapTail(parts[i-1].Text, opts.OverlapTokens); tail != "" {
+				parts[i].Text = tail + "\n" + parts[i].Text
+			}
+		}
+	}
+
+	LogInfo(fmt.Sprintf("Текст разделен на %d частей", len(parts)))
+	return parts
+}
+
+// findBlocks находит границы заголовков и пунктов и режет текст на блоки между ними
+func findBlocks(text string) []textBlock {
+	type boundary struct {
+		pos   int
+		kind  blockKind
+		title string
+	}
+
+	var bounds []boundary
+	addHeadings := func(re *regexp.Regexp) {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			bounds = append(bounds, boundary{pos: m[2], kind: blockHeading, title: headingTitle(text, m[2], m[3])})
+		}
+	}
+	addHeadings(articleHeadingRe)
+	addHeadings(chapterHeadingRe)
+	addHeadings(upperHeadingRe)
+	for _, m := range numberedSectionRe.FindAllStringSubmatchIndex(text, -1) {
+		if text[m[4]:m[5]] != text[m[6]:m[7]] {
+			continue
+		}
+		title := strings.Join(strings.Fields(text[m[2]:m[3]]), " ")
+		bounds = append(bounds, boundary{pos: m[2], kind: blockHeading, title: title})
+	}
+	for _, m := range clauseRe.FindAllStringSubmatchIndex(text, -1) {
+		bounds = append(bounds, boundary{pos: m[2], kind: blockClause})
+	}
+
+	sort.SliceStable(bounds, func(i, j int) bool {
+		if bounds[i].pos != bounds[j].pos {
+			return bounds[i].pos < bounds[j].pos
+		}
+		return bounds[i].kind < bounds[j].kind
+	})
+
+	var blocks []textBlock
+	prev := textBlock{kind: blockText, start: 0}
+	for i, b := range bounds {
+		if i > 0 && b.pos == bounds[i-1].pos {
+			continue
+		}
+		if b.pos > prev.start {
+			prev.end = b.pos
+			blocks = append(blocks, prev)
+		}
+		prev = textBlock{kind: b.kind, title: b.title, start: b.pos}
+	}
+	prev.end = len(text)
+	if prev.end > prev.start {
+		blocks = append(blocks, prev)
+	}
+
+	return blocks
+}
+
+// DocumentHeadings возвращает заголовки разделов и статей документа в порядке следования
+func DocumentHeadings(text string) []string {
+	var headings []string
+	for _, b := range findBlocks(text) {
+		if b.kind == blockHeading && b.title != "" {
+			headings = append(headings, b.title)
+		}
+	}
+	return headings
+}
+
+// headingTitle берёт заголовок вместе с названием, например "Статья 5. Срок аренды"
+func headingTitle(text string, start, end int) string {
+	rest := text[end:]
+	if i := strings.IndexAny(rest, "\n"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := sentenceEndRe.FindStringIndex(rest); i != nil {
+		rest = rest[:i[0]+1]
+	}
+
+	title := strings.Join(strings.Fields(text[start:end]+rest), " ")
+	if utf8.RuneCountInString(title) > maxSectionTitleRunes {
+		title = string([]rune(title)[:maxSectionTitleRunes]) + "…"
+	}
+	return strings.TrimRight(title, ".,; ")
+}
+
+// splitOversized дробит блоки, которые сами по себе не помещаются в бюджет:
+// сначала по концам предложений, затем по словам
+func splitOversized(text string, blocks []textBlock, maxRunes int) []textBlock {
+	var out []textBlock
+	for _, b := range blocks {
+		if utf8.RuneCountInString(text[b.start:b.end]) <= maxRunes {
+			out = append(out, b)
+			continue
+		}
+
+		first := true
+		pos := b.start
+		for pos < b.end {
+			end := cutPoint(text, pos, b.end, maxRunes)
+			piece := textBlock{kind: blockText, start: pos, end: end}
+			if first {
+				piece.kind, piece.title = b.kind, b.title
+				first = false
+			}
+			out = append(out, piece)
+			pos = end
+		}
+	}
+	return out
+}
+
+// cutPoint ищет место разреза не дальше maxRunes символов от start
+func cutPoint(text string, start, end, maxRunes int) int {
+	limit := start
+	for n := 0; limit < end && n < maxRunes; n++ {
+		_, size := utf8.DecodeRuneInString(text[limit:])
+		limit += size
+	}
+	if limit >= end {
+		return end
+	}
+
+	window := text[start:limit]
+	if locs := sentenceEndRe.FindAllStringIndex(window, -1); len(locs) > 0 {
+		if cut := start + locs[len(locs)-1][1]; cut-start > len(window)/2 {
+			return cut
+		}
+	}
+	if i := strings.LastIndexAny(window, " \n\t"); i > len(window)/2 {
+		return start + i + 1
+	}
+	return limit
+}
+
+// overlapTail возвращает конец части длиной примерно tokens токенов, начиная с целого слова
+func overlapTail(text string, tokens int) string {
+	runes := []rune(text)
+	n := tokens * runesPerToken
+	if n >= len(runes) {
+		return ""
+	}
+
+	tail := string(runes[len(runes)-n:])
+	if i := strings.IndexAny(tail, " \n\t"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return strings.TrimSpace(tail)
+}
+
+// TruncateTokens обрезает текст примерно до maxTokens токенов по концу
+// предложения или слова; maxTokens <= 0 — без ограничения
+func TruncateTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 || estimateTokens(text) <= maxTokens {
+		return text
+	}
+	return strings.TrimSpace(text[:cutPoint(text, 0, len(text), maxTokens*runesPerToken)])
+}
+
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + runesPerToken - 1) / runesPerToken
+}
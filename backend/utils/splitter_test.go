@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const testContract = `ДОГОВОР АРЕНДЫ
+
+1. ПРЕДМЕТ ДОГОВОРА
+1.1. Арендодатель передаёт Арендатору помещение.
+1.2. Помещение используется под офис.
+
+2. АРЕНДНАЯ ПЛАТА
+2.1. Арендная плата составляет 100 000 тенге в месяц.
+2.2. Плата вносится до пятого числа.
+
+Статья 3. Ответственность сторон
+За просрочку платы начисляется пеня.`
+
+func TestDocumentHeadings(t *testing.T) {
+	want := []string{"1. ПРЕДМЕТ ДОГОВОРА", "2. АРЕНДНАЯ ПЛАТА", "Статья 3. Ответственность сторон"}
+	if got := DocumentHeadings(testContract); !reflect.DeepEqual(got, want) {
+		t.Errorf("DocumentHeadings() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitDocument(t *testing.T) {
+	t.Run("fits in one part", func(t *testing.T) {
+		parts := SplitDocument(testContract, SplitOptions{MaxTokens: 4000})
+		if len(parts) != 1 {
+			t.Fatalf("got %d parts, want 1", len(parts))
+		}
+		if parts[0].Text != strings.TrimSpace(testContract) || parts[0].Start != 0 {
+			t.Errorf("part = %+v", parts[0])
+		}
+	})
+
+	t.Run("splits on structure", func(t *testing.T) {
+		parts := SplitDocument(testContract, SplitOptions{MaxTokens: 50})
+		if len(parts) < 2 {
+			t.Fatalf("got %d parts, want several", len(parts))
+		}
+		runes := []rune(testContract)
+		for i, p := range parts {
+			if p.Index != i {
+				t.Errorf("part %d has index %d", i, p.Index)
+			}
+			if estimateTokens(p.Text) > 50 {
+				t.Errorf("part %d is over budget: %d tokens", i, estimateTokens(p.Text))
+			}
+			if got := strings.TrimSpace(string(runes[p.Start:p.End])); got != p.Text {
+				t.Errorf("part %d offsets [%d:%d] give %q, want %q", i, p.Start, p.End, got, p.Text)
+			}
+			if i > 0 && p.Start < parts[i-1].End {
+				t.Errorf("part %d overlaps part %d without overlap budget", i, i-1)
+			}
+		}
+		last := parts[len(parts)-1]
+		if len(last.Sections) == 0 || last.Sections[len(last.Sections)-1] != "Статья 3. Ответственность сторон" {
+			t.Errorf("last part sections = %q", last.Sections)
+		}
+	})
+
+	t.Run("overlap repeats the previous tail", func(t *testing.T) {
+		parts := SplitDocument(testContract, SplitOptions{MaxTokens: 50, OverlapTokens: 5})
+		if len(parts) < 2 {
+			t.Fatalf("got %d parts, want several", len(parts))
+		}
+		for i := 1; i < len(parts); i++ {
+			first, _, _ := strings.Cut(parts[i].Text, "\n")
+			if !strings.HasSuffix(parts[i-1].Text, first) {
+				t.Errorf("part %d does not start with the tail of part %d: %q", i, i-1, first)
+			}
+		}
+	})
+
+	t.Run("overlap stays within the budget", func(t *testing.T) {
+		long := strings.Repeat("Арендатор обязан содержать помещение в порядке. ", 40)
+		for _, text := range []string{testContract, long} {
+			parts := SplitDocument(text, SplitOptions{MaxTokens: 60, OverlapTokens: 20})
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want several", len(parts))
+			}
+			for i, p := range parts {
+				if n := estimateTokens(p.Text); n > 60 {
+					t.Errorf("part %d has %d tokens with overlap, want at most 60", i, n)
+				}
+			}
+		}
+	})
+
+	t.Run("oversized paragraph is cut by sentences", func(t *testing.T) {
+		long := strings.Repeat("Арендатор обязан содержать помещение в порядке. ", 40)
+		parts := SplitDocument(long, SplitOptions{MaxTokens: 100})
+		if len(parts) < 2 {
+			t.Fatalf("got %d parts, want several", len(parts))
+		}
+		for i, p := range parts {
+			if utf8.RuneCountInString(p.Text) > 100*runesPerToken {
+				t.Errorf("part %d has %d runes", i, utf8.RuneCountInString(p.Text))
+			}
+			if !strings.HasSuffix(p.Text, ".") {
+				t.Errorf("part %d is not cut on a sentence end: %q", i, p.Text)
+			}
+		}
+	})
+}
+
+func TestTruncateTokens(t *testing.T) {
+	text := strings.Repeat("Арендатор вносит плату. ", 100)
+
+	if got := TruncateTokens(text, 0); got != text {
+		t.Error("TruncateTokens(text, 0) changed the text")
+	}
+	if got := TruncateTokens("короткий текст", 100); got != "короткий текст" {
+		t.Errorf("TruncateTokens() = %q", got)
+	}
+
+	got := TruncateTokens(text, 50)
+	if estimateTokens(got) > 50 {
+		t.Errorf("truncated text has %d tokens, want at most 50", estimateTokens(got))
+	}
+	if !strings.HasPrefix(text, got) || !strings.HasSuffix(got, ".") {
+		t.Errorf("TruncateTokens() = %q, want a prefix cut on a sentence end", got)
+	}
+}
@@ -0,0 +1,139 @@
+// tempfiles.go
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTempDir           = "./temp"
+	defaultTempMaxAgeMin     = 60
+	defaultTempSweepEveryMin = 10
+)
+
+// TempFiles выдаёт временные файлы со случайными именами в одном каталоге,
+// помнит, какие из них сейчас в работе, и периодически удаляет забытые
+type TempFiles struct {
+	dir    string
+	maxAge time.Duration
+
+	mu     sync.Mutex
+	active map[string]struct{}
+}
+
+var (
+	tempFiles     *TempFiles
+	tempFilesOnce sync.Once
+)
+
+// Temp возвращает общий менеджер временных файлов (TEMP_DIR, TEMP_MAX_AGE_MIN)
+func Temp() *TempFiles {
+	tempFilesOnce.Do(func() {
+		maxAge := GetEnvInt("TEMP_MAX_AGE_MIN", defaultTempMaxAgeMin)
+		if maxAge <= 0 {
+			maxAge = defaultTempMaxAgeMin
+		}
+		tempFiles = &TempFiles{
+			dir:    GetEnv("TEMP_DIR", defaultTempDir),
+			maxAge: time.Duration(maxAge) * time.Minute,
+			active: make(map[string]struct{}),
+		}
+	})
+	return tempFiles
+}
+
+// InitTempFiles создаёт каталог временных файлов, удаляет оставшиеся
+// от прошлых запусков и запускает периодическую очистку (TEMP_SWEEP_INTERVAL_MIN)
+func InitTempFiles() error {
+	t := Temp()
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return err
+	}
+	t.Sweep()
+
+	interval := GetEnvInt("TEMP_SWEEP_INTERVAL_MIN", defaultTempSweepEveryMin)
+	if interval <= 0 {
+		interval = defaultTempSweepEveryMin
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			t.Sweep()
+		}
+	}()
+	return nil
+}
+
+// Create создаёт пустой временный файл со случайным именем
+func (t *TempFiles) Create() (*os.File, error) {
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(t.dir, tempFilePrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.active[f.Name()] = struct{}{}
+	t.mu.Unlock()
+	return f, nil
+}
+
+// Remove удаляет временный файл. Вызывать через defer сразу после Create.
+func (t *TempFiles) Remove(path string) {
+	t.mu.Lock()
+	delete(t.active, path)
+	t.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		LogWarning(fmt.Sprintf("Не удалось удалить временный файл: %v", err))
+	}
+}
+
+// Sweep удаляет временные файлы старше maxAge, которые сейчас не используются
+func (t *TempFiles) Sweep() int {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogWarning(fmt.Sprintf("Не удалось прочитать каталог временных файлов: %v", err))
+		}
+		return 0
+	}
+
+	cutoff := time.Now().Add(-t.maxAge)
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), tempFilePrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(t.dir, e.Name())
+		t.mu.Lock()
+		_, inUse := t.active[path]
+		t.mu.Unlock()
+		if inUse {
+			continue
+		}
+
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		LogInfo(fmt.Sprintf("Удалено забытых временных файлов: %d", removed))
+	}
+	return removed
+}
@@ -0,0 +1,374 @@
+// text_extractors.go
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const maxTextFileSize = 50 << 20
+
+func readTextFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть документ: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxTextFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения документа: %v", err)
+	}
+	return data, nil
+}
+
+// ExtractTextFromTXT читает текстовый файл. Кроме UTF-8 понимает UTF-16 с BOM
+// и Windows-1251, в которой до сих пор сохраняют многие документы.
+func ExtractTextFromTXT(path string) (string, error) {
+	LogAction(fmt.Sprintf("Извлечение текста из TXT: %s", path))
+
+	data, err := readTextFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := decodeText(data)
+	if err != nil {
+		return "", fmt.Errorf("ошибка декодирования текста: %v", err)
+	}
+
+	text = normalizeText(text)
+	if text == "" {
+		return "", fmt.Errorf("файл пуст")
+	}
+
+	LogInfo(fmt.Sprintf("Извлечено %d символов из TXT", len(text)))
+	return text, nil
+}
+
+func decodeText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder().Bytes(data)
+		return string(decoded), err
+	case utf8.Valid(data):
+		return string(data), nil
+	default:
+		decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+		return string(decoded), err
+	}
+}
+
+// ExtractTextFromHTML извлекает видимый текст страницы без скриптов и стилей.
+// Блочные элементы дают переносы строк, ячейки таблиц разделяются " | ".
+func ExtractTextFromHTML(path string) (string, error) {
+	LogAction(fmt.Sprintf("Извлечение текста из HTML: %s", path))
+
+	data, err := readTextFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	enc, _, _ := charset.DetermineEncoding(data, "text/html")
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		decoded = data
+	}
+
+	text := normalizeText(htmlText(decoded))
+	if text == "" {
+		return "", fmt.Errorf("файл пуст")
+	}
+
+	LogInfo(fmt.Sprintf("Извлечено %d символов из HTML", len(text)))
+	return text, nil
+}
+
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "header": true, "footer": true,
+	"blockquote": true, "pre": true, "ul": true, "ol": true, "dt": true, "dd": true,
+	"title": true, "hr": true,
+}
+
+var htmlSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+func htmlText(data []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(data))
+
+	var b strings.Builder
+	skip := 0
+	cellStart := false
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(z.Text())), " ")
+			if text == "" {
+				continue
+			}
+			if cellStart {
+				b.WriteString(" | ")
+				cellStart = false
+			} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString(" ")
+			}
+			b.WriteString(text)
+
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+
+			if htmlSkipTags[tag] {
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+				continue
+			}
+
+			switch {
+			case (tag == "td" || tag == "th") && tt == html.StartTagToken:
+				// разделитель ставится только между ячейками одной строки
+				cellStart = b.Len() > 0 && !strings.HasSuffix(b.String(), "\n")
+			case htmlBlockTags[tag]:
+				cellStart = false
+				b.WriteString("\n")
+			}
+		}
+	}
+}
+
+// ExtractTextFromRTF извлекает текст из RTF: обрабатывает группы, управляющие
+// слова абзацев и табуляции, \'hh в кодовой странице документа и \uN
+func ExtractTextFromRTF(path string) (string, error) {
+	LogAction(fmt.Sprintf("Извлечение текста из RTF: %s", path))
+
+	data, err := readTextFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{\rtf`)) {
+		return "", fmt.Errorf("файл не является документом RTF")
+	}
+
+	text := normalizeText(rtfText(data))
+	if text == "" {
+		return "", fmt.Errorf("файл пуст")
+	}
+
+	LogInfo(fmt.Sprintf("Извлечено %d символов из RTF", len(text)))
+	return text, nil
+}
+
+// Группы RTF, которые не содержат текста документа
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "themedata": true, "colorschememapping": true,
+	"latentstyles": true, "datastore": true, "xmlnstbl": true, "listtable": true,
+	"listoverridetable": true, "rsidtbl": true, "generator": true, "filetbl": true,
+	"revtbl": true, "fldinst": true, "bkmkstart": true, "bkmkend": true,
+	"pgdsctbl": true, "mmathPr": true, "wgrffmtfilter": true,
+}
+
+type rtfState struct {
+	skip     bool
+	ucSkip   int
+	encoding encoding.Encoding
+}
+
+func rtfText(data []byte) string {
+	var out strings.Builder
+	var pending []byte // байты \'hh, декодируются пачкой
+
+	state := rtfState{ucSkip: 1, encoding: charmap.Windows1251}
+	var stack []rtfState
+	skipChars := 0
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if decoded, err := state.encoding.NewDecoder().Bytes(pending); err == nil {
+			out.Write(decoded)
+		}
+		pending = pending[:0]
+	}
+	write := func(s string) {
+		if skipChars > 0 {
+			skipChars--
+			return
+		}
+		if !state.skip {
+			flush()
+			out.WriteString(s)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch ch {
+		case '{':
+			flush()
+			stack = append(stack, state)
+			skipChars = 0
+		case '}':
+			flush()
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			skipChars = 0
+		case '\r', '\n':
+		case '\\':
+			if i+1 >= len(data) {
+				continue
+			}
+			next := data[i+1]
+			switch {
+			case next == '\'' && i+3 < len(data):
+				v, err := strconv.ParseUint(string(data[i+2:i+4]), 16, 8)
+				i += 3
+				if err != nil {
+					continue
+				}
+				if skipChars > 0 {
+					skipChars--
+					continue
+				}
+				if !state.skip {
+					pending = append(pending, byte(v))
+				}
+			case next == '*':
+				state.skip = true
+				i++
+			case next == '\\' || next == '{' || next == '}':
+				write(string(next))
+				i++
+			case next == '~':
+				write(" ")
+				i++
+			case next == '-' || next == '_':
+				i++
+			case isASCIILetter(next):
+				j := i + 1
+				for j < len(data) && isASCIILetter(data[j]) {
+					j++
+				}
+				word := string(data[i+1 : j])
+				k := j
+				if k < len(data) && (data[k] == '-' || isASCIIDigit(data[k])) {
+					k++
+					for k < len(data) && isASCIIDigit(data[k]) {
+						k++
+					}
+				}
+				param, hasParam := 0, k > j
+				if hasParam {
+					param, _ = strconv.Atoi(string(data[j:k]))
+				}
+				if k < len(data) && data[k] == ' ' {
+					k++
+				}
+				i = k - 1
+				rtfControl(word, param, hasParam, &state, write, &skipChars)
+			default:
+				i++
+			}
+		default:
+			write(string(ch))
+		}
+	}
+	flush()
+	return out.String()
+}
+
+func rtfControl(word string, param int, hasParam bool, state *rtfState, write func(string), skipChars *int) {
+	if rtfSkipDestinations[word] {
+		state.skip = true
+		return
+	}
+	switch word {
+	case "par", "line", "sect", "page", "row":
+		write("\n")
+	case "tab":
+		write("\t")
+	case "cell":
+		write(" | ")
+	case "emdash":
+		write("—")
+	case "endash":
+		write("–")
+	case "lquote", "rquote":
+		write("'")
+	case "ldblquote", "rdblquote":
+		write("\"")
+	case "bullet":
+		write("•")
+	case "uc":
+		if hasParam {
+			state.ucSkip = param
+		}
+	case "u":
+		if param < 0 {
+			param += 65536
+		}
+		write(string(rune(param)))
+		*skipChars = state.ucSkip
+	case "ansicpg":
+		if enc := rtfCodePage(param); enc != nil {
+			state.encoding = enc
+		}
+	case "header", "footer", "headerl", "headerr", "headerf", "footerl", "footerr", "footerf":
+		write("\n")
+	}
+}
+
+func rtfCodePage(cp int) encoding.Encoding {
+	switch cp {
+	case 1251:
+		return charmap.Windows1251
+	case 1252:
+		return charmap.Windows1252
+	case 1250:
+		return charmap.Windows1250
+	case 866:
+		return charmap.CodePage866
+	case 20866:
+		return charmap.KOI8R
+	}
+	return nil
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
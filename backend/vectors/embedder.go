@@ -0,0 +1,215 @@
+// embedder.go
+
+package vectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"legally/laws"
+	"legally/utils"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	EmbedderHash   = "hash"
+	EmbedderOpenAI = "openai"
+
+	defaultHashDims       = 1024
+	defaultEmbeddingModel = "text-embedding-3-small"
+	embeddingTimeout      = 60 * time.Second
+	// Модели эмбеддингов OpenAI принимают до 8191 токена; оценка токенов
+	// по числу символов грубая, поэтому бюджет взят с запасом
+	defaultEmbeddingMaxTokens = 6000
+)
+
+// EmbeddingMaxTokens читает EMBEDDING_MAX_TOKENS — сколько токенов начала
+// документа отправляется на эмбеддинг
+func EmbeddingMaxTokens() int {
+	if n := utils.GetEnvInt("EMBEDDING_MAX_TOKENS", defaultEmbeddingMaxTokens); n > 0 {
+		return n
+	}
+	return defaultEmbeddingMaxTokens
+}
+
+// Embedder переводит тексты в векторы одной размерности.
+// Name включает модель: векторы разных эмбеддеров несравнимы.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFromEnv выбирает эмбеддер по EMBEDDER:
+//   - hash (по умолчанию) — хеширование термов, без сети
+//   - openai — EMBEDDING_BASE_URL, EMBEDDING_API_KEY, EMBEDDING_MODEL, EMBEDDING_MAX_TOKENS
+func EmbedderFromEnv() (Embedder, error) {
+	switch name := strings.ToLower(utils.GetEnv("EMBEDDER", EmbedderHash)); name {
+	case EmbedderHash:
+		return NewHashEmbedder(utils.GetEnvInt("EMBEDDING_DIMS", defaultHashDims)), nil
+	case EmbedderOpenAI:
+		return NewOpenAIEmbedder(
+			utils.GetEnv("EMBEDDING_BASE_URL", "https://api.openai.com/v1"),
+			utils.GetEnv("EMBEDDING_API_KEY", ""),
+			utils.GetEnv("EMBEDDING_MODEL", defaultEmbeddingModel),
+			EmbeddingMaxTokens(),
+		), nil
+	default:
+		return nil, fmt.Errorf("неизвестный EMBEDDER: %s", name)
+	}
+}
+
+// HashEmbedder строит вектор по основам слов (laws.Tokenize): каждая основа
+// хешируется в одно из dims измерений с весом 1+log(tf). Близость таких
+// векторов — близость словаря документов, без внешних сервисов.
+type HashEmbedder struct {
+	dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = defaultHashDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("%s-%d", EmbedderHash, e.dims)
+}
+
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		freq := map[string]int{}
+		for _, t := range laws.Tokenize(text) {
+			freq[t]++
+		}
+
+		vec := make([]float32, e.dims)
+		for term, f := range freq {
+			h := fnv.New32a()
+			h.Write([]byte(term))
+			sum := h.Sum32()
+			// Знак из старшего бита уменьшает вклад коллизий
+			weight := float32(1 + math.Log(float64(f)))
+			if sum&(1<<31) != 0 {
+				weight = -weight
+			}
+			vec[int(sum%uint32(e.dims))] += weight
+		}
+		out[i] = normalize(vec)
+	}
+	return out, nil
+}
+
+// OpenAIEmbedder — клиент API /embeddings (OpenAI и совместимые серверы).
+// Длинный текст обрезается до maxTokens: модель отклоняет входы больше своего предела.
+type OpenAIEmbedder struct {
+	baseURL   string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+func NewOpenAIEmbedder(baseURL, apiKey, model string, maxTokens int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		maxTokens: maxTokens,
+		client:    &http.Client{Timeout: embeddingTimeout},
+	}
+}
+
+func (e *OpenAIEmbedder) Name() string {
+	return EmbedderOpenAI + "-" + e.model
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	input := make([]string, len(texts))
+	for i, text := range texts {
+		input[i] = utils.TruncateTokens(text, e.maxTokens)
+	}
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": input})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга запроса эмбеддингов: %w", err)
+	}
+
+	endpoint := e.baseURL + "/embeddings"
+	utils.LogRequest("out", endpoint, len(body))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса эмбеддингов: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("сервис эмбеддингов вернул %d: %s", resp.StatusCode, msg)
+	}
+
+	var res struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("не удалось декодировать эмбеддинги: %w", err)
+	}
+	if len(res.Data) != len(texts) {
+		return nil, fmt.Errorf("сервис эмбеддингов вернул %d векторов вместо %d", len(res.Data), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for _, d := range res.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("неверный индекс эмбеддинга: %d", d.Index)
+		}
+		out[d.Index] = normalize(d.Embedding)
+	}
+	return out, nil
+}
+
+// normalize приводит вектор к единичной длине, чтобы косинус считался скалярным произведением
+func normalize(vec []float32) []float32 {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vec
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,64 @@
+package vectors
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOpenAIEmbedderTruncatesInput(t *testing.T) {
+	var inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		inputs = req.Input
+		// Векторы возвращаются в обратном порядке: клиент раскладывает их по index
+		data := []map[string]interface{}{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(i + 1), 0, 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	long := strings.Repeat("Арендатор вносит плату ежемесячно. ", 2000)
+	vecs, err := NewOpenAIEmbedder(server.URL, "", "test-model", 100).Embed(context.Background(), []string{"договор", long})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if inputs[0] != "договор" {
+		t.Errorf("short input = %q", inputs[0])
+	}
+	if n := utf8.RuneCountInString(inputs[1]); n > 300 || !strings.HasPrefix(long, inputs[1]) {
+		t.Errorf("long input has %d runes, want a prefix of at most 300", n)
+	}
+	for i, v := range vecs {
+		if math.Abs(dot(v, v)-1) > 1e-6 || v[0] <= 0 {
+			t.Errorf("vector %d = %v, want a unit vector", i, v)
+		}
+	}
+}
+
+func TestHashEmbedderSimilarity(t *testing.T) {
+	vecs, err := NewHashEmbedder(256).Embed(context.Background(), []string{
+		"Договор аренды нежилого помещения",
+		"Арендатор по договору аренды помещения",
+		"Трудовой договор с работником",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, other := dot(vecs[0], vecs[1]), dot(vecs[0], vecs[2]); same <= other {
+		t.Errorf("similar texts scored %.3f, unrelated %.3f", same, other)
+	}
+}
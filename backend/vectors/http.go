@@ -0,0 +1,142 @@
+// http.go
+
+package vectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"legally/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	httpTimeout = 30 * time.Second
+	// Во сколько раз больше записей запрашивается, когда часть условий
+	// фильтра проверяется после поиска
+	httpOverfetch = 4
+)
+
+// HTTP — адаптер Python-сервиса эмбеддингов (rag/pinecone_api.py): сервис сам
+// считает эмбеддинги и хранит их в Pinecone, поэтому Embedder здесь не нужен.
+// Текст обрезается до maxTokens, как и у OpenAIEmbedder.
+type HTTP struct {
+	baseURL   string
+	maxTokens int
+	client    *http.Client
+}
+
+func NewHTTP(baseURL string, maxTokens int) *HTTP {
+	return &HTTP{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		maxTokens: maxTokens,
+		client:    &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (s *HTTP) Name() string {
+	return StoreHTTP + " (" + s.baseURL + ")"
+}
+
+func (s *HTTP) Upsert(ctx context.Context, records []Record) error {
+	for _, r := range records {
+		payload := map[string]interface{}{
+			"id":       r.ID,
+			"user_id":  fmt.Sprint(r.Metadata["user_id"]),
+			"text":     utils.TruncateTokens(r.Text, s.maxTokens),
+			"metadata": r.Metadata,
+		}
+		if err := s.post(ctx, "/embed", payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *HTTP) Query(ctx context.Context, q Query) ([]Match, error) {
+	if q.TopK <= 0 {
+		q.TopK = defaultTopK
+	}
+	topK := q.TopK
+	if len(q.Filter.Contains) > 0 {
+		topK *= httpOverfetch
+	}
+	payload := map[string]interface{}{
+		"query":  q.Text,
+		"top_k":  topK,
+		"filter": q.Filter.Pinecone(),
+	}
+
+	var res struct {
+		Matches []Match `json:"matches"`
+	}
+	if err := s.post(ctx, "/search", payload, &res); err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(res.Matches))
+	for _, m := range res.Matches {
+		if m.Score < q.MinScore || !q.Filter.Match(m.Metadata) {
+			continue
+		}
+		if text, ok := m.Metadata["text"].(string); ok {
+			m.Text = text
+			delete(m.Metadata, "text")
+		}
+		matches = append(matches, m)
+	}
+	if len(matches) > q.TopK {
+		matches = matches[:q.TopK]
+	}
+	return matches, nil
+}
+
+func (s *HTTP) Delete(ctx context.Context, ids []string, filter Filter) error {
+	if len(ids) == 0 && filter.Empty() {
+		return ErrEmptyDelete
+	}
+	return s.post(ctx, "/delete", map[string]interface{}{"ids": ids, "filter": filter.Pinecone()}, nil)
+}
+
+func (s *HTTP) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}, filter Filter) error {
+	payload := map[string]interface{}{"id": id, "metadata": metadata, "filter": filter.Pinecone()}
+	return s.post(ctx, "/update", payload, nil)
+}
+
+func (s *HTTP) post(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка кодирования запроса: %w", err)
+	}
+
+	endpoint := s.baseURL + path
+	utils.LogRequest("out", endpoint, len(body))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка вызова сервиса эмбеддингов: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("сервис эмбеддингов вернул %d: %s", resp.StatusCode, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("не удалось декодировать ответ сервиса эмбеддингов: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// local.go
+
+package vectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"legally/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const localFile = "vectors.json"
+
+// Local — хранилище в одном JSON-файле на диске; поиск — перебором
+// записей в памяти. Рассчитано на документы одного сервера, без внешних сервисов.
+type Local struct {
+	path     string
+	embedder Embedder
+
+	mu      sync.RWMutex
+	records map[string]*localRecord
+}
+
+type localRecord struct {
+	ID       string                 `json:"id"`
+	Text     string                 `json:"text"`
+	Vector   []float32              `json:"vector"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type localSnapshot struct {
+	Embedder string         `json:"embedder"`
+	Records  []*localRecord `json:"records"`
+}
+
+// OpenLocal открывает хранилище в каталоге dir, создавая его при необходимости.
+// Записи, посчитанные другим эмбеддером, пересчитываются при открытии.
+func OpenLocal(dir string, embedder Embedder) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+	s := &Local{
+		path:     filepath.Join(dir, localFile),
+		embedder: embedder,
+		records:  map[string]*localRecord{},
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snap localSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("повреждён файл хранилища %s: %w", s.path, err)
+	}
+	for _, r := range snap.Records {
+		s.records[r.ID] = r
+	}
+
+	if snap.Embedder != embedder.Name() && len(snap.Records) > 0 {
+		utils.LogWarning(fmt.Sprintf("Эмбеддер изменился (%s → %s), пересчёт %d записей", snap.Embedder, embedder.Name(), len(snap.Records)))
+		if err := s.reembed(context.Background(), snap.Records); err != nil {
+			return nil, err
+		}
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Local) Name() string {
+	return StoreLocal + " (" + s.embedder.Name() + ")"
+}
+
+func (s *Local) Upsert(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	texts := make([]string, len(records))
+	for i, r := range records {
+		texts[i] = r.Text
+	}
+	vecs, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range records {
+		s.records[r.ID] = &localRecord{ID: r.ID, Text: r.Text, Vector: vecs[i], Metadata: r.Metadata}
+	}
+	return s.save()
+}
+
+func (s *Local) Query(ctx context.Context, q Query) ([]Match, error) {
+	if q.TopK <= 0 {
+		q.TopK = defaultTopK
+	}
+	vecs, err := s.embedder.Embed(ctx, []string{q.Text})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := []Match{}
+	for _, r := range s.records {
+		if !q.Filter.Match(r.Metadata) {
+			continue
+		}
+		score := dot(vecs[0], r.Vector)
+		if score < q.MinScore {
+			continue
+		}
+		matches = append(matches, Match{ID: r.ID, Score: score, Text: r.Text, Metadata: r.Metadata})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > q.TopK {
+		matches = matches[:q.TopK]
+	}
+	return matches, nil
+}
+
+func (s *Local) Delete(_ context.Context, ids []string, filter Filter) error {
+	if len(ids) == 0 && filter.Empty() {
+		return ErrEmptyDelete
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	remove := func(r *localRecord) {
+		if filter.Match(r.Metadata) {
+			delete(s.records, r.ID)
+			removed++
+		}
+	}
+	if len(ids) > 0 {
+		for _, id := range ids {
+			if r, ok := s.records[id]; ok {
+				remove(r)
+			}
+		}
+	} else {
+		for _, r := range s.records {
+			remove(r)
+		}
+	}
+	if removed == 0 {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *Local) UpdateMetadata(_ context.Context, id string, metadata map[string]interface{}, filter Filter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[id]
+	if !ok || !filter.Match(r.Metadata) {
+		return nil
+	}
+	// Метаданные копируются: найденные ранее Match ссылаются на прежнюю карту
+	updated := make(map[string]interface{}, len(r.Metadata)+len(metadata))
+	for k, v := range r.Metadata {
+		updated[k] = v
+	}
+	for k, v := range metadata {
+		updated[k] = v
+	}
+	r.Metadata = updated
+	return s.save()
+}
+
+func (s *Local) reembed(ctx context.Context, records []*localRecord) error {
+	texts := make([]string, len(records))
+	for i, r := range records {
+		texts[i] = r.Text
+	}
+	vecs, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("ошибка пересчёта эмбеддингов: %w", err)
+	}
+	for i, r := range records {
+		r.Vector = vecs[i]
+	}
+	return nil
+}
+
+// save записывает хранилище во временный файл и подменяет им прежний,
+// чтобы сбой посреди записи не оставил файл недописанным
+func (s *Local) save() error {
+	snap := localSnapshot{Embedder: s.embedder.Name(), Records: make([]*localRecord, 0, len(s.records))}
+	for _, r := range s.records {
+		snap.Records = append(snap.Records, r)
+	}
+	sort.Slice(snap.Records, func(i, j int) bool { return snap.Records[i].ID < snap.Records[j].ID })
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи хранилища: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
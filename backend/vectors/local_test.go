@@ -0,0 +1,124 @@
+package vectors
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	store, err := OpenLocal(dir, NewHashEmbedder(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Upsert(ctx, []Record{
+		{ID: "a1", Text: "Договор аренды нежилого помещения", Metadata: map[string]interface{}{"user_id": "u1", "type": "Договор аренды", "created_at": int64(100)}},
+		{ID: "a2", Text: "Договор аренды квартиры", Metadata: map[string]interface{}{"user_id": "u2", "type": "Договор аренды", "created_at": int64(200)}},
+		{ID: "a3", Text: "Трудовой договор с работником", Metadata: map[string]interface{}{"user_id": "u1", "type": "Трудовой договор", "created_at": int64(300)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(matches []Match) []string {
+		var out []string
+		for _, m := range matches {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+	minCreated := 150.0
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"own documents only", Query{Text: "аренда помещения", Filter: Filter{Equals: map[string]string{"user_id": "u1"}}}, []string{"a1", "a3"}},
+		{"organization members", Query{Text: "аренда помещения", TopK: 2, Filter: Filter{In: map[string][]string{"user_id": {"u1", "u2"}}}}, []string{"a1", "a2"}},
+		{"type and date", Query{Text: "договор", Filter: Filter{In: map[string][]string{"type": {"Договор аренды"}}, Range: map[string]Range{"created_at": {Min: &minCreated}}}}, []string{"a2"}},
+		{"score threshold", Query{Text: "аренда помещения", MinScore: 0.99}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := store.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ids(matches)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Query() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// Удаление с фильтром не трогает чужие записи
+	if err := store.Delete(ctx, []string{"a1", "a2"}, Filter{Equals: map[string]string{"user_id": "u1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, nil, Filter{}); !errors.Is(err, ErrEmptyDelete) {
+		t.Errorf("Delete() without ids and filter error = %v", err)
+	}
+
+	// Записи переживают повторное открытие
+	reopened, err := OpenLocal(dir, NewHashEmbedder(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := reopened.Query(ctx, Query{Text: "договор", TopK: 10})
+	if got := ids(matches); len(got) != 2 || got[0] == "a1" || got[1] == "a1" {
+		t.Errorf("after delete and reopen = %v, want a2 and a3", got)
+	}
+}
+
+func TestFilterPinecone(t *testing.T) {
+	min := 1.0
+	f := Filter{
+		Equals:   map[string]string{"user_id": "u1"},
+		Range:    map[string]Range{"created_at": {Min: &min}},
+		Contains: map[string]string{"filename": "аренд"},
+	}
+	got := f.Pinecone()
+	if len(got) != 2 || got["user_id"] == nil || got["created_at"] == nil {
+		t.Errorf("Pinecone() = %v", got)
+	}
+	if (Filter{}).Pinecone() != nil {
+		t.Error("empty filter should produce nil")
+	}
+}
+
+func TestLocalUpdateMetadata(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenLocal(t.TempDir(), NewHashEmbedder(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Upsert(ctx, []Record{{ID: "a1", Text: "Договор аренды", Metadata: map[string]interface{}{"user_id": "u1", "type": "Договор аренды"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := store.records["a1"].Vector
+
+	// Чужой пользователь запись не меняет
+	store.UpdateMetadata(ctx, "a1", map[string]interface{}{"type": "Иное"}, Filter{Equals: map[string]string{"user_id": "u2"}})
+	store.UpdateMetadata(ctx, "a1", map[string]interface{}{"type": "Договор подряда"}, Filter{Equals: map[string]string{"user_id": "u1"}})
+	if err := store.UpdateMetadata(ctx, "missing", map[string]interface{}{"type": "Иное"}, Filter{}); err != nil {
+		t.Errorf("UpdateMetadata() of a missing record error = %v", err)
+	}
+
+	r := store.records["a1"]
+	if r.Metadata["type"] != "Договор подряда" || r.Metadata["user_id"] != "u1" {
+		t.Errorf("metadata = %v", r.Metadata)
+	}
+	if &r.Vector[0] != &before[0] {
+		t.Error("UpdateMetadata() recomputed the embedding")
+	}
+}
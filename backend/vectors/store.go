@@ -0,0 +1,222 @@
+// store.go
+
+package vectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"legally/utils"
+	"strings"
+	"sync"
+)
+
+const (
+	StoreHTTP  = "http"
+	StoreLocal = "local"
+	StoreOff   = "off"
+
+	defaultTopK     = 5
+	defaultLocalDir = "./data/vectors"
+)
+
+// Record — документ хранилища. ID задаёт вызывающий (ID анализа), повторная
+// запись с тем же ID заменяет прежнюю.
+type Record struct {
+	ID       string
+	Text     string
+	Metadata map[string]interface{}
+}
+
+// Filter — условия на метаданные; запись подходит, если выполнены все условия.
+// Equals — точное совпадение значения поля, In — одно из значений,
+// Range — число в границах, Contains — подстрока без учёта регистра.
+type Filter struct {
+	Equals   map[string]string
+	In       map[string][]string
+	Range    map[string]Range
+	Contains map[string]string
+}
+
+// Range — границы числового поля включительно; nil — граница не задана
+type Range struct {
+	Min *float64
+	Max *float64
+}
+
+// Query — поиск записей, близких к тексту, среди подходящих под фильтр.
+// MinScore отбрасывает записи с близостью ниже порога.
+type Query struct {
+	Text     string
+	TopK     int
+	MinScore float64
+	Filter   Filter
+}
+
+// Match — найденная запись и её близость к запросу (косинус, больше — ближе)
+type Match struct {
+	ID       string                 `json:"id"`
+	Score    float64                `json:"score"`
+	Text     string                 `json:"text,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Number возвращает числовое поле метаданных записи
+func (m Match) Number(field string) (float64, bool) {
+	return number(m.Metadata[field])
+}
+
+// Store — векторное хранилище документов пользователей
+type Store interface {
+	Name() string
+	Upsert(ctx context.Context, records []Record) error
+	Query(ctx context.Context, q Query) ([]Match, error)
+	// Delete удаляет записи с указанными ID, подходящие под фильтр;
+	// без ID — все записи, подходящие под фильтр
+	Delete(ctx context.Context, ids []string, filter Filter) error
+	// UpdateMetadata заменяет поля метаданных записи, подходящей под фильтр,
+	// не пересчитывая эмбеддинг; отсутствующая запись не считается ошибкой
+	UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}, filter Filter) error
+}
+
+// ErrEmptyDelete — удаление без ID и без фильтра стёрло бы всё хранилище
+var ErrEmptyDelete = errors.New("не указаны записи для удаления")
+
+// Empty сообщает, что фильтр не содержит условий
+func (f Filter) Empty() bool {
+	return len(f.Equals) == 0 && len(f.In) == 0 && len(f.Range) == 0 && len(f.Contains) == 0
+}
+
+// Match сообщает, что метаданные удовлетворяют фильтру
+func (f Filter) Match(metadata map[string]interface{}) bool {
+	for field, want := range f.Equals {
+		value, ok := metadata[field]
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	for field, values := range f.In {
+		value, ok := metadata[field]
+		if !ok || !containsString(values, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	for field, r := range f.Range {
+		n, ok := number(metadata[field])
+		if !ok || r.Min != nil && n < *r.Min || r.Max != nil && n > *r.Max {
+			return false
+		}
+	}
+	for field, part := range f.Contains {
+		value, ok := metadata[field].(string)
+		if !ok || !strings.Contains(strings.ToLower(value), strings.ToLower(part)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pinecone переводит фильтр в синтаксис фильтров метаданных Pinecone.
+// Contains в Pinecone не выражается и проверяется после поиска.
+func (f Filter) Pinecone() map[string]interface{} {
+	out := map[string]interface{}{}
+	for field, want := range f.Equals {
+		out[field] = map[string]interface{}{"$eq": want}
+	}
+	for field, values := range f.In {
+		out[field] = map[string]interface{}{"$in": values}
+	}
+	for field, r := range f.Range {
+		cond := map[string]interface{}{}
+		if r.Min != nil {
+			cond["$gte"] = *r.Min
+		}
+		if r.Max != nil {
+			cond["$lte"] = *r.Max
+		}
+		if len(cond) > 0 {
+			out[field] = cond
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// number читает числовое поле метаданных: после чтения из JSON числа становятся float64
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// NewFromEnv создаёт хранилище по VECTOR_STORE:
+//   - local (по умолчанию) — файл в VECTOR_STORE_DIR, эмбеддинги по EMBEDDER
+//   - http — Python-сервис эмбеддингов по адресу VECTOR_SERVICE_URL
+//   - off — хранилище отключено
+func NewFromEnv() (Store, error) {
+	switch name := strings.ToLower(utils.GetEnv("VECTOR_STORE", StoreLocal)); name {
+	case StoreLocal:
+		embedder, err := EmbedderFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		s, err := OpenLocal(utils.GetEnv("VECTOR_STORE_DIR", defaultLocalDir), embedder)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case StoreHTTP:
+		url := utils.GetEnv("VECTOR_SERVICE_URL", "")
+		if url == "" {
+			return nil, fmt.Errorf("VECTOR_SERVICE_URL не установлен")
+		}
+		return NewHTTP(url, EmbeddingMaxTokens()), nil
+	case StoreOff:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("неизвестный VECTOR_STORE: %s", name)
+	}
+}
+
+var (
+	defaultStore Store
+	defaultOnce  sync.Once
+)
+
+// Default возвращает хранилище, выбранное окружением. Если хранилище
+// отключено или не создалось, возвращается nil и документы не индексируются.
+func Default() Store {
+	defaultOnce.Do(func() {
+		s, err := NewFromEnv()
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Ошибка создания векторного хранилища: %v", err))
+			return
+		}
+		if s == nil {
+			utils.LogInfo("Векторное хранилище отключено")
+			return
+		}
+		defaultStore = s
+		utils.LogSuccess(fmt.Sprintf("Векторное хранилище: %s", s.Name()))
+	})
+	return defaultStore
+}
@@ -1,5 +1,6 @@
 from fastapi import FastAPI, HTTPException
 from pydantic import BaseModel
+from typing import List, Optional
 from dotenv import load_dotenv
 import openai
 import os
@@ -15,13 +16,25 @@ index = pc.Index("legally-index")
 
 # === Модели запроса ===
 class EmbedRequest(BaseModel):
+    id: Optional[str] = None
     user_id: str
     text: str
     metadata: dict = {}
 
 class SearchRequest(BaseModel):
-    user_id: str
+    user_id: Optional[str] = None
     query: str
+    top_k: int = 5
+    filter: Optional[dict] = None
+
+class DeleteRequest(BaseModel):
+    ids: List[str] = []
+    filter: Optional[dict] = None
+
+class UpdateRequest(BaseModel):
+    id: str
+    metadata: dict
+    filter: Optional[dict] = None
 
 # === Вставка в Pinecone ===
 @app.post("/embed")
@@ -34,7 +47,7 @@ def embed_and_store(data: EmbedRequest):
 
         index.upsert([
             (
-                f"{data.user_id}_{hash(data.text)}",  # уникальный ID
+                data.id or f"{data.user_id}_{hash(data.text)}",  # ID анализа или производный
                 embedding,
                 {
                     **data.metadata,
@@ -48,9 +61,20 @@ def embed_and_store(data: EmbedRequest):
     except Exception as e:
         raise HTTPException(status_code=500, detail=str(e))
 
-# === Поиск по вектору и ответ GPT ===
+def search_filter(data: SearchRequest):
+    """Фильтр метаданных запроса; user_id всегда добавляется к нему через $and,
+    а фильтр без user_id должен сам ограничивать выборку по пользователям"""
+    if data.user_id:
+        owner = {"user_id": {"$eq": data.user_id}}
+        return {"$and": [data.filter, owner]} if data.filter else owner
+    if not data.filter or "user_id" not in data.filter:
+        raise HTTPException(status_code=400, detail="поиск без user_id не ограничен пользователем")
+    return data.filter
+
+# === Поиск по вектору ===
 @app.post("/search")
 def search_docs(data: SearchRequest):
+    flt = search_filter(data)
     try:
         # Получение эмбеддинга для запроса
         embedding = openai.Embedding.create(
@@ -58,36 +82,60 @@ def search_docs(data: SearchRequest):
             input=data.query
         )['data'][0]['embedding']
 
-        # Поиск похожих документов в Pinecone
+        # Поиск похожих документов в Pinecone; ответ по ним строит бэкенд
         results = index.query(
             vector=embedding,
-            top_k=5,
+            top_k=data.top_k,
             include_metadata=True,
-            filter={"user_id": {"$eq": data.user_id}}  # фильтрация по пользователю
-        )
-
-        matches = results.get('matches', [])
-        if not matches:
-            return {"matches": None, "answer": "Ничего не найдено по запросу."}
-
-        # Сбор контекста
-        context = "\n".join([m['metadata'].get("text", "") for m in matches])
-
-        # Запрос к OpenAI GPT
-        gpt_response = openai.ChatCompletion.create(
-            model="gpt-4",
-            messages=[
-                {"role": "system", "content": "Ты — AI-юрист. Отвечай строго по контексту закона."},
-                {"role": "user", "content": f"Контекст:\n{context}\n\nВопрос: {data.query}"}
-            ],
-            temperature=0.2,
-            max_tokens=800
+            filter=flt
         )
 
         return {
-            "matches": matches,
-            "answer": gpt_response['choices'][0]['message']['content']
+            "matches": [
+                {"id": m['id'], "score": m['score'], "metadata": m.get('metadata') or {}}
+                for m in results.get('matches', [])
+            ]
         }
 
     except Exception as e:
         raise HTTPException(status_code=500, detail=str(e))
+
+# === Удаление векторов ===
+@app.post("/delete")
+def delete_docs(data: DeleteRequest):
+    if not data.ids and not data.filter:
+        raise HTTPException(status_code=400, detail="не указаны записи для удаления")
+    try:
+        if data.ids:
+            # Pinecone удаляет по ID без учёта фильтра, поэтому ID сверяются с ним заранее
+            ids = data.ids
+            if data.filter:
+                fetched = index.fetch(ids=ids).vectors
+                ids = [i for i in ids if i in fetched and matches_filter(fetched[i].metadata or {}, data.filter)]
+            if ids:
+                index.delete(ids=ids)
+            return {"deleted": len(ids)}
+        index.delete(filter=data.filter)
+        return {"deleted": None}
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=str(e))
+
+# === Обновление метаданных без пересчёта эмбеддинга ===
+@app.post("/update")
+def update_metadata(data: UpdateRequest):
+    try:
+        fetched = index.fetch(ids=[data.id]).vectors
+        if data.id not in fetched or not matches_filter(fetched[data.id].metadata or {}, data.filter or {}):
+            return {"updated": 0}
+        index.update(id=data.id, set_metadata=data.metadata)
+        return {"updated": 1}
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=str(e))
+
+def matches_filter(metadata: dict, flt: dict) -> bool:
+    """Проверка условий $eq фильтра Pinecone по метаданным"""
+    for field, cond in flt.items():
+        want = cond.get("$eq") if isinstance(cond, dict) else cond
+        if str(metadata.get(field)) != str(want):
+            return False
+    return True